@@ -0,0 +1,105 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// hashLength - length of the content hash in the hashed file names
+const hashLength = 10
+
+// cache policies of the served files
+const (
+	// hashedCacheControl - hashed file never changes, as its name changes with the content
+	hashedCacheControl = "public, max-age=31536000, immutable"
+	// plainCacheControl - files requested by the plain names may change on the next deploy
+	plainCacheControl = "public, max-age=300"
+)
+
+// Assets - static files served with content hashes in their names, e.g. 'css/main.3f2a9c1b0e.css'
+// Hashed names change with the content, so browsers cache hashed files forever
+type Assets struct {
+	files fs.FS
+	// hashedNames - hashed file names by the file names
+	hashedNames map[string]string
+	// names - file names by the hashed file names
+	names map[string]string
+}
+
+// New - hashes contents of all the static files
+// If fingerprint is false, files are not hashed and URLs of the files are the plain ones.
+// It is used in development mode, so that changed files are served without a restart
+func New(files fs.FS, fingerprint bool) (*Assets, error) {
+	assets := &Assets{
+		files:       files,
+		hashedNames: make(map[string]string),
+		names:       make(map[string]string),
+	}
+	if !fingerprint {
+		return assets, nil
+	}
+
+	err := fs.WalkDir(files, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(files, name)
+		if err != nil {
+			return err
+		}
+		hashedName := hashName(name, content)
+		assets.hashedNames[name] = hashedName
+		assets.names[hashedName] = name
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error hashing static files: %s", err)
+	}
+	return assets, nil
+}
+
+// hashName - inserts hash of the content before the file extension
+func hashName(name string, content []byte) string {
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])[:hashLength]
+	extension := path.Ext(name)
+	return strings.TrimSuffix(name, extension) + "." + hash + extension
+}
+
+// URL - returns URL of the static file with the given name, e.g. '/css/main.3f2a9c1b0e.css' for 'css/main.css'
+// Returns error if there is no such file
+func (assets *Assets) URL(name string) (string, error) {
+	name = strings.TrimPrefix(name, "/")
+	if hashedName, ok := assets.hashedNames[name]; ok {
+		return "/" + hashedName, nil
+	}
+	if _, err := fs.Stat(assets.files, name); err != nil {
+		return "", fmt.Errorf("static file %s is not found", name)
+	}
+	return "/" + name, nil
+}
+
+// Handler - returns handler serving static files both by hashed and plain names
+// Hashed files are cached forever, files requested by the plain names are cached shortly
+func (assets *Assets) Handler() http.Handler {
+	fileServer := http.FileServer(http.FS(assets.files))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, isHashed := assets.names[strings.TrimPrefix(r.URL.Path, "/")]
+		if !isHashed {
+			w.Header().Set("Cache-Control", plainCacheControl)
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Cache-Control", hashedCacheControl)
+		plainRequest := r.Clone(r.Context())
+		plainRequest.URL.Path = "/" + name
+		plainRequest.URL.RawPath = ""
+		fileServer.ServeHTTP(w, plainRequest)
+	})
+}
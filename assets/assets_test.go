@@ -0,0 +1,77 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+const testCSS = "body { color: black; }"
+
+func newTestFiles() fstest.MapFS {
+	return fstest.MapFS{
+		"css/main.css":       &fstest.MapFile{Data: []byte(testCSS)},
+		"images/favicon.ico": &fstest.MapFile{Data: []byte("icon")},
+	}
+}
+
+// serveAsset - serves GET request by the assets handler
+func serveAsset(assets *Assets, path string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	assets.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+	return recorder
+}
+
+func TestAssetURLHasContentHash(t *testing.T) {
+	assets, err := New(newTestFiles(), true)
+	require.NoError(t, err)
+
+	sum := sha256.Sum256([]byte(testCSS))
+	expectedURL := "/css/main." + hex.EncodeToString(sum[:])[:hashLength] + ".css"
+	url, err := assets.URL("css/main.css")
+	require.NoError(t, err)
+	require.Equal(t, expectedURL, url)
+
+	_, err = assets.URL("css/missing.css")
+	require.Error(t, err)
+}
+
+func TestHashedAssetIsCachedForever(t *testing.T) {
+	assets, err := New(newTestFiles(), true)
+	require.NoError(t, err)
+	url, err := assets.URL("css/main.css")
+	require.NoError(t, err)
+
+	response := serveAsset(assets, url)
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Equal(t, testCSS, response.Body.String())
+	require.Equal(t, hashedCacheControl, response.Header().Get("Cache-Control"))
+	require.Contains(t, response.Header().Get("Content-Type"), "text/css")
+}
+
+func TestPlainAssetIsCachedShortly(t *testing.T) {
+	assets, err := New(newTestFiles(), true)
+	require.NoError(t, err)
+
+	response := serveAsset(assets, "/css/main.css")
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Equal(t, testCSS, response.Body.String())
+	require.Equal(t, plainCacheControl, response.Header().Get("Cache-Control"))
+
+	response = serveAsset(assets, "/css/main.0000000000.css")
+	require.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestAssetsWithoutFingerprint(t *testing.T) {
+	assets, err := New(newTestFiles(), false)
+	require.NoError(t, err)
+
+	url, err := assets.URL("images/favicon.ico")
+	require.NoError(t, err)
+	require.Equal(t, "/images/favicon.ico", url)
+	require.Equal(t, plainCacheControl, serveAsset(assets, url).Header().Get("Cache-Control"))
+}
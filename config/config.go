@@ -0,0 +1,340 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/blinky-z/Blog/storage"
+	"github.com/blinky-z/Blog/themes"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Config - server settings
+// @Admins - usernames of the users getting admin role
+// @AutoMigrate - apply pending database migrations at startup
+// @Theme - name of the site theme
+// @OverrideDir - directory with files replacing the embedded themes and robots files. Empty to use only embedded files
+// @DevMode - development mode: page templates are parsed again on changes of their files
+type Config struct {
+	DB                  DB
+	Server              Server
+	Domain              string
+	JWTSecretKey        string
+	Admins              []string
+	RegistrationEnabled bool
+	AutoMigrate         bool
+	Theme               string
+	OverrideDir         string
+	DevMode             bool
+	Storage             Storage
+	Site                Site
+}
+
+// DB - database connection settings
+type DB struct {
+	User     string
+	Password string
+	Name     string
+	Host     string
+	Port     string
+}
+
+// Server - http server settings
+// @ReadTimeout - max duration of reading the entire request, including the body
+// @WriteTimeout - max duration from the end of reading the request headers to the end of writing the response
+// @IdleTimeout - max duration of waiting for the next request on keep-alive connection
+// @MaxHeaderBytes - max size of the request headers
+// @ShutdownTimeout - max duration of the graceful shutdown. Requests unfinished by the deadline are dropped
+// @DrainDelay - delay between failing readiness check and closing the listener on shutdown,
+// so that load balancer stops routing requests to the server. It is a part of the shutdown timeout and must be
+// shorter, leaving time for the active requests to finish
+type Server struct {
+	Port            string
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	MaxHeaderBytes  int
+	ShutdownTimeout time.Duration
+	DrainDelay      time.Duration
+}
+
+// Storage - storage of uploaded files settings
+// @Backend - 'local' or 's3'
+// @MediaDir - directory of uploaded files for local backend
+type Storage struct {
+	Backend  string
+	MediaDir string
+	S3       storage.S3Config
+}
+
+// storage backends
+const (
+	// LocalStorageBackend - uploaded files are stored in the local directory
+	LocalStorageBackend = "local"
+	// S3StorageBackend - uploaded files are stored in S3-compatible storage
+	S3StorageBackend = "s3"
+)
+
+const (
+	// PathEnvVariable - env variable with path to the config file
+	PathEnvVariable = "CONFIG_FILE"
+	// DefaultPath - config file used if the path is not set. The default config file is optional
+	DefaultPath = "config.yaml"
+)
+
+// keys to access config values. Nested keys are sections of the config file
+const (
+	dbUserKey                = "db.user"
+	dbPasswordKey            = "db.password"
+	dbNameKey                = "db.name"
+	dbHostKey                = "db.host"
+	dbPortKey                = "db.port"
+	serverPortKey            = "server.port"
+	serverReadTimeoutKey     = "server.read_timeout"
+	serverWriteTimeoutKey    = "server.write_timeout"
+	serverIdleTimeoutKey     = "server.idle_timeout"
+	serverMaxHeaderBytesKey  = "server.max_header_bytes"
+	serverShutdownTimeoutKey = "server.shutdown_timeout"
+	serverDrainDelayKey      = "server.drain_delay"
+	domainKey                = "domain"
+	jwtSecretKey             = "jwt_secret_key"
+	adminsKey                = "admins"
+	registrationEnabledKey   = "registration_enabled"
+	autoMigrateKey           = "auto_migrate"
+	themeKey                 = "theme"
+	overrideDirKey           = "override_dir"
+	devModeKey               = "dev_mode"
+	storageBackendKey        = "storage.backend"
+	mediaDirKey              = "storage.media_dir"
+	s3EndpointKey            = "storage.s3.endpoint"
+	s3RegionKey              = "storage.s3.region"
+	s3BucketKey              = "storage.s3.bucket"
+	s3AccessKeyKey           = "storage.s3.access_key"
+	s3SecretKeyKey           = "storage.s3.secret_key"
+
+	siteNameKey         = "site.name"
+	siteTaglineKey      = "site.tagline"
+	siteTitleKey        = "site.title"
+	siteDescriptionKey  = "site.description"
+	siteKeywordsKey     = "site.keywords"
+	siteAboutKey        = "site.about"
+	sitePostsPerPageKey = "site.posts_per_page"
+)
+
+// envVariables - env variables bound to the config keys. Env variables take precedence over the config file
+var envVariables = map[string]string{
+	dbUserKey:                "DB_USER",
+	dbPasswordKey:            "DB_PASSWORD",
+	dbNameKey:                "DB_NAME",
+	dbHostKey:                "DB_HOST",
+	dbPortKey:                "DB_PORT",
+	serverPortKey:            "SERVER_PORT",
+	serverReadTimeoutKey:     "SERVER_READ_TIMEOUT",
+	serverWriteTimeoutKey:    "SERVER_WRITE_TIMEOUT",
+	serverIdleTimeoutKey:     "SERVER_IDLE_TIMEOUT",
+	serverMaxHeaderBytesKey:  "SERVER_MAX_HEADER_BYTES",
+	serverShutdownTimeoutKey: "SERVER_SHUTDOWN_TIMEOUT",
+	serverDrainDelayKey:      "SERVER_DRAIN_DELAY",
+	domainKey:                "DOMAIN",
+	jwtSecretKey:             "JWT_SECRET_KEY",
+	adminsKey:                "ADMINS",
+	registrationEnabledKey:   "REGISTRATION_ENABLED",
+	autoMigrateKey:           "AUTO_MIGRATE",
+	themeKey:                 "THEME",
+	overrideDirKey:           "OVERRIDE_DIR",
+	devModeKey:               "DEV_MODE",
+	storageBackendKey:        "STORAGE_BACKEND",
+	mediaDirKey:              "MEDIA_DIR",
+	s3EndpointKey:            "S3_ENDPOINT",
+	s3RegionKey:              "S3_REGION",
+	s3BucketKey:              "S3_BUCKET",
+	s3AccessKeyKey:           "S3_ACCESS_KEY",
+	s3SecretKeyKey:           "S3_SECRET_KEY",
+}
+
+// defaults - default config values
+var defaults = map[string]interface{}{
+	serverReadTimeoutKey:     30 * time.Second,
+	serverWriteTimeoutKey:    60 * time.Second,
+	serverIdleTimeoutKey:     120 * time.Second,
+	serverMaxHeaderBytesKey:  1 << 20,
+	serverShutdownTimeoutKey: 30 * time.Second,
+	serverDrainDelayKey:      5 * time.Second,
+	autoMigrateKey:           true,
+	themeKey:                 themes.DefaultName,
+	storageBackendKey:        LocalStorageBackend,
+	mediaDirKey:              filepath.FromSlash("media/"),
+	siteNameKey:              "Blog",
+	siteTitleKey:             "Blog",
+	sitePostsPerPageKey:      10,
+}
+
+// Loader - loads config from env variables and the config file
+type Loader struct {
+	viper *viper.Viper
+	// path - absolute path of the config file. Empty if there is no config file
+	path string
+}
+
+// NewLoader - creates config loader and reads the config file
+// Config file is read from the path set in CONFIG_FILE env variable. If the variable is not set, the default
+// config file is read if it exists
+// Format of the config file is detected by its extension: YAML and TOML are supported
+func NewLoader() (*Loader, error) {
+	v := viper.New()
+	for key, envVariable := range envVariables {
+		if err := v.BindEnv(key, envVariable); err != nil {
+			return nil, err
+		}
+	}
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+
+	path, isPathSet := os.LookupEnv(PathEnvVariable)
+	if !isPathSet {
+		path = DefaultPath
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return &Loader{viper: v}, nil
+		}
+	}
+
+	// config file is watched by its directory, so the path should be absolute
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	v.SetConfigFile(absolutePath)
+	if err = v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("error reading config file %s: %s", absolutePath, err)
+	}
+	return &Loader{viper: v, path: absolutePath}, nil
+}
+
+// Path - returns absolute path of the read config file. Returns empty string if there is no config file
+func (l *Loader) Path() string {
+	return l.path
+}
+
+// getList - returns list value. Lists are set either as a list in the config file
+// or as a comma separated string, e.g. in env variables
+func (l *Loader) getList(key string) []string {
+	var values []string
+	if value, ok := l.viper.Get(key).(string); ok {
+		values = strings.Split(value, ",")
+	} else {
+		values = l.viper.GetStringSlice(key)
+	}
+
+	list := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// Config - returns loaded config
+func (l *Loader) Config() (Config, error) {
+	site, err := l.Site()
+	if err != nil {
+		return Config{}, err
+	}
+
+	config := Config{
+		DB: DB{
+			User:     l.viper.GetString(dbUserKey),
+			Password: l.viper.GetString(dbPasswordKey),
+			Name:     l.viper.GetString(dbNameKey),
+			Host:     l.viper.GetString(dbHostKey),
+			Port:     l.viper.GetString(dbPortKey),
+		},
+		Server: Server{
+			Port:            l.viper.GetString(serverPortKey),
+			ReadTimeout:     l.viper.GetDuration(serverReadTimeoutKey),
+			WriteTimeout:    l.viper.GetDuration(serverWriteTimeoutKey),
+			IdleTimeout:     l.viper.GetDuration(serverIdleTimeoutKey),
+			MaxHeaderBytes:  l.viper.GetInt(serverMaxHeaderBytesKey),
+			ShutdownTimeout: l.viper.GetDuration(serverShutdownTimeoutKey),
+			DrainDelay:      l.viper.GetDuration(serverDrainDelayKey),
+		},
+		Domain:              l.viper.GetString(domainKey),
+		JWTSecretKey:        l.viper.GetString(jwtSecretKey),
+		Admins:              l.getList(adminsKey),
+		RegistrationEnabled: l.viper.GetBool(registrationEnabledKey),
+		AutoMigrate:         l.viper.GetBool(autoMigrateKey),
+		Theme:               l.viper.GetString(themeKey),
+		OverrideDir:         l.viper.GetString(overrideDirKey),
+		DevMode:             l.viper.GetBool(devModeKey),
+		Storage: Storage{
+			Backend:  l.viper.GetString(storageBackendKey),
+			MediaDir: l.viper.GetString(mediaDirKey),
+			S3: storage.S3Config{
+				Endpoint:  l.viper.GetString(s3EndpointKey),
+				Region:    l.viper.GetString(s3RegionKey),
+				Bucket:    l.viper.GetString(s3BucketKey),
+				AccessKey: l.viper.GetString(s3AccessKeyKey),
+				SecretKey: l.viper.GetString(s3SecretKeyKey),
+			},
+		},
+		Site: site,
+	}
+
+	if config.Server.ReadTimeout < 0 || config.Server.WriteTimeout < 0 || config.Server.IdleTimeout < 0 ||
+		config.Server.ShutdownTimeout < 0 || config.Server.DrainDelay < 0 {
+		return config, errors.New("server timeouts must not be negative")
+	}
+	if config.Server.DrainDelay >= config.Server.ShutdownTimeout {
+		return config, errors.New("server drain delay must be shorter than shutdown timeout")
+	}
+	if config.Server.MaxHeaderBytes < 1 {
+		return config, errors.New("server max header bytes must be positive")
+	}
+	if config.Storage.Backend != LocalStorageBackend && config.Storage.Backend != S3StorageBackend {
+		return config, fmt.Errorf("unknown storage backend: %s", config.Storage.Backend)
+	}
+	return config, nil
+}
+
+// Site - returns loaded site settings
+func (l *Loader) Site() (Site, error) {
+	site := Site{
+		Name:         l.viper.GetString(siteNameKey),
+		Tagline:      l.viper.GetString(siteTaglineKey),
+		Title:        l.viper.GetString(siteTitleKey),
+		Description:  l.viper.GetString(siteDescriptionKey),
+		Keywords:     l.getList(siteKeywordsKey),
+		About:        l.viper.GetString(siteAboutKey),
+		PostsPerPage: l.viper.GetInt(sitePostsPerPageKey),
+	}
+
+	if site.PostsPerPage < 1 {
+		return site, errors.New("site posts per page must be positive")
+	}
+	return site, nil
+}
+
+// WatchSite - updates site settings when the config file changes
+// Invalid site settings are ignored. Other settings are applied only after restart
+func (l *Loader) WatchSite(site *LiveSite, logInfo, logError *log.Logger) {
+	if l.path == "" {
+		return
+	}
+
+	l.viper.OnConfigChange(func(event fsnotify.Event) {
+		changedSite, err := l.Site()
+		if err != nil {
+			logError.Printf("Invalid site settings in the changed config file: %s. Keeping the current settings", err)
+			return
+		}
+		site.Set(changedSite)
+		logInfo.Printf("Site settings reloaded from %s", l.path)
+	})
+	l.viper.WatchConfig()
+}
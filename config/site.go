@@ -0,0 +1,62 @@
+package config
+
+import "sync"
+
+// Site - site branding settings. They are reloaded without a restart when the config file changes
+// @Name - short site name, e.g. 'Progbloom'
+// @Tagline - short site description following the name in page titles, e.g. 'A blog about programming'
+// @Title - site title in the page header
+// @Description - site description in the page header
+// @Keywords - meta keywords of the site pages
+// @About - html content of the about page
+type Site struct {
+	Name         string
+	Tagline      string
+	Title        string
+	Description  string
+	Keywords     []string
+	About        string
+	PostsPerPage int
+}
+
+// FullName - returns site name followed by the tagline, e.g. 'Progbloom - A blog about programming'
+func (site Site) FullName() string {
+	if site.Tagline == "" {
+		return site.Name
+	}
+	return site.Name + " - " + site.Tagline
+}
+
+// TitleSuffix - returns suffix of the page titles
+func (site Site) TitleSuffix() string {
+	return " | " + site.FullName()
+}
+
+// PageDescription - returns meta description of the site page, e.g. 'Progbloom - A blog about programming. All posts'
+func (site Site) PageDescription(page string) string {
+	return site.FullName() + ". " + page
+}
+
+// LiveSite - site settings shared between the config watcher and the request handlers
+type LiveSite struct {
+	mutex sync.RWMutex
+	site  Site
+}
+
+func NewLiveSite(site Site) *LiveSite {
+	return &LiveSite{site: site}
+}
+
+// Get - returns current site settings
+func (liveSite *LiveSite) Get() Site {
+	liveSite.mutex.RLock()
+	defer liveSite.mutex.RUnlock()
+	return liveSite.site
+}
+
+// Set - replaces site settings
+func (liveSite *LiveSite) Set(site Site) {
+	liveSite.mutex.Lock()
+	defer liveSite.mutex.Unlock()
+	liveSite.site = site
+}
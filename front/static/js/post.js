@@ -1,10 +0,0 @@
-$.fancybox.defaults.animationEffect = "fade";
-$.fancybox.defaults.buttons = [
-    "zoom",
-    "download",
-    "close"
-];
-$.fancybox.defaults.toolbar = false;
-$.fancybox.defaults.mobile = {
-    clickSlide: "close"
-};
\ No newline at end of file
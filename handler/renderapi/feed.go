@@ -0,0 +1,386 @@
+package renderapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"github.com/blinky-z/Blog/handler/restapi"
+	"github.com/blinky-z/Blog/models"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// feedPostsCount - amount of the most recent posts included in feeds
+	feedPostsCount int = 20
+	// feedLanguage - language of the posts
+	feedLanguage = "ru"
+)
+
+// feed - represents feed data common to all feed formats
+type feed struct {
+	Title       string
+	Description string
+	// Author - name of the feed author. It is the site title
+	Author string
+	// Link - absolute URL of the html page that corresponds to the feed
+	Link string
+	// SelfLink - absolute URL of the feed itself
+	SelfLink string
+	Updated  time.Time
+	Posts    []models.Post
+}
+
+// rssFeed - represents RSS 2.0 document
+type rssFeed struct {
+	XMLName      xml.Name   `xml:"rss"`
+	Version      string     `xml:"version,attr"`
+	XMLNSAtom    string     `xml:"xmlns:atom,attr"`
+	XMLNSContent string     `xml:"xmlns:content,attr"`
+	XMLNSDC      string     `xml:"xmlns:dc,attr"`
+	Channel      rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	AtomLink      atomLink  `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Description string   `xml:"description"`
+	Content     string   `xml:"content:encoded"`
+	Creators    []string `xml:"dc:creator"`
+	Categories  []string `xml:"category"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// atomFeed - represents Atom 1.0 document
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Summary    atomText       `xml:"summary"`
+	Content    atomText       `xml:"content"`
+	Authors    []atomAuthor   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// jsonFeed - represents JSON Feed 1.1 document
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description"`
+	Language    string         `json:"language"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	Summary       string           `json:"summary"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Authors       []jsonFeedAuthor `json:"authors,omitempty"`
+	Tags          []string         `json:"tags"`
+}
+
+type jsonFeedAuthor struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Avatar string `json:"avatar,omitempty"`
+}
+
+// postURL - returns absolute URL of the post page
+func (renderApi *Handler) postURL(post models.Post) string {
+	return renderApi.domain.String() + "/posts/" + url.PathEscape(post.Slug)
+}
+
+// authorURL - returns absolute URL of the author page
+func (renderApi *Handler) authorURL(author models.Author) string {
+	return renderApi.domain.String() + "/authors/" + url.PathEscape(author.Username)
+}
+
+// postFullContent - returns full html content of the post. Snippet is a part of the post, so it goes first
+func postFullContent(post models.Post) string {
+	return post.Snippet + post.Content
+}
+
+// postUpdated - returns time the post was changed last time. Scheduled posts are dated in future until published
+func postUpdated(post models.Post) time.Time {
+	if post.Date.After(post.Updated) {
+		return post.Date
+	}
+	return post.Updated
+}
+
+// getFeed - retrieves the most recent posts and builds the feed
+// Feed contains posts tagged with the 'tag' route variable if it is set, or all posts otherwise
+// 'feedPath' is a path of the feed relative to the html page it corresponds to
+func (renderApi *Handler) getFeed(r *http.Request, feedPath string) (*feed, error) {
+	tag := mux.Vars(r)["tag"]
+
+	var posts []models.Post
+	var err error
+	if tag != "" {
+		posts, err = renderApi.posts.GetPostsInRangeByTag(0, feedPostsCount, tag)
+	} else {
+		posts, err = renderApi.posts.GetPostsInRange(0, feedPostsCount)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	site := renderApi.site.Get()
+	siteURL := renderApi.domain.String()
+	result := &feed{
+		Title:       site.FullName(),
+		Description: site.Description,
+		Author:      site.Title,
+		Link:        siteURL + "/",
+		SelfLink:    siteURL + "/" + feedPath,
+		Posts:       posts,
+	}
+	if tag != "" {
+		tagPageURL := siteURL + "/tags/" + url.PathEscape(tag)
+		result.Title = "Posts tagged with " + tag + site.TitleSuffix()
+		result.Description = "Posts tagged with " + tag
+		result.Link = tagPageURL
+		result.SelfLink = tagPageURL + "/" + feedPath
+	}
+	// deleted and unpublished posts leave the feed without changing the remaining posts,
+	// so the feed is not older than the last change of any post
+	if result.Updated, err = renderApi.posts.GetContentChanged(); err != nil {
+		return nil, err
+	}
+	for _, post := range posts {
+		if updated := postUpdated(post); updated.After(result.Updated) {
+			result.Updated = updated
+		}
+	}
+
+	return result, nil
+}
+
+// serveGenerated - writes the generated document, such as feed or sitemap. Responds with 304 Not Modified
+// if the document has not changed since the time passed in If-Modified-Since header
+func serveGenerated(w http.ResponseWriter, r *http.Request, contentType string, content []byte, updated time.Time) {
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, "", updated, bytes.NewReader(content))
+}
+
+// renderFeedHandler - creates handler that builds the feed and encodes it with the given function
+func (renderApi *Handler) renderFeedHandler(feedPath, contentType string,
+	encode func(feed *feed) ([]byte, error)) http.Handler {
+	logError := renderApi.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		feed, err := renderApi.getFeed(r, feedPath)
+		if err != nil {
+			logError.Printf("Error retrieving feed posts: %s", err)
+			restapi.Respond(w, http.StatusInternalServerError)
+			return
+		}
+
+		encodedFeed, err := encode(feed)
+		if err != nil {
+			logError.Printf("Error encoding %s feed: %s", feedPath, err)
+			restapi.Respond(w, http.StatusInternalServerError)
+			return
+		}
+
+		serveGenerated(w, r, contentType, encodedFeed, feed.Updated)
+	})
+}
+
+func (renderApi *Handler) encodeRSSFeed(feed *feed) ([]byte, error) {
+	items := make([]rssItem, 0, len(feed.Posts))
+	for _, post := range feed.Posts {
+		postURL := renderApi.postURL(post)
+		creators := make([]string, 0, len(post.Authors))
+		for _, author := range post.Authors {
+			creators = append(creators, author.Name())
+		}
+		items = append(items, rssItem{
+			Title:       post.Title,
+			Link:        postURL,
+			GUID:        rssGUID{IsPermaLink: true, Value: postURL},
+			PubDate:     post.Date.Format(time.RFC1123Z),
+			Description: post.Snippet,
+			Content:     postFullContent(post),
+			Creators:    creators,
+			Categories:  post.Tags,
+		})
+	}
+
+	encodedFeed, err := xml.MarshalIndent(rssFeed{
+		Version:      "2.0",
+		XMLNSAtom:    "http://www.w3.org/2005/Atom",
+		XMLNSContent: "http://purl.org/rss/1.0/modules/content/",
+		XMLNSDC:      "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         feed.Title,
+			Link:          feed.Link,
+			Description:   feed.Description,
+			Language:      feedLanguage,
+			LastBuildDate: feed.Updated.Format(time.RFC1123Z),
+			AtomLink:      atomLink{Href: feed.SelfLink, Rel: "self", Type: "application/rss+xml"},
+			Items:         items,
+		},
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), encodedFeed...), nil
+}
+
+func (renderApi *Handler) encodeAtomFeed(feed *feed) ([]byte, error) {
+	entries := make([]atomEntry, 0, len(feed.Posts))
+	for _, post := range feed.Posts {
+		postURL := renderApi.postURL(post)
+		categories := make([]atomCategory, 0, len(post.Tags))
+		for _, tag := range post.Tags {
+			categories = append(categories, atomCategory{Term: tag})
+		}
+		authors := make([]atomAuthor, 0, len(post.Authors))
+		for _, author := range post.Authors {
+			authors = append(authors, atomAuthor{Name: author.Name(), URI: renderApi.authorURL(author)})
+		}
+		entries = append(entries, atomEntry{
+			Title:      post.Title,
+			ID:         postURL,
+			Link:       atomLink{Href: postURL, Rel: "alternate", Type: "text/html"},
+			Published:  post.Date.Format(time.RFC3339),
+			Updated:    postUpdated(post).Format(time.RFC3339),
+			Summary:    atomText{Type: "html", Value: post.Snippet},
+			Content:    atomText{Type: "html", Value: postFullContent(post)},
+			Authors:    authors,
+			Categories: categories,
+		})
+	}
+
+	encodedFeed, err := xml.MarshalIndent(atomFeed{
+		XMLNS:   "http://www.w3.org/2005/Atom",
+		Title:   feed.Title,
+		ID:      feed.SelfLink,
+		Updated: feed.Updated.Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: feed.SelfLink, Rel: "self", Type: "application/atom+xml"},
+			{Href: feed.Link, Rel: "alternate", Type: "text/html"},
+		},
+		Author:  atomAuthor{Name: feed.Author},
+		Entries: entries,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), encodedFeed...), nil
+}
+
+func (renderApi *Handler) encodeJSONFeed(feed *feed) ([]byte, error) {
+	items := make([]jsonFeedItem, 0, len(feed.Posts))
+	for _, post := range feed.Posts {
+		postURL := renderApi.postURL(post)
+		tags := post.Tags
+		if tags == nil {
+			tags = make([]string, 0)
+		}
+		authors := make([]jsonFeedAuthor, 0, len(post.Authors))
+		for _, author := range post.Authors {
+			authors = append(authors, jsonFeedAuthor{
+				Name:   author.Name(),
+				URL:    renderApi.authorURL(author),
+				Avatar: author.AvatarURL,
+			})
+		}
+		items = append(items, jsonFeedItem{
+			ID:            postURL,
+			URL:           postURL,
+			Title:         post.Title,
+			ContentHTML:   postFullContent(post),
+			Summary:       post.Metadata.Description,
+			DatePublished: post.Date.Format(time.RFC3339),
+			DateModified:  postUpdated(post).Format(time.RFC3339),
+			Authors:       authors,
+			Tags:          tags,
+		})
+	}
+
+	return json.MarshalIndent(jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       feed.Title,
+		HomePageURL: feed.Link,
+		FeedURL:     feed.SelfLink,
+		Description: feed.Description,
+		Language:    feedLanguage,
+		Items:       items,
+	}, "", "  ")
+}
+
+// RenderRSSFeedHandler - handler for RSS 2.0 feed of the most recent posts
+// Serves both feed of all posts and feed of posts with the given tag
+func (renderApi *Handler) RenderRSSFeedHandler() http.Handler {
+	return renderApi.renderFeedHandler("feed.xml", "application/rss+xml; charset=utf-8", renderApi.encodeRSSFeed)
+}
+
+// RenderAtomFeedHandler - handler for Atom feed of the most recent posts
+// Serves both feed of all posts and feed of posts with the given tag
+func (renderApi *Handler) RenderAtomFeedHandler() http.Handler {
+	return renderApi.renderFeedHandler("atom.xml", "application/atom+xml; charset=utf-8", renderApi.encodeAtomFeed)
+}
+
+// RenderJSONFeedHandler - handler for JSON Feed of the most recent posts
+// Serves both feed of all posts and feed of posts with the given tag
+func (renderApi *Handler) RenderJSONFeedHandler() http.Handler {
+	return renderApi.renderFeedHandler("feed.json", "application/feed+json; charset=utf-8", renderApi.encodeJSONFeed)
+}
@@ -3,45 +3,56 @@ package renderapi
 import (
 	"database/sql"
 	"fmt"
+	"github.com/blinky-z/Blog/config"
 	"github.com/blinky-z/Blog/handler/restapi"
 	"github.com/blinky-z/Blog/models"
-	"github.com/blinky-z/Blog/service/postService"
-	"github.com/blinky-z/Blog/service/tagService"
+	"github.com/blinky-z/Blog/repository"
+	"github.com/blinky-z/Blog/service/commentService"
+	"github.com/blinky-z/Blog/service/mediaService"
 	"github.com/gorilla/mux"
+	"html/template"
 	"log"
 	"net/http"
 	"net/url"
-	"path/filepath"
 	"strconv"
 	"strings"
-	"text/template"
 	"time"
 )
 
+// Handler - used for dependency injection
+// Posts, tags and users are accessed through the repositories. Database is used for comments and media
 type Handler struct {
-	db          *sql.DB
-	admins      *[]string
-	layoutsPath string
-	domain      *url.URL
-	logInfo     *log.Logger
-	logError    *log.Logger
+	db        *sql.DB
+	posts     repository.PostRepository
+	tags      repository.TagRepository
+	users     repository.UserRepository
+	templates *Templates
+	domain    *url.URL
+	site      *config.LiveSite
+	logInfo   *log.Logger
+	logError  *log.Logger
 }
 
-func NewRenderAPIHandler(db *sql.DB, layoutsPath string, domain *url.URL, logInfo, logError *log.Logger) *Handler {
+func NewRenderAPIHandler(db *sql.DB, posts repository.PostRepository, tags repository.TagRepository,
+	users repository.UserRepository, templates *Templates, domain *url.URL, site *config.LiveSite,
+	logInfo, logError *log.Logger) *Handler {
 	return &Handler{
-		db:          db,
-		layoutsPath: layoutsPath,
-		domain:      domain,
-		logInfo:     logInfo,
-		logError:    logError,
+		db:        db,
+		posts:     posts,
+		tags:      tags,
+		users:     users,
+		templates: templates,
+		domain:    domain,
+		site:      site,
+		logInfo:   logInfo,
+		logError:  logError,
 	}
 }
 
 const (
 	timeFormat           = "January 2 2006, 15:04:05"
 	recentPostsCount int = 5
-	postsPerPage     int = 10
-	siteSuffix           = " | Progbloom - A blog about programming"
+	mediaPerPage     int = 30
 )
 
 // SiteHead - represents <head> tag data
@@ -50,8 +61,9 @@ type SiteHead struct {
 	Metadata models.MetaData
 }
 
-//SiteDescription - represents site description visible on front
+// SiteDescription - represents site description visible on front
 type SiteDescription struct {
+	Name        string
 	Title       string
 	Description string
 }
@@ -72,43 +84,113 @@ type pageSelector struct {
 	HasOlderPosts  bool
 }
 
+// renderedPost - represents a post with its snippet and content marked as safe html
+// Post html is sanitized when the post is saved, so it is rendered without escaping
+type renderedPost struct {
+	models.Post
+	Snippet template.HTML
+	Content template.HTML
+}
+
+// renderedSearchResult - represents a search result with its headline marked as safe html
+// Headline is sanitized by the search, so it is rendered without escaping
+type renderedSearchResult struct {
+	models.PostSearchResult
+	Headline template.HTML
+}
+
 // indexPageData - represents index page data
 type indexPageData struct {
-	Posts []models.Post
+	Posts []renderedPost
 }
 
-// postPageData - represents a single post ("/posts/{id}") page data
+// postPageData - represents a single post ("/posts/{slug}") page data
 type postPageData struct {
-	Post models.Post
+	Post     renderedPost
+	Comments []*models.CommentWithChilds
 }
 
 // postPageData - represents all posts ("/posts") or all posts tagged with ("tags/{tag}) page data
 type allPostsPageData struct {
-	Posts        []models.Post
+	Posts        []renderedPost
 	PageSelector pageSelector
 	Type         string
 	Tag          string // set if it's the tags/{tag} page
 }
 
+// authorPageData - represents author ("/authors/{username}") page data
+type authorPageData struct {
+	Author       models.Author
+	Posts        []renderedPost
+	PageSelector pageSelector
+}
+
+// searchPageData - represents search results ("/search?q=") page data
+type searchPageData struct {
+	Query        string
+	Results      []renderedSearchResult
+	PageSelector pageSelector
+}
+
+// adminManageMediaPageData - represents data for admin dashboard media library
+type adminManageMediaPageData struct {
+	Media        []models.Media
+	PageSelector pageSelector
+}
+
 // adminEditorPageData - represents data for admin dashboard editor
 type adminEditorPageData struct {
 	Post        models.Post
 	Tags        []string
+	CoAuthors   []string
 	PostPresent bool
 }
 
-var defaultMetaKeywords = []string{"programming", "coding", "Linux", "Java", "C", "C++", "low-level programming", "algorithms",
-	"data structures"}
+// newSiteDescription - returns description of the site with the given settings
+func newSiteDescription(site config.Site) SiteDescription {
+	return SiteDescription{
+		Name:        site.Name,
+		Title:       site.Title,
+		Description: site.Description,
+	}
+}
 
-var defaultSiteDescription = SiteDescription{
-	Title:       "Progbloom 🌻",
-	Description: "A blog about programming. I write about Linux, Java and low-level programming",
+// newRenderedPost - marks sanitized html of the post as safe
+func newRenderedPost(post models.Post) renderedPost {
+	return renderedPost{
+		Post:    post,
+		Snippet: template.HTML(post.Snippet),
+		Content: template.HTML(post.Content),
+	}
+}
+
+// newRenderedPosts - marks sanitized html of the posts as safe
+func newRenderedPosts(posts []models.Post) []renderedPost {
+	renderedPosts := make([]renderedPost, len(posts))
+	for index, post := range posts {
+		renderedPosts[index] = newRenderedPost(post)
+	}
+	return renderedPosts
+}
+
+// newRenderedSearchResults - marks sanitized headlines of the search results as safe
+func newRenderedSearchResults(results []models.PostSearchResult) []renderedSearchResult {
+	renderedResults := make([]renderedSearchResult, len(results))
+	for index, result := range results {
+		renderedResults[index] = renderedSearchResult{
+			PostSearchResult: result,
+			Headline:         template.HTML(result.Headline),
+		}
+	}
+	return renderedResults
 }
 
 // functions for use in go templates
 var renderFuncs = template.FuncMap{
-	"formatTime":    formatTime,
-	"sliceToString": sliceToString,
+	"formatTime":      formatTime,
+	"formatISOTime":   formatISOTime,
+	"sliceToString":   sliceToString,
+	"allPostStatuses": allPostStatuses,
 }
 
 // formatTime - formats time.Time and returns formatted time as string
@@ -116,6 +198,19 @@ func formatTime(t time.Time) string {
 	return t.Format(timeFormat)
 }
 
+// formatISOTime - formats time in RFC 3339 format. Returns empty string if time is not set
+func formatISOTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// allPostStatuses - returns all post statuses in the order they should be displayed in
+func allPostStatuses() []models.PostStatus {
+	return []models.PostStatus{models.PostStatusDraft, models.PostStatusPublished, models.PostStatusScheduled}
+}
+
 func sliceToString(a []string) string {
 	var sb strings.Builder
 	aLen := len(a) - 1
@@ -128,52 +223,80 @@ func sliceToString(a []string) string {
 	return sb.String()
 }
 
-// RenderPostPageHandler - handler for server-side rendering of /posts/{id} page
+// redirectToPost - permanently redirects to the canonical URL of the post with the given slug
+func redirectToPost(w http.ResponseWriter, r *http.Request, slug string) {
+	http.Redirect(w, r, "/posts/"+url.PathEscape(slug), http.StatusMovedPermanently)
+}
+
+// RenderPostPageHandler - handler for server-side rendering of /posts/{slug} page
+// Old URLs with post ID or with one of the previous post slugs are redirected to the current slug
 func (renderApi *Handler) RenderPostPageHandler() http.Handler {
 	logError := renderApi.logError
-	layoutsPath := renderApi.layoutsPath
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		postID := mux.Vars(r)["id"]
-		if !restapi.IsPostIDValid(postID) {
-			restapi.Respond(w, http.StatusNotFound)
+		site := renderApi.site.Get()
+		slug := mux.Vars(r)["slug"]
+
+		if restapi.IsPostIDValid(slug) {
+			currentSlug, err := renderApi.posts.GetSlugByID(slug)
+			if err != nil {
+				switch err {
+				case sql.ErrNoRows:
+					restapi.Respond(w, http.StatusNotFound)
+					return
+				default:
+					logError.Printf("Error retrieving post slug: %s", err)
+					restapi.Respond(w, http.StatusInternalServerError)
+					return
+				}
+			}
+			redirectToPost(w, r, currentSlug)
 			return
 		}
 
-		post, err := postService.GetByID(renderApi.db, postID)
+		post, err := renderApi.posts.GetBySlug(slug)
 		if err != nil {
-			switch err {
-			case sql.ErrNoRows:
-				restapi.Respond(w, http.StatusNotFound)
-				return
-			default:
+			if err != sql.ErrNoRows {
+				logError.Printf("Error retrieving post: %s", err)
 				restapi.Respond(w, http.StatusInternalServerError)
 				return
 			}
+
+			currentSlug, err := renderApi.posts.GetSlugByOldSlug(slug)
+			if err != nil {
+				switch err {
+				case sql.ErrNoRows:
+					restapi.Respond(w, http.StatusNotFound)
+					return
+				default:
+					logError.Printf("Error retrieving post slug: %s", err)
+					restapi.Respond(w, http.StatusInternalServerError)
+					return
+				}
+			}
+			redirectToPost(w, r, currentSlug)
+			return
 		}
 
-		tmpl, err := template.New("post").Funcs(renderFuncs).
-			ParseFiles(
-				layoutsPath+"partials/head.html",
-				layoutsPath+"partials/header.html",
-				layoutsPath+"partials/footer.html",
-				layoutsPath+"post.html")
+		comments, err := commentService.GetTreeByPostID(renderApi.db, post.ID)
 		if err != nil {
+			logError.Printf("Error retrieving post comments: %s", err)
 			restapi.Respond(w, http.StatusInternalServerError)
 			return
 		}
 
 		var data Site
 		data.Head = SiteHead{
-			Title:    post.Title + siteSuffix,
+			Title:    post.Title + site.TitleSuffix(),
 			Metadata: post.Metadata,
 		}
 		data.Domain = renderApi.domain
-		data.Desc = defaultSiteDescription
+		data.Desc = newSiteDescription(site)
 		data.Data = postPageData{
-			Post: post,
+			Post:     newRenderedPost(post),
+			Comments: comments,
 		}
 
-		if err := tmpl.ExecuteTemplate(w, "post", data); err != nil {
+		if err := renderApi.templates.Execute(w, "post", data); err != nil {
 			logError.Printf("Error rendering single post page: %s", err)
 			restapi.Respond(w, http.StatusInternalServerError)
 		}
@@ -183,42 +306,30 @@ func (renderApi *Handler) RenderPostPageHandler() http.Handler {
 // renderIndexPageHandler - handler for server-side rendering of index page
 func (renderApi *Handler) RenderIndexPageHandler() http.Handler {
 	logError := renderApi.logError
-	layoutsPath := renderApi.layoutsPath
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		posts, err := postService.GetPostsInRange(renderApi.db, 0, recentPostsCount)
+		site := renderApi.site.Get()
+		posts, err := renderApi.posts.GetPostsInRange(0, recentPostsCount)
 		if err != nil {
 			renderApi.logInfo.Printf("Error retrieving posts: %s", err)
 			restapi.Respond(w, http.StatusInternalServerError)
 			return
 		}
 
-		tmpl, err := template.New("index").Funcs(renderFuncs).
-			ParseFiles(
-				layoutsPath+filepath.FromSlash("partials/head.html"),
-				layoutsPath+filepath.FromSlash("partials/header.html"),
-				layoutsPath+filepath.FromSlash("partials/footer.html"),
-				layoutsPath+"index.html")
-		if err != nil {
-			logError.Printf("Error allocating index template: %s", err)
-			restapi.Respond(w, http.StatusInternalServerError)
-			return
-		}
-
 		var data Site
 		data.Head = SiteHead{
-			Title: "Home" + siteSuffix,
+			Title: "Home" + site.TitleSuffix(),
 			Metadata: models.MetaData{
-				Description: "Progbloom - A blog about programming. I write about Linux, Java and low-level programming. Recent posts",
-				Keywords:    defaultMetaKeywords,
+				Description: site.PageDescription("Recent posts"),
+				Keywords:    site.Keywords,
 			},
 		}
 		data.Domain = renderApi.domain
-		data.Desc = defaultSiteDescription
+		data.Desc = newSiteDescription(site)
 		data.Data = indexPageData{
-			Posts: posts,
+			Posts: newRenderedPosts(posts),
 		}
 
-		if err := tmpl.ExecuteTemplate(w, "index", data); err != nil {
+		if err := renderApi.templates.Execute(w, "index", data); err != nil {
 			logError.Printf("Error rendering index page: %s", err)
 			restapi.Respond(w, http.StatusInternalServerError)
 		}
@@ -228,8 +339,8 @@ func (renderApi *Handler) RenderIndexPageHandler() http.Handler {
 // RenderAllPostsPageHandler - handler for server-side rendering of all posts and tagged posts page
 func (renderApi *Handler) RenderAllPostsPageHandler() http.Handler {
 	logError := renderApi.logError
-	layoutsPath := renderApi.layoutsPath
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site := renderApi.site.Get()
 		rangeParams := &restapi.GetPostsRequestQueryParams{
 			Page:         r.FormValue("page"),
 			PostsPerPage: "",
@@ -251,51 +362,39 @@ func (renderApi *Handler) RenderAllPostsPageHandler() http.Handler {
 		var posts []models.Post
 		var err error
 		if tag != "" {
-			posts, err = postService.GetPostsInRangeByTag(renderApi.db, page*postsPerPage, postsPerPage+1, tag)
+			posts, err = renderApi.posts.GetPostsInRangeByTag(page*site.PostsPerPage, site.PostsPerPage+1, tag)
 		} else {
-			posts, err = postService.GetPostsInRange(renderApi.db, page*postsPerPage, postsPerPage+1)
+			posts, err = renderApi.posts.GetPostsInRange(page*site.PostsPerPage, site.PostsPerPage+1)
 		}
 		if err != nil {
 			restapi.Respond(w, http.StatusInternalServerError)
 			return
 		}
 
-		tmpl, err := template.New("all-posts").Funcs(renderFuncs).
-			ParseFiles(
-				layoutsPath+filepath.FromSlash("partials/head.html"),
-				layoutsPath+filepath.FromSlash("partials/header.html"),
-				layoutsPath+filepath.FromSlash("partials/footer.html"),
-				layoutsPath+"all-posts.html")
-		if err != nil {
-			logError.Printf("Error rendering all posts/tagged page: %s", err)
-			restapi.Respond(w, http.StatusInternalServerError)
-			return
-		}
-
 		var data Site
 
 		var Title string
 		if tag != "" {
-			Title = "Posts tagged with " + tag + siteSuffix
+			Title = "Posts tagged with " + tag + site.TitleSuffix()
 		} else {
-			Title = "All Posts" + " | Progbloom - A blog about programming"
+			Title = "All Posts" + site.TitleSuffix()
 		}
 
 		data.Head = SiteHead{
 			Title: Title,
 			Metadata: models.MetaData{
-				Description: "Progbloom - A blog about programming. All posts",
-				Keywords:    defaultMetaKeywords,
+				Description: site.PageDescription("All posts"),
+				Keywords:    site.Keywords,
 			},
 		}
 		data.Domain = renderApi.domain
-		data.Desc = defaultSiteDescription
+		data.Desc = newSiteDescription(site)
 
 		pageSelector := pageSelector{}
 		if page != 0 {
 			pageSelector.HasNewerPosts = true
 			if tag != "" {
-				pageSelector.NewerPostsLink = fmt.Sprintf("/tags/%s?page=%d", tag, page-1)
+				pageSelector.NewerPostsLink = fmt.Sprintf("/tags/%s?page=%d", url.PathEscape(tag), page-1)
 			} else {
 				pageSelector.NewerPostsLink = fmt.Sprintf("/posts?page=%d", page-1)
 			}
@@ -304,22 +403,22 @@ func (renderApi *Handler) RenderAllPostsPageHandler() http.Handler {
 		}
 
 		// hack here: if we were able to retrieve more posts than default value, then we have older posts
-		if len(posts) > postsPerPage {
+		if len(posts) > site.PostsPerPage {
 			pageSelector.HasOlderPosts = true
 			if tag != "" {
-				pageSelector.OlderPostsLink = fmt.Sprintf("/tags/%s?page=%d", tag, page+1)
+				pageSelector.OlderPostsLink = fmt.Sprintf("/tags/%s?page=%d", url.PathEscape(tag), page+1)
 			} else {
 				pageSelector.OlderPostsLink = fmt.Sprintf("/posts?page=%d", page+1)
 			}
 
 			// remove very last post, as we need less posts
-			posts = posts[:postsPerPage]
+			posts = posts[:site.PostsPerPage]
 		} else {
 			pageSelector.HasOlderPosts = false
 		}
 
 		allPostsPageData := allPostsPageData{}
-		allPostsPageData.Posts = posts
+		allPostsPageData.Posts = newRenderedPosts(posts)
 		allPostsPageData.PageSelector = pageSelector
 		if tag != "" {
 			allPostsPageData.Type = "Tagged"
@@ -328,30 +427,168 @@ func (renderApi *Handler) RenderAllPostsPageHandler() http.Handler {
 
 		data.Data = allPostsPageData
 
-		if err := tmpl.ExecuteTemplate(w, "all-posts", data); err != nil {
+		if err := renderApi.templates.Execute(w, "all-posts", data); err != nil {
 			logError.Printf("Error rendering all posts/tagged page: %s", err)
 			restapi.Respond(w, http.StatusInternalServerError)
 		}
 	})
 }
 
-// RenderAllTagsPageHandler - handler for server-side rendering of all tags page
-func (renderApi *Handler) RenderAllTagsPageHandler() http.Handler {
+// RenderAuthorPageHandler - handler for server-side rendering of author page with author profile and posts
+func (renderApi *Handler) RenderAuthorPageHandler() http.Handler {
 	logError := renderApi.logError
-	layoutsPath := renderApi.layoutsPath
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tmpl, err := template.New("all-tags").Funcs(renderFuncs).
-			ParseFiles(
-				layoutsPath+filepath.FromSlash("partials/head.html"),
-				layoutsPath+filepath.FromSlash("partials/header.html"),
-				layoutsPath+filepath.FromSlash("partials/footer.html"),
-				layoutsPath+"all-tags.html")
+		site := renderApi.site.Get()
+		rangeParams := &restapi.GetPostsRequestQueryParams{
+			Page:         r.FormValue("page"),
+			PostsPerPage: "",
+		}
+
+		validateQueryParamsError := restapi.ValidateGetPostsRequestQueryParams(rangeParams)
+		if validateQueryParamsError != nil {
+			restapi.Respond(w, http.StatusNotFound)
+			return
+		}
+		page, _ := strconv.Atoi(rangeParams.Page)
+
+		username := mux.Vars(r)["username"]
+		author, err := renderApi.users.GetAuthorByUsername(username)
 		if err != nil {
+			if err == sql.ErrNoRows {
+				restapi.Respond(w, http.StatusNotFound)
+				return
+			}
+			logError.Printf("Error retrieving author: %s", err)
 			restapi.Respond(w, http.StatusInternalServerError)
 			return
 		}
 
-		allTags, err := tagService.GetAll(renderApi.db)
+		posts, err := renderApi.posts.GetPostsInRangeByAuthor(page*site.PostsPerPage, site.PostsPerPage+1, username)
+		if err != nil {
+			logError.Printf("Error retrieving author posts: %s", err)
+			restapi.Respond(w, http.StatusInternalServerError)
+			return
+		}
+
+		var data Site
+		description := author.Bio
+		if description == "" {
+			description = site.PageDescription("Posts by " + author.Name())
+		}
+		data.Head = SiteHead{
+			Title: author.Name() + site.TitleSuffix(),
+			Metadata: models.MetaData{
+				Description: description,
+				Keywords:    site.Keywords,
+			},
+		}
+		data.Domain = renderApi.domain
+		data.Desc = newSiteDescription(site)
+
+		authorPath := "/authors/" + url.PathEscape(username)
+		pageSelector := pageSelector{}
+		if page != 0 {
+			pageSelector.HasNewerPosts = true
+			pageSelector.NewerPostsLink = fmt.Sprintf("%s?page=%d", authorPath, page-1)
+		}
+		if len(posts) > site.PostsPerPage {
+			pageSelector.HasOlderPosts = true
+			pageSelector.OlderPostsLink = fmt.Sprintf("%s?page=%d", authorPath, page+1)
+			posts = posts[:site.PostsPerPage]
+		}
+
+		data.Data = authorPageData{
+			Author:       author,
+			Posts:        newRenderedPosts(posts),
+			PageSelector: pageSelector,
+		}
+
+		if err := renderApi.templates.Execute(w, "author", data); err != nil {
+			logError.Printf("Error rendering author page: %s", err)
+			restapi.Respond(w, http.StatusInternalServerError)
+		}
+	})
+}
+
+// RenderSearchPageHandler - handler for server-side rendering of search results page
+func (renderApi *Handler) RenderSearchPageHandler() http.Handler {
+	logError := renderApi.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site := renderApi.site.Get()
+		query := strings.TrimSpace(r.FormValue("q"))
+		rangeParams := &restapi.GetPostsRequestQueryParams{
+			Page:         r.FormValue("page"),
+			PostsPerPage: "",
+		}
+
+		validateQueryParamsError := restapi.ValidateGetPostsRequestQueryParams(rangeParams)
+		if validateQueryParamsError != nil {
+			restapi.Respond(w, http.StatusNotFound)
+			return
+		}
+		page, _ := strconv.Atoi(rangeParams.Page)
+
+		// empty query shows just the search form
+		results := make([]models.PostSearchResult, 0)
+		if query != "" {
+			if restapi.ValidateSearchQuery(query) != nil {
+				restapi.Respond(w, http.StatusBadRequest)
+				return
+			}
+
+			var err error
+			results, err = renderApi.posts.Search(query, page*site.PostsPerPage, site.PostsPerPage+1)
+			if err != nil {
+				logError.Printf("Error searching posts: %s", err)
+				restapi.Respond(w, http.StatusInternalServerError)
+				return
+			}
+		}
+
+		var data Site
+		data.Head = SiteHead{
+			Title: "Search" + site.TitleSuffix(),
+			Metadata: models.MetaData{
+				Description: site.PageDescription("Search"),
+				Keywords:    site.Keywords,
+			},
+		}
+		data.Domain = renderApi.domain
+		data.Desc = newSiteDescription(site)
+
+		escapedQuery := url.QueryEscape(query)
+		pageSelector := pageSelector{}
+		if page != 0 {
+			pageSelector.HasNewerPosts = true
+			pageSelector.NewerPostsLink = fmt.Sprintf("/search?q=%s&page=%d", escapedQuery, page-1)
+		}
+		// if we were able to retrieve more results than default value, then we have more results
+		if len(results) > site.PostsPerPage {
+			pageSelector.HasOlderPosts = true
+			pageSelector.OlderPostsLink = fmt.Sprintf("/search?q=%s&page=%d", escapedQuery, page+1)
+
+			results = results[:site.PostsPerPage]
+		}
+
+		data.Data = searchPageData{
+			Query:        query,
+			Results:      newRenderedSearchResults(results),
+			PageSelector: pageSelector,
+		}
+
+		if err := renderApi.templates.Execute(w, "search", data); err != nil {
+			logError.Printf("Error rendering search page: %s", err)
+			restapi.Respond(w, http.StatusInternalServerError)
+		}
+	})
+}
+
+// RenderAllTagsPageHandler - handler for server-side rendering of all tags page
+func (renderApi *Handler) RenderAllTagsPageHandler() http.Handler {
+	logError := renderApi.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site := renderApi.site.Get()
+		allTags, err := renderApi.tags.GetAll()
 		if err != nil {
 			restapi.Respond(w, http.StatusInternalServerError)
 			return
@@ -363,110 +600,105 @@ func (renderApi *Handler) RenderAllTagsPageHandler() http.Handler {
 
 		var data Site
 		data.Head = SiteHead{
-			Title: "Tags Cloud" + siteSuffix,
+			Title: "Tags Cloud" + site.TitleSuffix(),
 			Metadata: models.MetaData{
-				Description: "Progbloom - A blog about programming. All tags",
-				Keywords:    defaultMetaKeywords,
+				Description: site.PageDescription("All tags"),
+				Keywords:    site.Keywords,
 			},
 		}
 		data.Domain = renderApi.domain
-		data.Desc = defaultSiteDescription
+		data.Desc = newSiteDescription(site)
 		data.Data = struct {
 			Tags []string
 		}{
 			Tags: allTagsAsStringSlice,
 		}
 
-		if err := tmpl.ExecuteTemplate(w, "all-tags", data); err != nil {
+		if err := renderApi.templates.Execute(w, "all-tags", data); err != nil {
 			logError.Printf("Error rendering all tags page: %s", err)
 			restapi.Respond(w, http.StatusInternalServerError)
 		}
 	})
 }
 
-//RenderAboutPageHandler - handler for server-side rendering of about page
+// RenderAboutPageHandler - handler for server-side rendering of about page
 func (renderApi *Handler) RenderAboutPageHandler() http.Handler {
 	logError := renderApi.logError
-	layoutsPath := renderApi.layoutsPath
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tmpl, err := template.New("about").Funcs(renderFuncs).
-			ParseFiles(
-				layoutsPath+filepath.FromSlash("partials/head.html"),
-				layoutsPath+filepath.FromSlash("partials/header.html"),
-				layoutsPath+filepath.FromSlash("partials/footer.html"),
-				layoutsPath+"about.html")
-		if err != nil {
-			restapi.Respond(w, http.StatusInternalServerError)
-			return
-		}
-
+		site := renderApi.site.Get()
 		var data Site
 		data.Head = SiteHead{
-			Title: "About" + siteSuffix,
+			Title: "About" + site.TitleSuffix(),
 			Metadata: models.MetaData{
-				Description: "Progbloom - A blog about programming. About my site",
-				Keywords:    defaultMetaKeywords,
+				Description: site.PageDescription("About my site"),
+				Keywords:    site.Keywords,
 			},
 		}
 		data.Domain = renderApi.domain
-		data.Desc = defaultSiteDescription
+		data.Desc = newSiteDescription(site)
+		// about page is written by the site owner in the config file, so it is trusted
 		data.Data = struct {
-			Content string
+			Content template.HTML
 		}{
-			Content: `Приветствую на моем сайте! Я пишу о Linux, Java и низкоуровневом программировании.
-				<br>
-				<hr>
-				<b>Мои труды:</b>
-				<ul>
-			<li><a href="https://habr.com/ru/post/460257/">Hello, World! Глубокое погружение в Терминалы</a></li>
-			</ul>`,
+			Content: template.HTML(site.About),
 		}
 
-		if err := tmpl.ExecuteTemplate(w, "about", data); err != nil {
+		if err := renderApi.templates.Execute(w, "about", data); err != nil {
 			logError.Printf("Error rendering about page: %s", err)
 			restapi.Respond(w, http.StatusInternalServerError)
 		}
 	})
 }
 
-//RenderAdminPageHandler - handler for server-side rendering of admin dashboard page
-func (renderApi *Handler) RenderAdminPageHandler() http.Handler {
+// RenderAdminLoginPageHandler - handler for server-side rendering of admin dashboard login page
+func (renderApi *Handler) RenderAdminLoginPageHandler() http.Handler {
 	logError := renderApi.logError
-	layoutsPath := renderApi.layoutsPath
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tmpl, err := template.New("admin").Funcs(renderFuncs).
-			ParseFiles(
-				layoutsPath+filepath.FromSlash("partials/head.html"),
-				layoutsPath+filepath.FromSlash("partials/header.html"),
-				layoutsPath+filepath.FromSlash("partials/footer.html"),
-				layoutsPath+"admin.html")
-		if err != nil {
+		site := renderApi.site.Get()
+		var data Site
+
+		data.Head = SiteHead{
+			Title:    "Admin Dashboard - Log In" + site.TitleSuffix(),
+			Metadata: models.MetaData{},
+		}
+		data.Domain = renderApi.domain
+		data.Desc = newSiteDescription(site)
+		data.Data = nil
+
+		if err := renderApi.templates.Execute(w, "admin-login", data); err != nil {
+			logError.Printf("Error rendering admin login page: %s", err)
 			restapi.Respond(w, http.StatusInternalServerError)
-			return
 		}
+	})
+}
 
+// RenderAdminPageHandler - handler for server-side rendering of admin dashboard page
+func (renderApi *Handler) RenderAdminPageHandler() http.Handler {
+	logError := renderApi.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site := renderApi.site.Get()
 		var data Site
 
 		data.Head = SiteHead{
-			Title:    "Admin Dashboard" + siteSuffix,
+			Title:    "Admin Dashboard" + site.TitleSuffix(),
 			Metadata: models.MetaData{},
 		}
 		data.Domain = renderApi.domain
-		data.Desc = defaultSiteDescription
+		data.Desc = newSiteDescription(site)
 		data.Data = nil
 
-		if err := tmpl.ExecuteTemplate(w, "admin", data); err != nil {
+		if err := renderApi.templates.Execute(w, "admin", data); err != nil {
 			logError.Printf("Error rendering about page: %s", err)
 			restapi.Respond(w, http.StatusInternalServerError)
 		}
 	})
 }
 
-//RenderAdminPageHandler - handler for server-side rendering of admin dashboard editor page
+// RenderAdminPageHandler - handler for server-side rendering of admin dashboard editor page
 func (renderApi *Handler) RenderAdminEditorPageHandler() http.Handler {
 	logError := renderApi.logError
-	layoutsPath := renderApi.layoutsPath
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site := renderApi.site.Get()
 		adminEditorPageData := adminEditorPageData{}
 		postID := r.FormValue("id")
 		if postID != "" {
@@ -475,7 +707,7 @@ func (renderApi *Handler) RenderAdminEditorPageHandler() http.Handler {
 				return
 			}
 
-			post, err := postService.GetByIDWithMarkdownContent(renderApi.db, postID)
+			post, err := renderApi.posts.GetByIDWithMarkdownContent(postID)
 			if err != nil {
 				switch err {
 				case sql.ErrNoRows:
@@ -488,12 +720,19 @@ func (renderApi *Handler) RenderAdminEditorPageHandler() http.Handler {
 			}
 			adminEditorPageData.Post = post
 			adminEditorPageData.PostPresent = true
+
+			coAuthors, err := renderApi.posts.GetCoAuthorsByPostID(postID)
+			if err != nil {
+				restapi.Respond(w, http.StatusInternalServerError)
+				return
+			}
+			adminEditorPageData.CoAuthors = coAuthors
 		} else {
 			adminEditorPageData.Post = models.Post{}
 			adminEditorPageData.PostPresent = false
 		}
 
-		allTags, err := tagService.GetAll(renderApi.db)
+		allTags, err := renderApi.tags.GetAll()
 		if err != nil {
 			restapi.Respond(w, http.StatusInternalServerError)
 			return
@@ -504,28 +743,17 @@ func (renderApi *Handler) RenderAdminEditorPageHandler() http.Handler {
 		}
 		adminEditorPageData.Tags = allTagsAsStringSlice
 
-		tmpl, err := template.New("admin-editor").Funcs(renderFuncs).
-			ParseFiles(
-				layoutsPath+filepath.FromSlash("partials/head.html"),
-				layoutsPath+filepath.FromSlash("partials/header.html"),
-				layoutsPath+filepath.FromSlash("partials/footer.html"),
-				layoutsPath+"admin/editor.html")
-		if err != nil {
-			restapi.Respond(w, http.StatusInternalServerError)
-			return
-		}
-
 		var data Site
 
 		data.Head = SiteHead{
-			Title:    "Admin Dashboard - Editor" + siteSuffix,
+			Title:    "Admin Dashboard - Editor" + site.TitleSuffix(),
 			Metadata: models.MetaData{},
 		}
 		data.Domain = renderApi.domain
-		data.Desc = defaultSiteDescription
+		data.Desc = newSiteDescription(site)
 		data.Data = adminEditorPageData
 
-		if err := tmpl.ExecuteTemplate(w, "admin-editor", data); err != nil {
+		if err := renderApi.templates.Execute(w, "admin-editor", data); err != nil {
 			logError.Printf("Error rendering admin editor page: %s", err)
 			restapi.Respond(w, http.StatusInternalServerError)
 		}
@@ -535,8 +763,8 @@ func (renderApi *Handler) RenderAdminEditorPageHandler() http.Handler {
 // RenderAllPostsPageHandler - handler for server-side rendering of admin dashboard posts managing page
 func (renderApi *Handler) RenderAdminManagePostsPageHandler() http.Handler {
 	logError := renderApi.logError
-	layoutsPath := renderApi.layoutsPath
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site := renderApi.site.Get()
 		rangeParams := &restapi.GetPostsRequestQueryParams{
 			Page:         r.FormValue("page"),
 			PostsPerPage: "",
@@ -549,20 +777,16 @@ func (renderApi *Handler) RenderAdminManagePostsPageHandler() http.Handler {
 		}
 		page, _ := strconv.Atoi(rangeParams.Page)
 
-		posts, err := postService.GetPostsInRange(renderApi.db, page*postsPerPage, postsPerPage+1)
-		if err != nil {
-			restapi.Respond(w, http.StatusInternalServerError)
-			return
+		// users that can't manage all posts see only posts they write
+		var posts []models.Post
+		var err error
+		if restapi.HasPermission(restapi.GetUserRole(r), restapi.PermissionManagePosts) {
+			posts, err = renderApi.posts.GetPostsInRangeWithAnyStatus(page*site.PostsPerPage, site.PostsPerPage+1)
+		} else {
+			posts, err = renderApi.posts.GetPostsInRangeWithAnyStatusByAuthor(page*site.PostsPerPage,
+				site.PostsPerPage+1, restapi.GetUsername(r))
 		}
-
-		tmpl, err := template.New("admin-manage-posts").Funcs(renderFuncs).
-			ParseFiles(
-				layoutsPath+filepath.FromSlash("partials/head.html"),
-				layoutsPath+filepath.FromSlash("partials/header.html"),
-				layoutsPath+filepath.FromSlash("partials/footer.html"),
-				layoutsPath+"admin/manage-posts.html")
 		if err != nil {
-			logError.Printf("Error allocating admin dashboard posts managing page: %s", err)
 			restapi.Respond(w, http.StatusInternalServerError)
 			return
 		}
@@ -570,11 +794,11 @@ func (renderApi *Handler) RenderAdminManagePostsPageHandler() http.Handler {
 		var data Site
 
 		data.Head = SiteHead{
-			Title:    "Admin Dashboard - Manage posts" + " | Progbloom - A blog about programming",
+			Title:    "Admin Dashboard - Manage posts" + site.TitleSuffix(),
 			Metadata: models.MetaData{},
 		}
 		data.Domain = renderApi.domain
-		data.Desc = defaultSiteDescription
+		data.Desc = newSiteDescription(site)
 
 		pageSelector := pageSelector{}
 		if page != 0 {
@@ -585,23 +809,23 @@ func (renderApi *Handler) RenderAdminManagePostsPageHandler() http.Handler {
 		}
 
 		// hack here: if we were able to retrieve more posts than default value, then we have older posts
-		if len(posts) > postsPerPage {
+		if len(posts) > site.PostsPerPage {
 			pageSelector.HasOlderPosts = true
 			pageSelector.OlderPostsLink = fmt.Sprintf("/manage-posts?page=%d", page+1)
 
 			// remove very last post, as we need less posts
-			posts = posts[:postsPerPage]
+			posts = posts[:site.PostsPerPage]
 		} else {
 			pageSelector.HasOlderPosts = false
 		}
 
 		allPostsPageData := allPostsPageData{}
-		allPostsPageData.Posts = posts
+		allPostsPageData.Posts = newRenderedPosts(posts)
 		allPostsPageData.PageSelector = pageSelector
 
 		data.Data = allPostsPageData
 
-		if err := tmpl.ExecuteTemplate(w, "admin-manage-posts", data); err != nil {
+		if err := renderApi.templates.Execute(w, "admin-manage-posts", data); err != nil {
 			logError.Printf("Error rendering admin dashboard posts managing page: %s", err)
 			restapi.Respond(w, http.StatusInternalServerError)
 		}
@@ -610,21 +834,9 @@ func (renderApi *Handler) RenderAdminManagePostsPageHandler() http.Handler {
 
 func (renderApi *Handler) RenderAdminManageTagsPageHandler() http.Handler {
 	logError := renderApi.logError
-	layoutsPath := renderApi.layoutsPath
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tmpl, err := template.New("admin-manage-tags").Funcs(renderFuncs).
-			ParseFiles(
-				layoutsPath+filepath.FromSlash("partials/head.html"),
-				layoutsPath+filepath.FromSlash("partials/header.html"),
-				layoutsPath+filepath.FromSlash("partials/footer.html"),
-				layoutsPath+"admin/manage-tags.html")
-		if err != nil {
-			logError.Printf("Error allocating admin dashboard tags managing page: %s", err)
-			restapi.Respond(w, http.StatusInternalServerError)
-			return
-		}
-
-		tags, err := tagService.GetAll(renderApi.db)
+		site := renderApi.site.Get()
+		tags, err := renderApi.tags.GetAll()
 		if err != nil {
 			logError.Printf("Error retrieving all tags: %s", err)
 			restapi.Respond(w, http.StatusInternalServerError)
@@ -633,20 +845,76 @@ func (renderApi *Handler) RenderAdminManageTagsPageHandler() http.Handler {
 
 		var data Site
 		data.Head = SiteHead{
-			Title:    "Admin Dashboard - Manage tags" + " | Progbloom - A blog about programming",
+			Title:    "Admin Dashboard - Manage tags" + site.TitleSuffix(),
 			Metadata: models.MetaData{},
 		}
 		data.Domain = renderApi.domain
-		data.Desc = defaultSiteDescription
+		data.Desc = newSiteDescription(site)
 		data.Data = struct {
 			Tags []models.Tag
 		}{
 			Tags: tags,
 		}
 
-		if err := tmpl.ExecuteTemplate(w, "admin-manage-tags", data); err != nil {
+		if err := renderApi.templates.Execute(w, "admin-manage-tags", data); err != nil {
 			logError.Printf("Error rendering admin dashboard tags managing page: %s", err)
 			restapi.Respond(w, http.StatusInternalServerError)
 		}
 	})
 }
+
+// RenderAdminManageMediaPageHandler - handler for server-side rendering of admin dashboard media library
+func (renderApi *Handler) RenderAdminManageMediaPageHandler() http.Handler {
+	logError := renderApi.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site := renderApi.site.Get()
+		rangeParams := &restapi.GetPostsRequestQueryParams{
+			Page:         r.FormValue("page"),
+			PostsPerPage: "",
+		}
+
+		validateQueryParamsError := restapi.ValidateGetPostsRequestQueryParams(rangeParams)
+		if validateQueryParamsError != nil {
+			restapi.Respond(w, http.StatusNotFound)
+			return
+		}
+		page, _ := strconv.Atoi(rangeParams.Page)
+
+		media, err := mediaService.GetInRange(renderApi.db, page*mediaPerPage, mediaPerPage+1)
+		if err != nil {
+			logError.Printf("Error retrieving uploaded files: %s", err)
+			restapi.Respond(w, http.StatusInternalServerError)
+			return
+		}
+
+		var data Site
+
+		data.Head = SiteHead{
+			Title:    "Admin Dashboard - Media library" + site.TitleSuffix(),
+			Metadata: models.MetaData{},
+		}
+		data.Domain = renderApi.domain
+		data.Desc = newSiteDescription(site)
+
+		pageSelector := pageSelector{}
+		if page != 0 {
+			pageSelector.HasNewerPosts = true
+			pageSelector.NewerPostsLink = fmt.Sprintf("/manage-media?page=%d", page-1)
+		}
+		if len(media) > mediaPerPage {
+			pageSelector.HasOlderPosts = true
+			pageSelector.OlderPostsLink = fmt.Sprintf("/manage-media?page=%d", page+1)
+			media = media[:mediaPerPage]
+		}
+
+		data.Data = adminManageMediaPageData{
+			Media:        media,
+			PageSelector: pageSelector,
+		}
+
+		if err := renderApi.templates.Execute(w, "admin-manage-media", data); err != nil {
+			logError.Printf("Error rendering admin dashboard media library page: %s", err)
+			restapi.Respond(w, http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package renderapi
+
+import (
+	"context"
+	"github.com/blinky-z/Blog/assets"
+	"github.com/blinky-z/Blog/config"
+	"github.com/blinky-z/Blog/handler/restapi"
+	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/repository/memory"
+	"github.com/blinky-z/Blog/service/postService"
+	"github.com/blinky-z/Blog/themes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAuthor = "author"
+
+// testEnv - render handler together with the repositories it reads
+type testEnv struct {
+	handler *Handler
+	posts   *memory.PostRepository
+	tags    *memory.TagRepository
+	users   *memory.UserRepository
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	store := memory.NewStore()
+	posts := memory.NewPostRepository(store)
+	tags := memory.NewTagRepository(store)
+	users := memory.NewUserRepository(store)
+	_ = users.Save(testAuthor, "author@example.com", "password", models.RoleAdmin)
+
+	domain, _ := url.Parse("http://example.com")
+	site := config.NewLiveSite(config.Site{Name: "Test Blog", Title: "Test Blog", PostsPerPage: 10})
+	logger := log.New(ioutil.Discard, "", 0)
+	siteTheme, err := themes.Load(themes.Embedded(), themes.DefaultName)
+	require.NoError(t, err)
+	siteAssets, err := assets.New(siteTheme.StaticFiles(), true)
+	require.NoError(t, err)
+	templates, err := NewTemplates(siteTheme, siteAssets, logger, logger)
+	require.NoError(t, err)
+	handler := NewRenderAPIHandler(nil, posts, tags, users, templates, domain, site, logger, logger)
+	return &testEnv{handler: handler, posts: posts, tags: tags, users: users}
+}
+
+func (env *testEnv) savePost(t *testing.T, title string, status models.PostStatus, tags ...string) *models.Post {
+	post, err := env.posts.Save(&postService.SaveRequest{
+		Title:     title,
+		ContentMD: "Snippet of " + title + "<cut>Content of " + title,
+		Tags:      tags,
+		Status:    status,
+		Author:    testAuthor,
+	})
+	require.NoError(t, err)
+	return post
+}
+
+// renderPage - serves GET request by the handler and returns response code and body
+func renderPage(handler http.Handler, path string, vars map[string]string) (*httptest.ResponseRecorder, string) {
+	request := mux.SetURLVars(httptest.NewRequest("GET", path, nil), vars)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder, recorder.Body.String()
+}
+
+func TestAllPostsPageContainsOnlyPublishedPosts(t *testing.T) {
+	env := newTestEnv(t)
+	published := env.savePost(t, "Published Post", models.PostStatusPublished)
+	draft := env.savePost(t, "Draft Post", models.PostStatusDraft)
+
+	recorder, body := renderPage(env.handler.RenderAllPostsPageHandler(), "/posts", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.True(t, strings.Contains(body, "/posts/"+published.Slug))
+	require.False(t, strings.Contains(body, "/posts/"+draft.Slug))
+}
+
+func TestTagPageContainsOnlyTaggedPosts(t *testing.T) {
+	env := newTestEnv(t)
+	tagged := env.savePost(t, "Tagged Post", models.PostStatusPublished, "golang")
+	notTagged := env.savePost(t, "Not Tagged Post", models.PostStatusPublished)
+
+	recorder, body := renderPage(env.handler.RenderAllPostsPageHandler(), "/tags/golang",
+		map[string]string{"tag": "golang"})
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.True(t, strings.Contains(body, "/posts/"+tagged.Slug))
+	require.False(t, strings.Contains(body, "/posts/"+notTagged.Slug))
+}
+
+func TestPostPageRedirectsFromOldSlug(t *testing.T) {
+	env := newTestEnv(t)
+	post := env.savePost(t, "Post With Old Slug", models.PostStatusPublished)
+	_, err := env.posts.Update(&postService.UpdateRequest{
+		ID:        post.ID,
+		Title:     post.Title,
+		Slug:      "new-slug",
+		ContentMD: "Snippet<cut>Content",
+		Status:    models.PostStatusPublished,
+	})
+	require.NoError(t, err)
+
+	recorder, _ := renderPage(env.handler.RenderPostPageHandler(), "/posts/"+post.Slug,
+		map[string]string{"slug": post.Slug})
+	require.Equal(t, http.StatusMovedPermanently, recorder.Code)
+	require.True(t, strings.HasSuffix(recorder.Header().Get("Location"), "/posts/new-slug"))
+}
+
+func TestSitemapContainsPostsAndTags(t *testing.T) {
+	env := newTestEnv(t)
+	post := env.savePost(t, "Sitemap Post", models.PostStatusPublished, "sitemap-tag")
+
+	recorder, body := renderPage(env.handler.RenderSitemapHandler(), "/sitemap.xml", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.True(t, strings.Contains(body, "/posts/"+post.Slug))
+	require.True(t, strings.Contains(body, "/tags/sitemap-tag"))
+}
+
+func TestSitemapPageLastModifiedComparesTimesInDifferentZones(t *testing.T) {
+	// 10:00 in UTC+3 is earlier than 08:00 in UTC, though its RFC 3339 string is greater
+	earlier := time.Date(2020, time.January, 1, 10, 0, 0, 0, time.FixedZone("UTC+3", 3*60*60))
+	later := time.Date(2020, time.January, 1, 8, 0, 0, 0, time.UTC)
+	urls := []sitemapURL{newSitemapURL("/earlier", earlier, "", ""), newSitemapURL("/later", later, "", "")}
+
+	require.True(t, later.Equal(sitemapPageLastModified(urls)))
+	require.Equal(t, "2020-01-01T07:00:00Z", urls[0].LastMod)
+}
+
+func TestFeedContainsRenamedTag(t *testing.T) {
+	env := newTestEnv(t)
+	post := env.savePost(t, "Feed Post", models.PostStatusPublished, "old-name")
+	allTags, err := env.tags.GetAll()
+	require.NoError(t, err)
+	_, err = env.tags.Update(allTags[0].ID, "new-name")
+	require.NoError(t, err)
+
+	recorder, body := renderPage(env.handler.RenderRSSFeedHandler(), "/tags/new-name/feed.xml",
+		map[string]string{"tag": "new-name"})
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.True(t, strings.Contains(body, "/posts/"+post.Slug))
+}
+
+func TestFeedIsModifiedAfterPostDeletion(t *testing.T) {
+	env := newTestEnv(t)
+	env.savePost(t, "Old Post", models.PostStatusPublished)
+	newPost := env.savePost(t, "New Post", models.PostStatusPublished)
+
+	recorder, _ := renderPage(env.handler.RenderRSSFeedHandler(), "/feed.xml", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	lastModified := recorder.Header().Get("Last-Modified")
+
+	// Last-Modified has one second precision
+	time.Sleep(time.Second)
+	require.NoError(t, env.posts.DeleteByID(newPost.ID))
+
+	for _, handler := range []http.Handler{env.handler.RenderRSSFeedHandler(), env.handler.RenderSitemapHandler()} {
+		request := httptest.NewRequest("GET", "/feed.xml", nil)
+		request.Header.Set("If-Modified-Since", lastModified)
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.False(t, strings.Contains(recorder.Body.String(), "/posts/"+newPost.Slug))
+	}
+}
+
+func TestManagePostsPageShowsAuthorOnlyOwnPosts(t *testing.T) {
+	env := newTestEnv(t)
+	const writer = "writer"
+	require.NoError(t, env.users.Save(writer, "writer@example.com", "password", models.RoleAuthor))
+	otherDraft := env.savePost(t, "Other Draft", models.PostStatusDraft)
+	for _, request := range []*postService.SaveRequest{
+		{Title: "Own Draft", Status: models.PostStatusDraft, Author: writer},
+		{Title: "Co-written Draft", Status: models.PostStatusDraft, Author: testAuthor, CoAuthors: []string{writer}},
+	} {
+		request.ContentMD = "Snippet<cut>Content"
+		_, err := env.posts.Save(request)
+		require.NoError(t, err)
+	}
+
+	for role, username := range map[models.UserRole]string{models.RoleAuthor: writer, models.RoleAdmin: testAuthor} {
+		request := httptest.NewRequest("GET", "/manage-posts", nil)
+		ctx := context.WithValue(request.Context(), restapi.CtxUsernameKey, username)
+		request = request.WithContext(context.WithValue(ctx, restapi.CtxRoleKey, role))
+		recorder := httptest.NewRecorder()
+		env.handler.RenderAdminManagePostsPageHandler().ServeHTTP(recorder, request)
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		body := recorder.Body.String()
+		require.True(t, strings.Contains(body, "Own Draft"))
+		require.True(t, strings.Contains(body, "Co-written Draft"))
+		require.Equal(t, role == models.RoleAdmin, strings.Contains(body, otherDraft.Title))
+	}
+}
+
+func TestTagPageEscapesTagName(t *testing.T) {
+	env := newTestEnv(t)
+	tag := `<script>alert("tag")</script>`
+
+	recorder, body := renderPage(env.handler.RenderAllPostsPageHandler(), "/tags/"+url.PathEscape(tag),
+		map[string]string{"tag": tag})
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.False(t, strings.Contains(body, tag))
+	require.True(t, strings.Contains(body, "&lt;script&gt;"))
+}
+
+func TestPostHTMLIsSanitized(t *testing.T) {
+	env := newTestEnv(t)
+	_, err := env.posts.Save(&postService.SaveRequest{
+		Title: "Post With Script",
+		ContentMD: "*Allowed* snippet<script>alert(\"snippet\")</script>" +
+			"<cut><img src=\"/x.png\" onerror=\"alert('content')\">",
+		Status: models.PostStatusPublished,
+		Author: testAuthor,
+	})
+	require.NoError(t, err)
+
+	recorder, body := renderPage(env.handler.RenderIndexPageHandler(), "/", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.True(t, strings.Contains(body, "<em>Allowed</em> snippet"))
+	require.False(t, strings.Contains(body, "alert"))
+
+	post, err := env.posts.GetBySlug("post-with-script")
+	require.NoError(t, err)
+	require.True(t, strings.Contains(post.Content, `<img src="/x.png">`))
+	require.False(t, strings.Contains(post.Content, "onerror"))
+}
+
+func TestPageLinksHashedAssets(t *testing.T) {
+	env := newTestEnv(t)
+
+	recorder, body := renderPage(env.handler.RenderIndexPageHandler(), "/", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Regexp(t, `href="/css/main\.[0-9a-f]{10}\.css"`, body)
+	require.False(t, strings.Contains(body, `href="/css/main.css"`))
+}
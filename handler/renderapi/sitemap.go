@@ -0,0 +1,228 @@
+package renderapi
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/blinky-z/Blog/handler/restapi"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// sitemapMaxURLs - maximum amount of URLs in a single sitemap allowed by the sitemaps protocol
+	// Sitemap index that refers to multiple sitemaps is served instead if there are more URLs
+	sitemapMaxURLs int = 50000
+	// sitemapXMLNS - sitemaps protocol namespace
+	sitemapXMLNS = "http://www.sitemaps.org/schemas/sitemap/0.9"
+)
+
+// sitemapURLSet - represents sitemap document
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// sitemapURL - represents page in sitemap document
+// @lastModified - time of the last change of the page, LastMod is formatted from it
+type sitemapURL struct {
+	Loc          string `xml:"loc"`
+	LastMod      string `xml:"lastmod"`
+	ChangeFreq   string `xml:"changefreq,omitempty"`
+	Priority     string `xml:"priority,omitempty"`
+	lastModified time.Time
+}
+
+// newSitemapURL - creates sitemap URL of the page changed at the given time
+func newSitemapURL(loc string, lastModified time.Time, changeFreq, priority string) sitemapURL {
+	return sitemapURL{
+		Loc:          loc,
+		LastMod:      formatSitemapTime(lastModified),
+		ChangeFreq:   changeFreq,
+		Priority:     priority,
+		lastModified: lastModified,
+	}
+}
+
+// formatSitemapTime - formats time for sitemap documents. Times are in UTC, so that they don't depend
+// on time zone of the database or the server
+func formatSitemapTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// sitemapIndex - represents sitemap index document that refers to multiple sitemaps
+type sitemapIndex struct {
+	XMLName  xml.Name          `xml:"sitemapindex"`
+	XMLNS    string            `xml:"xmlns,attr"`
+	Sitemaps []sitemapIndexRef `xml:"sitemap"`
+}
+
+type sitemapIndexRef struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// getSitemapURLs - retrieves URLs of all public pages: static pages, published posts and tags
+// returns URLs, time of the last change among all pages and error
+func (renderApi *Handler) getSitemapURLs() ([]sitemapURL, time.Time, error) {
+	posts, err := renderApi.posts.GetPostsLastModified()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	tags, err := renderApi.tags.GetAllLastModified()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	// static pages list posts, so they change together with them. Deleted and unpublished posts leave
+	// no change time, so the pages are not older than the last change of any post
+	lastModified, err := renderApi.posts.GetContentChanged()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	for _, post := range posts {
+		if post.LastModified.After(lastModified) {
+			lastModified = post.LastModified
+		}
+	}
+
+	siteURL := renderApi.domain.String()
+	urls := make([]sitemapURL, 0, len(posts)+len(tags)+4)
+	urls = append(urls,
+		newSitemapURL(siteURL+"/", lastModified, "daily", "1.0"),
+		newSitemapURL(siteURL+"/posts", lastModified, "daily", "0.8"),
+		newSitemapURL(siteURL+"/tags", lastModified, "daily", "0.8"),
+		newSitemapURL(siteURL+"/about", lastModified, "monthly", "0.5"))
+	for _, post := range posts {
+		urls = append(urls, newSitemapURL(siteURL+"/posts/"+url.PathEscape(post.Name), post.LastModified, "", "0.9"))
+	}
+	for _, tag := range tags {
+		urls = append(urls, newSitemapURL(siteURL+"/tags/"+url.PathEscape(tag.Name), tag.LastModified, "", "0.6"))
+	}
+
+	return urls, lastModified, nil
+}
+
+// sitemapPageLastModified - returns time of the last change among the given URLs
+func sitemapPageLastModified(urls []sitemapURL) time.Time {
+	var lastModified time.Time
+	for _, sitemapURL := range urls {
+		if sitemapURL.lastModified.After(lastModified) {
+			lastModified = sitemapURL.lastModified
+		}
+	}
+	return lastModified
+}
+
+func encodeXML(document interface{}) ([]byte, error) {
+	encodedDocument, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), encodedDocument...), nil
+}
+
+// RenderSitemapHandler - handler for sitemap of all public pages
+// If there are more URLs than a single sitemap can contain, sitemap index is served instead.
+// It refers to sitemap pages served by RenderSitemapPageHandler
+func (renderApi *Handler) RenderSitemapHandler() http.Handler {
+	logError := renderApi.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		urls, lastModified, err := renderApi.getSitemapURLs()
+		if err != nil {
+			logError.Printf("Error retrieving sitemap URLs: %s", err)
+			restapi.Respond(w, http.StatusInternalServerError)
+			return
+		}
+
+		var document interface{}
+		if len(urls) <= sitemapMaxURLs {
+			document = sitemapURLSet{XMLNS: sitemapXMLNS, URLs: urls}
+		} else {
+			index := sitemapIndex{XMLNS: sitemapXMLNS}
+			for page := 1; (page-1)*sitemapMaxURLs < len(urls); page++ {
+				pageEnd := page * sitemapMaxURLs
+				if pageEnd > len(urls) {
+					pageEnd = len(urls)
+				}
+				index.Sitemaps = append(index.Sitemaps, sitemapIndexRef{
+					Loc:     fmt.Sprintf("%s/sitemap-%d.xml", renderApi.domain.String(), page),
+					LastMod: formatSitemapTime(sitemapPageLastModified(urls[(page-1)*sitemapMaxURLs : pageEnd])),
+				})
+			}
+			document = index
+		}
+
+		encodedSitemap, err := encodeXML(document)
+		if err != nil {
+			logError.Printf("Error encoding sitemap: %s", err)
+			restapi.Respond(w, http.StatusInternalServerError)
+			return
+		}
+
+		serveGenerated(w, r, "application/xml; charset=utf-8", encodedSitemap, lastModified)
+	})
+}
+
+// RenderSitemapPageHandler - handler for a single sitemap of the sitemap index
+// Page number is passed in 'page' route variable and starts from 1
+func (renderApi *Handler) RenderSitemapPageHandler() http.Handler {
+	logError := renderApi.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, err := strconv.Atoi(mux.Vars(r)["page"])
+		if err != nil || page < 1 {
+			restapi.Respond(w, http.StatusNotFound)
+			return
+		}
+
+		urls, lastModified, err := renderApi.getSitemapURLs()
+		if err != nil {
+			logError.Printf("Error retrieving sitemap URLs: %s", err)
+			restapi.Respond(w, http.StatusInternalServerError)
+			return
+		}
+
+		pageStart := (page - 1) * sitemapMaxURLs
+		if pageStart >= len(urls) {
+			restapi.Respond(w, http.StatusNotFound)
+			return
+		}
+		pageEnd := pageStart + sitemapMaxURLs
+		if pageEnd > len(urls) {
+			pageEnd = len(urls)
+		}
+
+		encodedSitemap, err := encodeXML(sitemapURLSet{XMLNS: sitemapXMLNS, URLs: urls[pageStart:pageEnd]})
+		if err != nil {
+			logError.Printf("Error encoding sitemap page: %s", err)
+			restapi.Respond(w, http.StatusInternalServerError)
+			return
+		}
+
+		serveGenerated(w, r, "application/xml; charset=utf-8", encodedSitemap, lastModified)
+	})
+}
+
+// robotsAllowedPaths - public site paths allowed for crawling
+var robotsAllowedPaths = []string{"/$", "/posts$", "/posts?page=*", "/posts/*", "/tags$", "/tags/*", "/about$"}
+
+// RenderRobotsHandler - handler for robots.txt of the public site
+// Sitemap location is built on the configured domain
+func (renderApi *Handler) RenderRobotsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var sb strings.Builder
+		sb.WriteString("User-agent: *\n")
+		for _, path := range robotsAllowedPaths {
+			sb.WriteString("Allow: " + path + "\n")
+		}
+		sb.WriteString("Sitemap: " + renderApi.domain.String() + "/sitemap.xml\n")
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(sb.String()))
+	})
+}
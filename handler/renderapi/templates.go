@@ -0,0 +1,159 @@
+package renderapi
+
+import (
+	"errors"
+	"fmt"
+	"github.com/blinky-z/Blog/assets"
+	"github.com/blinky-z/Blog/themes"
+	"github.com/fsnotify/fsnotify"
+	"html/template"
+	"io"
+	"io/fs"
+	"log"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// templatesReloadDelay - delay between the last change of the templates files and reloading of the templates
+const templatesReloadDelay = 100 * time.Millisecond
+
+// Templates - registry of the page templates
+// Templates are parsed once from the theme files and executed from memory
+// All template files are parsed into a single set, so the defined template names should be unique across all files
+type Templates struct {
+	theme     *themes.Theme
+	assets    *assets.Assets
+	mutex     sync.RWMutex
+	templates *template.Template
+	// watcher - watcher of the template directories. Nil if templates are not watched
+	watcher  *fsnotify.Watcher
+	logInfo  *log.Logger
+	logError *log.Logger
+}
+
+// NewTemplates - parses all layouts and partials of the theme
+// Templates refer to the theme static files with 'asset' function, e.g. {{asset "css/main.css"}}
+func NewTemplates(siteTheme *themes.Theme, siteAssets *assets.Assets,
+	logInfo, logError *log.Logger) (*Templates, error) {
+	templates := &Templates{
+		theme:    siteTheme,
+		assets:   siteAssets,
+		logInfo:  logInfo,
+		logError: logError,
+	}
+	parsedTemplates, err := templates.parse()
+	if err != nil {
+		return nil, err
+	}
+	templates.templates = parsedTemplates
+	return templates, nil
+}
+
+// parse - parses template files of the theme
+func (templates *Templates) parse() (*template.Template, error) {
+	files := templates.theme.TemplateFiles()
+	if len(files) == 0 {
+		return nil, fmt.Errorf("theme %s has no templates", templates.theme.Name())
+	}
+
+	// templates are named by the file names like in template.ParseFiles
+	parsedTemplates := template.New("").Funcs(renderFuncs).Funcs(template.FuncMap{
+		"asset": templates.assets.URL,
+	})
+	for _, file := range files {
+		content, err := fs.ReadFile(templates.theme.FS(), file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading template %s: %s", file, err)
+		}
+		if _, err = parsedTemplates.New(path.Base(file)).Parse(string(content)); err != nil {
+			return nil, fmt.Errorf("error parsing templates: %s", err)
+		}
+	}
+	return parsedTemplates, nil
+}
+
+// Execute - executes the template with the given name
+func (templates *Templates) Execute(w io.Writer, name string, data interface{}) error {
+	templates.mutex.RLock()
+	parsedTemplates := templates.templates
+	templates.mutex.RUnlock()
+	return parsedTemplates.ExecuteTemplate(w, name, data)
+}
+
+// Reload - parses template files of the theme again
+// Current templates are kept if the changed templates have errors
+func (templates *Templates) Reload() error {
+	parsedTemplates, err := templates.parse()
+	if err != nil {
+		return err
+	}
+	templates.mutex.Lock()
+	templates.templates = parsedTemplates
+	templates.mutex.Unlock()
+	return nil
+}
+
+// Watch - reloads templates when files in the given directories change, until Close is called
+// Used in development mode, so that changed templates are applied without a restart
+// Directories should be the directories on disk the theme templates are read from
+func (templates *Templates) Watch(dirs []string) error {
+	if templates.watcher != nil {
+		return errors.New("templates are already watched")
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// directories are watched instead of the files, as editors often replace files on save
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return fmt.Errorf("error watching templates directory: %s", err)
+		}
+	}
+	templates.watcher = watcher
+
+	go func() {
+		// reload is delayed until the files stop changing, as editors save files with several writes
+		reloadTimer := time.NewTimer(0)
+		<-reloadTimer.C
+		var lastEvent fsnotify.Event
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					reloadTimer.Stop()
+					return
+				}
+				if filepath.Ext(event.Name) != ".html" || event.Op == fsnotify.Chmod {
+					continue
+				}
+				lastEvent = event
+				reloadTimer.Reset(templatesReloadDelay)
+			case <-reloadTimer.C:
+				if err := templates.Reload(); err != nil {
+					templates.logError.Printf("Error reloading templates: %s. Keeping the current templates", err)
+					continue
+				}
+				templates.logInfo.Printf("Templates reloaded after %s", lastEvent)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					reloadTimer.Stop()
+					return
+				}
+				templates.logError.Printf("Error watching templates: %s", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// Close - stops watching the template files. Does nothing if templates are not watched
+func (templates *Templates) Close() error {
+	if templates.watcher == nil {
+		return nil
+	}
+	return templates.watcher.Close()
+}
@@ -0,0 +1,97 @@
+package renderapi
+
+import (
+	"bytes"
+	"github.com/blinky-z/Blog/assets"
+	"github.com/blinky-z/Blog/themes"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestTheme - creates themes directory with the default theme providing the given page template and footer
+// Returns the loaded theme and its layouts directory. Themes directory should be removed by the caller
+func newTestTheme(t *testing.T, page string) (string, *themes.Theme, string) {
+	themesPath, err := ioutil.TempDir("", "blog-themes")
+	require.NoError(t, err)
+	themePath := filepath.Join(themesPath, themes.DefaultName)
+	layoutsPath := filepath.Join(themePath, themes.LayoutsDir)
+	require.NoError(t, os.MkdirAll(filepath.Join(layoutsPath, "partials"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(themePath, themes.ManifestFile),
+		[]byte("templates: [partials/footer.html, index.html]"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(layoutsPath, "partials", "footer.html"),
+		[]byte(`{{define "footer"}}footer{{end}}`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(layoutsPath, "index.html"), []byte(page), 0644))
+
+	siteTheme, err := themes.Load(os.DirFS(themesPath), themes.DefaultName)
+	require.NoError(t, err)
+	return themesPath, siteTheme, layoutsPath
+}
+
+func executeTemplate(t *testing.T, templates *Templates, name string) string {
+	var output bytes.Buffer
+	require.NoError(t, templates.Execute(&output, name, nil))
+	return output.String()
+}
+
+func TestTemplatesParseAllLayouts(t *testing.T) {
+	logger := log.New(ioutil.Discard, "", 0)
+	siteTheme, err := themes.Load(themes.Embedded(), themes.DefaultName)
+	require.NoError(t, err)
+	siteAssets, err := assets.New(siteTheme.StaticFiles(), true)
+	require.NoError(t, err)
+	templates, err := NewTemplates(siteTheme, siteAssets, logger, logger)
+	require.NoError(t, err)
+
+	for _, name := range []string{"index", "post", "all-posts", "all-tags", "author", "search", "about",
+		"admin", "admin-login", "admin-editor", "admin-manage-posts", "admin-manage-tags", "admin-manage-media"} {
+		require.NotNil(t, templates.templates.Lookup(name), "template %s is not parsed", name)
+	}
+}
+
+func TestTemplatesInvalidLayout(t *testing.T) {
+	logger := log.New(ioutil.Discard, "", 0)
+	themesPath, siteTheme, _ := newTestTheme(t, `{{define "index"}}{{.Missing}`)
+	defer os.RemoveAll(themesPath)
+
+	siteAssets, err := assets.New(siteTheme.StaticFiles(), false)
+	require.NoError(t, err)
+	_, err = NewTemplates(siteTheme, siteAssets, logger, logger)
+	require.Error(t, err)
+}
+
+func TestTemplatesReloadOnChange(t *testing.T) {
+	logger := log.New(ioutil.Discard, "", 0)
+	themesPath, siteTheme, layoutsPath := newTestTheme(t, `{{define "index"}}old {{template "footer"}}{{end}}`)
+	defer os.RemoveAll(themesPath)
+	siteAssets, err := assets.New(siteTheme.StaticFiles(), false)
+	require.NoError(t, err)
+	templates, err := NewTemplates(siteTheme, siteAssets, logger, logger)
+	require.NoError(t, err)
+	require.NoError(t, templates.Watch([]string{layoutsPath, filepath.Join(layoutsPath, "partials")}))
+	defer templates.Close()
+	require.Equal(t, "old footer", executeTemplate(t, templates, "index"))
+
+	// invalid template is not applied
+	require.NoError(t, ioutil.WriteFile(filepath.Join(layoutsPath, "index.html"),
+		[]byte(`{{define "index"}}broken{{end`), 0644))
+	time.Sleep(5 * templatesReloadDelay)
+	require.Equal(t, "old footer", executeTemplate(t, templates, "index"))
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(layoutsPath, "partials", "footer.html"),
+		[]byte(`{{define "footer"}}new footer{{end}}`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(layoutsPath, "index.html"),
+		[]byte(`{{define "index"}}new {{template "footer"}}{{end}}`), 0644))
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		var output bytes.Buffer
+		if templates.Execute(&output, "index", nil) == nil && output.String() == "new new footer" {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("Changed templates are not reloaded: got %q", executeTemplate(t, templates, "index"))
+}
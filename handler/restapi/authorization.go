@@ -0,0 +1,115 @@
+package restapi
+
+import (
+	"database/sql"
+	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/service/postService"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// Permission - represents an action that user role allows
+type Permission string
+
+// permissions
+const (
+	// PermissionManagePosts - create, edit, publish and delete any posts
+	PermissionManagePosts = Permission("manage_posts")
+	// PermissionWriteOwnDrafts - create drafts and edit own drafts
+	PermissionWriteOwnDrafts = Permission("write_own_drafts")
+	// PermissionManageTags - create, edit and delete tags
+	PermissionManageTags = Permission("manage_tags")
+	// PermissionManageComments - edit and delete comments
+	PermissionManageComments = Permission("manage_comments")
+	// PermissionManageSite - site maintenance, such as re-rendering all posts
+	PermissionManageSite = Permission("manage_site")
+	// PermissionManageUsers - change user roles
+	PermissionManageUsers = Permission("manage_users")
+	// PermissionEditOwnProfile - edit own public author profile
+	PermissionEditOwnProfile = Permission("edit_own_profile")
+	// PermissionUploadMedia - upload files and browse uploaded files
+	PermissionUploadMedia = Permission("upload_media")
+	// PermissionManageMedia - delete uploaded files
+	PermissionManageMedia = Permission("manage_media")
+)
+
+// rolePermissions - permissions of every role. Roles not listed here have no permissions
+var rolePermissions = map[models.UserRole][]Permission{
+	models.RoleAdmin: {PermissionManagePosts, PermissionWriteOwnDrafts, PermissionManageTags,
+		PermissionManageComments, PermissionManageSite, PermissionManageUsers, PermissionEditOwnProfile,
+		PermissionUploadMedia, PermissionManageMedia},
+	models.RoleEditor: {PermissionManagePosts, PermissionWriteOwnDrafts, PermissionManageTags,
+		PermissionManageComments, PermissionEditOwnProfile, PermissionUploadMedia, PermissionManageMedia},
+	models.RoleAuthor: {PermissionWriteOwnDrafts, PermissionEditOwnProfile, PermissionUploadMedia},
+}
+
+// HasPermission - checks if the role has the permission
+func HasPermission(role models.UserRole, permission Permission) bool {
+	for _, currentPermission := range rolePermissions[role] {
+		if currentPermission == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPermission - checks if the authenticated user has the permission
+func hasPermission(r *http.Request, permission Permission) bool {
+	return HasPermission(GetUserRole(r), permission)
+}
+
+// isOwnDraftRequested - checks if the requested post is a draft written by the authenticated user
+// Both post author and co-authors are considered as writers of the post
+// Post ID is taken from 'id' route variable or query parameter. Requests without post ID, such as post creation,
+// don't request any existing post, so they are considered as requesting own draft
+func (api *UserAPIHandler) isOwnDraftRequested(r *http.Request) (bool, error) {
+	postID := mux.Vars(r)["id"]
+	if postID == "" {
+		postID = r.URL.Query().Get("id")
+	}
+	if postID == "" {
+		return true, nil
+	}
+	if !IsPostIDValid(postID) {
+		return false, nil
+	}
+
+	return postService.IsDraftOfAuthor(api.db, postID, GetUsername(r))
+}
+
+// Authorization - creates middleware that allows request only if user role has any of the given permissions
+// PermissionWriteOwnDrafts is granted only for requests to own drafts. See isOwnDraftRequested
+// This middleware should be a next step after authentication
+func (api *UserAPIHandler) Authorization(permissions ...Permission) func(http.Handler) http.Handler {
+	logInfo := api.logInfo
+	logError := api.logError
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userRole := GetUserRole(r)
+			for _, permission := range permissions {
+				if !HasPermission(userRole, permission) {
+					continue
+				}
+
+				if permission == PermissionWriteOwnDrafts {
+					isOwnDraft, err := api.isOwnDraftRequested(r)
+					if err != nil && err != sql.ErrNoRows {
+						logError.Printf("Error checking post author. Error: %s", err)
+						RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+						return
+					}
+					if !isOwnDraft {
+						continue
+					}
+				}
+
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			logInfo.Printf("User doesn't have permissions to access %s %s. Username: %s, role: %s",
+				r.Method, r.URL.Path, GetUsername(r), userRole)
+			RespondWithError(w, http.StatusForbidden, NoPermissions)
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package restapi
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/service/commentService"
+	"github.com/blinky-z/Blog/service/postService"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CommentAPIHandler - used for dependency injection
+type CommentAPIHandler struct {
+	db       *sql.DB
+	logInfo  *log.Logger
+	logError *log.Logger
+}
+
+func NewCommentAPIHandler(db *sql.DB, logInfo, logError *log.Logger) *CommentAPIHandler {
+	return &CommentAPIHandler{
+		db:       db,
+		logInfo:  logInfo,
+		logError: logError,
+	}
+}
+
+// error codes for this API
+var (
+	// InvalidCommentContent - invalid comment content
+	InvalidCommentContent = models.NewRequestErrorCode("INVALID_CONTENT")
+	// InvalidCommentAuthor - invalid comment author name
+	InvalidCommentAuthor = models.NewRequestErrorCode("INVALID_AUTHOR")
+	// NoSuchComment - comment does not exist
+	NoSuchComment = models.NewRequestErrorCode("NO_SUCH_COMMENT")
+)
+
+// constants for use in validator methods
+const (
+	// MinCommentContentLen - minimum comment content length
+	MinCommentContentLen int = 1
+	// MaxCommentContentLen - maximum comment content length
+	MaxCommentContentLen int = 2048
+)
+
+func isCommentIDValid(id string) bool {
+	if id == "" {
+		return false
+	}
+	num, err := strconv.Atoi(id)
+	if err != nil || num < 0 {
+		return false
+	}
+
+	return true
+}
+
+func validateCommentContent(content *string) models.RequestErrorCode {
+	contentLen := len([]rune(strings.TrimSpace(*content)))
+	if contentLen > MaxCommentContentLen || contentLen < MinCommentContentLen {
+		return InvalidCommentContent
+	}
+	return nil
+}
+
+func validateCommentAuthor(author *string) models.RequestErrorCode {
+	authorLen := len([]rune(strings.TrimSpace(*author)))
+	if authorLen > MaxUsernameLen || authorLen < MinUsernameLen {
+		return InvalidCommentAuthor
+	}
+	return nil
+}
+
+// parseParentCommentID - parses parent comment ID from the comment creation request
+// parent comment ID can be either null or a string
+func parseParentCommentID(parentCommentID interface{}) (models.NullString, bool) {
+	switch id := parentCommentID.(type) {
+	case nil:
+		return models.NullString{}, true
+	case string:
+		if !isCommentIDValid(id) {
+			return models.NullString{}, false
+		}
+		return models.NullString{String: id, Valid: true}, true
+	default:
+		return models.NullString{}, false
+	}
+}
+
+// CreateCommentHandler - this handler serves comment creation requests
+func (api *CommentAPIHandler) CreateCommentHandler() http.Handler {
+	logInfo := api.logInfo
+	logError := api.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := models.CreateCommentRequest{}
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, BadRequestBody)
+			return
+		}
+
+		logInfo.Printf("Got new comment creation request. Request: %+v", request)
+
+		if !IsPostIDValid(request.PostID) {
+			logInfo.Printf("Can't create comment: invalid post ID. Post ID: %s", request.PostID)
+			RespondWithError(w, http.StatusBadRequest, InvalidRequest)
+			return
+		}
+		parentID, ok := parseParentCommentID(request.ParentCommentID)
+		if !ok {
+			logInfo.Printf("Can't create comment: invalid parent comment ID. Parent comment ID: %v",
+				request.ParentCommentID)
+			RespondWithError(w, http.StatusBadRequest, InvalidRequest)
+			return
+		}
+		if err := validateCommentAuthor(&request.Author); err != nil {
+			RespondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		request.Content = commentService.SanitizeContent(request.Content)
+		if err := validateCommentContent(&request.Content); err != nil {
+			RespondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		postExists, err := postService.ExistsPublishedByID(api.db, request.PostID)
+		if err != nil {
+			logError.Printf("Error checking post existence. Post ID: %s. Error: %s", request.PostID, err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+		if !postExists {
+			logInfo.Printf("Can't create comment: no such post. Post ID: %s", request.PostID)
+			RespondWithError(w, http.StatusBadRequest, InvalidRequest)
+			return
+		}
+
+		if parentID.Valid {
+			parentComment, err := commentService.GetByID(api.db, parentID.String)
+			if err != nil && err != sql.ErrNoRows {
+				logError.Printf("Error retrieving parent comment. Comment ID: %s. Error: %s", parentID.String, err)
+				RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+				return
+			}
+			if err == sql.ErrNoRows || parentComment.PostID != request.PostID {
+				logInfo.Printf("Can't create comment: no such parent comment. Parent comment ID: %s", parentID.String)
+				RespondWithError(w, http.StatusBadRequest, InvalidRequest)
+				return
+			}
+		}
+
+		saveRequest := &commentService.SaveRequest{
+			PostID:   request.PostID,
+			ParentID: parentID,
+			Author:   strings.TrimSpace(request.Author),
+			Content:  strings.TrimSpace(request.Content),
+		}
+		createdComment, err := commentService.Save(api.db, saveRequest)
+		if err != nil {
+			logError.Printf("Error saving comment in database: %s", err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+
+		logInfo.Printf("Comment saved. Comment: %+v", createdComment)
+		RespondWithBody(w, http.StatusCreated, createdComment)
+	})
+}
+
+// UpdateCommentHandler - this handler serves comment update requests
+func (api *CommentAPIHandler) UpdateCommentHandler() http.Handler {
+	logInfo := api.logInfo
+	logError := api.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := models.UpdateCommentRequest{}
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			RespondWithError(w, http.StatusBadRequest, BadRequestBody)
+			return
+		}
+
+		commentID := mux.Vars(r)["id"]
+		logInfo.Printf("Got new comment update request. Comment ID: %s", commentID)
+
+		if !isCommentIDValid(commentID) {
+			logInfo.Printf("Can't update comment: invalid comment ID. Comment ID: %s", commentID)
+			RespondWithError(w, http.StatusBadRequest, InvalidRequest)
+			return
+		}
+		request.Content = commentService.SanitizeContent(request.Content)
+		if err := validateCommentContent(&request.Content); err != nil {
+			RespondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		updatedComment, err := commentService.Update(api.db, commentID, strings.TrimSpace(request.Content))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				logInfo.Printf("Can't update comment: no such comment. Comment ID: %s", commentID)
+				RespondWithError(w, http.StatusBadRequest, NoSuchComment)
+				return
+			}
+			logError.Printf("Error updating comment in database. Comment ID: %s. Error: %s", commentID, err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+
+		logInfo.Printf("Comment updated. Comment: %+v", updatedComment)
+		RespondWithBody(w, http.StatusCreated, updatedComment)
+	})
+}
+
+// DeleteCommentHandler - this handler serves comment deletion requests
+func (api *CommentAPIHandler) DeleteCommentHandler() http.Handler {
+	logInfo := api.logInfo
+	logError := api.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		commentID := mux.Vars(r)["id"]
+		logInfo.Printf("Got new comment deletion request. Comment ID: %s", commentID)
+
+		if !isCommentIDValid(commentID) {
+			logInfo.Printf("Can't delete comment: invalid comment ID. Comment ID: %s", commentID)
+			RespondWithError(w, http.StatusBadRequest, InvalidRequest)
+			return
+		}
+
+		if err := commentService.DeleteByID(api.db, commentID); err != nil {
+			logError.Printf("Error deleting a comment. Comment ID: %s. Error: %s", commentID, err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+
+		logInfo.Printf("Comment deleted. Comment ID: %s", commentID)
+		Respond(w, http.StatusOK)
+	})
+}
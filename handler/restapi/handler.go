@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"github.com/blinky-z/Blog/models"
 	"net/http"
+	"strconv"
 )
 
 // general error codes
@@ -60,3 +61,52 @@ func RespondWithBody(w http.ResponseWriter, code int, payload interface{}) {
 
 	respondWithJSON(w, code, encodedResponse)
 }
+
+// RespondWithPage - helper function for responding with a page of paginated list in body
+// This function uses special 'Response' struct. See above
+func RespondWithPage(w http.ResponseWriter, code int, payload interface{}, pagination *models.Pagination) {
+	response := &models.Response{
+		Error:      nil,
+		Body:       payload,
+		Pagination: pagination,
+	}
+	encodedResponse, err := json.Marshal(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, code, encodedResponse)
+}
+
+// NewPagination - creates pagination metadata of the requested page
+// Links to the next and previous pages are built from the request URL with the changed 'page' query param
+func NewPagination(r *http.Request, page, pageSize, totalCount int) *models.Pagination {
+	pagination := &models.Pagination{
+		Page:       page,
+		PageSize:   pageSize,
+		TotalCount: totalCount,
+	}
+	if pageSize > 0 {
+		pagination.TotalPages = (totalCount + pageSize - 1) / pageSize
+	}
+
+	pageLink := func(page int) string {
+		query := r.URL.Query()
+		query.Set("page", strconv.Itoa(page))
+		return r.URL.Path + "?" + query.Encode()
+	}
+	if page+1 < pagination.TotalPages {
+		pagination.Next = pageLink(page + 1)
+	}
+	if page > 0 {
+		// previous page of the page out of range is the last page
+		prevPage := page - 1
+		if prevPage >= pagination.TotalPages && pagination.TotalPages > 0 {
+			prevPage = pagination.TotalPages - 1
+		}
+		pagination.Prev = pageLink(prevPage)
+	}
+
+	return pagination
+}
@@ -0,0 +1,51 @@
+package restapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/blinky-z/Blog/models"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testResponse - response of the handler with the body left encoded
+type testResponse struct {
+	Error string          `json:"error"`
+	Body  json.RawMessage `json:"body"`
+}
+
+var testLogger = log.New(ioutil.Discard, "", 0)
+
+// serveTestRequest - serves request by the handler on behalf of the user with the given role
+// route variables are passed as mux sets them for the matched route
+func serveTestRequest(handler http.Handler, method, path string, message interface{}, username string,
+	role models.UserRole, vars map[string]string) (int, testResponse) {
+	var body []byte
+	if message != nil {
+		body, _ = json.Marshal(message)
+	}
+	request := httptest.NewRequest(method, path, bytes.NewReader(body))
+	ctx := context.WithValue(request.Context(), CtxUsernameKey, username)
+	ctx = context.WithValue(ctx, CtxRoleKey, role)
+	request = mux.SetURLVars(request.WithContext(ctx), vars)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	var response testResponse
+	_ = json.Unmarshal(recorder.Body.Bytes(), &response)
+	return recorder.Code, response
+}
+
+// requireErrorResponse - asserts that the handler responded with expected status code and error code
+func requireErrorResponse(t *testing.T, code int, response testResponse, expectedCode int,
+	expectedError models.RequestErrorCode) {
+	require.Equal(t, expectedCode, code)
+	require.Equal(t, expectedError.Error(), response.Error)
+}
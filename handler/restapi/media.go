@@ -0,0 +1,434 @@
+package restapi
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/service/mediaService"
+	"github.com/blinky-z/Blog/storage"
+	"github.com/gorilla/mux"
+	"image"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// MediaAPIHandler - used for dependency injection
+type MediaAPIHandler struct {
+	db       *sql.DB
+	storage  storage.Storage
+	logInfo  *log.Logger
+	logError *log.Logger
+}
+
+func NewMediaAPIHandler(db *sql.DB, fileStorage storage.Storage, logInfo, logError *log.Logger) *MediaAPIHandler {
+	return &MediaAPIHandler{
+		db:       db,
+		storage:  fileStorage,
+		logInfo:  logInfo,
+		logError: logError,
+	}
+}
+
+// error codes for this API
+var (
+	// InvalidMediaType - uploaded file is not an image of the supported type
+	InvalidMediaType = models.NewRequestErrorCode("INVALID_MEDIA_TYPE")
+	// MediaTooLarge - uploaded file exceeds max file size
+	MediaTooLarge = models.NewRequestErrorCode("MEDIA_TOO_LARGE")
+	// NoSuchMedia - uploaded file does not exist
+	NoSuchMedia = models.NewRequestErrorCode("NO_SUCH_MEDIA")
+	// InvalidMediaRange - invalid range of uploaded files
+	InvalidMediaRange = models.NewRequestErrorCode("INVALID_MEDIA_RANGE")
+)
+
+// constants for use in validator methods
+const (
+	// MaxMediaSize - max size of uploaded file in bytes
+	MaxMediaSize int64 = 10 << 20
+	// MaxMediaFilenameLen - max length of uploaded file name. Longer names are truncated
+	MaxMediaFilenameLen int = 255
+
+	// mediaFormField - multipart form field with uploaded file
+	mediaFormField = "file"
+	// maxMediaRequestOverhead - max size of multipart request parts other than the file content
+	maxMediaRequestOverhead int64 = 1 << 20
+	// mediaCacheControl - uploaded files are never changed as every upload gets a new key
+	mediaCacheControl = "public, max-age=31536000, immutable"
+)
+
+// mediaTypeExtensions - supported types of uploaded files with extensions of the saved files
+// SVG is not supported as it may contain scripts
+var mediaTypeExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+func isMediaIDValid(id string) bool {
+	if id == "" {
+		return false
+	}
+	num, err := strconv.Atoi(id)
+	if err != nil || num < 0 {
+		return false
+	}
+
+	return true
+}
+
+// generateMediaKey - generates random storage key with the given extension
+func generateMediaKey(extension string) (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes) + extension, nil
+}
+
+// normalizeMediaFilename - drops directories some browsers send in the file name and truncates too long names
+func normalizeMediaFilename(filename string) string {
+	filename = strings.TrimSpace(filepath.Base(strings.Replace(filename, "\\", "/", -1)))
+	if filenameRunes := []rune(filename); len(filenameRunes) > MaxMediaFilenameLen {
+		filename = string(filenameRunes[:MaxMediaFilenameLen])
+	}
+	return filename
+}
+
+// UploadMediaHandler - this handler serves file upload requests
+// File is sent in 'file' field of multipart form. File type is detected by its content, the file name is kept
+// for information only
+func (api *MediaAPIHandler) UploadMediaHandler() http.Handler {
+	logInfo := api.logInfo
+	logError := api.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username := GetUsername(r)
+		logInfo.Printf("Got new file upload request. Username: %s, content length: %d", username, r.ContentLength)
+
+		if r.ContentLength > MaxMediaSize+maxMediaRequestOverhead {
+			logInfo.Printf("Can't upload file: request is too large. Content length: %d", r.ContentLength)
+			RespondWithError(w, http.StatusRequestEntityTooLarge, MediaTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, MaxMediaSize+maxMediaRequestOverhead)
+
+		file, header, err := r.FormFile(mediaFormField)
+		if err != nil {
+			logInfo.Printf("Can't upload file: invalid multipart form. Error: %s", err)
+			RespondWithError(w, http.StatusBadRequest, BadRequestBody)
+			return
+		}
+		defer file.Close()
+
+		content, err := ioutil.ReadAll(io.LimitReader(file, MaxMediaSize+1))
+		if err != nil {
+			logInfo.Printf("Can't upload file: error reading file. Error: %s", err)
+			RespondWithError(w, http.StatusBadRequest, BadRequestBody)
+			return
+		}
+		if int64(len(content)) > MaxMediaSize {
+			logInfo.Printf("Can't upload file: file is too large. Filename: %s", header.Filename)
+			RespondWithError(w, http.StatusRequestEntityTooLarge, MediaTooLarge)
+			return
+		}
+
+		contentType := http.DetectContentType(content)
+		extension, ok := mediaTypeExtensions[contentType]
+		if !ok {
+			logInfo.Printf("Can't upload file: unsupported file type. Filename: %s, type: %s",
+				header.Filename, contentType)
+			RespondWithError(w, http.StatusBadRequest, InvalidMediaType)
+			return
+		}
+
+		// size is checked before the image is decoded for stripping metadata
+		width, height, err := mediaService.ImageSize(content, contentType)
+		if err != nil {
+			logInfo.Printf("Can't upload file: invalid image. Filename: %s", header.Filename)
+			RespondWithError(w, http.StatusBadRequest, InvalidMediaType)
+			return
+		}
+		if int64(width)*int64(height) > mediaService.MaxImagePixels {
+			logInfo.Printf("Can't upload file: image is too large. Filename: %s, size: %dx%d",
+				header.Filename, width, height)
+			RespondWithError(w, http.StatusRequestEntityTooLarge, MediaTooLarge)
+			return
+		}
+		if content, err = mediaService.StripMetadata(content, contentType); err != nil {
+			logInfo.Printf("Can't upload file: invalid image. Filename: %s. Error: %s", header.Filename, err)
+			RespondWithError(w, http.StatusBadRequest, InvalidMediaType)
+			return
+		}
+		// EXIF orientation may swap width and height
+		if width, height, err = mediaService.ImageSize(content, contentType); err != nil {
+			logError.Printf("Error retrieving size of the image with stripped metadata. Filename: %s. Error: %s",
+				header.Filename, err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+
+		key, err := generateMediaKey(extension)
+		if err != nil {
+			logError.Printf("Error generating file key: %s", err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+		if err = api.storage.Save(key, content, contentType); err != nil {
+			logError.Printf("Error saving file in storage. Key: %s. Error: %s", key, err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+
+		media, err := mediaService.Save(api.db, &mediaService.SaveRequest{
+			Key:         key,
+			Filename:    normalizeMediaFilename(header.Filename),
+			ContentType: contentType,
+			Size:        int64(len(content)),
+			Width:       width,
+			Height:      height,
+			UploadedBy:  username,
+		})
+		if err != nil {
+			logError.Printf("Error saving file info in database. Key: %s. Error: %s", key, err)
+			if err = api.storage.Delete(key); err != nil {
+				logError.Printf("Error deleting not saved file from storage. Key: %s. Error: %s", key, err)
+			}
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+
+		api.saveVariants(media, content)
+
+		logInfo.Printf("File uploaded. File: %+v", media)
+		RespondWithBody(w, http.StatusCreated, media)
+	})
+}
+
+// saveVariants - generates and saves variants of the uploaded image
+// Missing variants are generated on the first request, so errors are only logged
+func (api *MediaAPIHandler) saveVariants(media models.Media, content []byte) {
+	variants := mediaService.VariantsOf(media)
+	if len(variants) == 0 {
+		return
+	}
+
+	decoded, err := mediaService.DecodeImage(content, media.ContentType)
+	if err != nil {
+		api.logError.Printf("Error decoding image for generating variants. Key: %s. Error: %s", media.Key, err)
+		return
+	}
+	for _, variant := range variants {
+		if _, err = api.saveVariant(media, decoded, variant); err != nil {
+			api.logError.Printf("Error saving image variant. Key: %s, variant: %s. Error: %s",
+				media.Key, variant.Name, err)
+		}
+	}
+}
+
+// saveVariant - generates the image variant from the decoded image and saves it in the storage
+// returns content of the variant and error
+func (api *MediaAPIHandler) saveVariant(media models.Media, decoded *image.RGBA,
+	variant mediaService.ImageVariant) ([]byte, error) {
+	content, err := mediaService.EncodeVariant(decoded, media.ContentType, variant)
+	if err != nil {
+		return nil, err
+	}
+	return content, api.storage.Save(mediaService.VariantKey(media.Key, variant), content, media.ContentType)
+}
+
+// generateVariant - generates the missing image variant from the original image in the storage
+// returns content of the variant and error
+func (api *MediaAPIHandler) generateVariant(media models.Media, variant mediaService.ImageVariant) ([]byte, error) {
+	file, err := api.storage.Open(media.Key)
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadAll(file)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := mediaService.DecodeImage(content, media.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	return api.saveVariant(media, decoded, variant)
+}
+
+// GetMediaHandler - this handler serves GET request for uploaded files in the given range
+// Response contains pagination metadata
+func (api *MediaAPIHandler) GetMediaHandler() http.Handler {
+	logInfo := api.logInfo
+	logError := api.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rangeParams := &GetPostsRequestQueryParams{
+			Page:         r.FormValue("page"),
+			PostsPerPage: r.FormValue("per-page"),
+		}
+
+		logInfo.Printf("Got range of files retrieve request. Range params: %+v", rangeParams)
+
+		if err := ValidateGetPostsRequestQueryParams(rangeParams); err != nil {
+			logInfo.Printf("Can't retrieve range of files: invalid query params. Range params: %+v", rangeParams)
+			RespondWithError(w, http.StatusBadRequest, InvalidMediaRange)
+			return
+		}
+
+		if rangeParams.Page == "" {
+			rangeParams.Page = DefaultPage
+		}
+		if rangeParams.PostsPerPage == "" {
+			rangeParams.PostsPerPage = DefaultPostsPerPage
+		}
+		// we know that params are valid so ignore the errors
+		page, _ := strconv.Atoi(rangeParams.Page)
+		perPage, _ := strconv.Atoi(rangeParams.PostsPerPage)
+
+		media, err := mediaService.GetInRange(api.db, page*perPage, perPage)
+		if err != nil {
+			logError.Printf("Error retrieving range of files from database: %s", err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+		totalCount, err := mediaService.Count(api.db)
+		if err != nil {
+			logError.Printf("Error counting files: %s", err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+
+		RespondWithPage(w, http.StatusOK, media, NewPagination(r, page, perPage, totalCount))
+	})
+}
+
+// DeleteMediaHandler - this handler serves uploaded file deletion requests
+// Posts that use the file are not changed, so the file should not be used anywhere before deletion
+func (api *MediaAPIHandler) DeleteMediaHandler() http.Handler {
+	logInfo := api.logInfo
+	logError := api.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mediaID := mux.Vars(r)["id"]
+		logInfo.Printf("Got new file deletion request. File ID: %s", mediaID)
+
+		if !isMediaIDValid(mediaID) {
+			logInfo.Printf("Can't delete file: invalid file ID. File ID: %s", mediaID)
+			RespondWithError(w, http.StatusBadRequest, InvalidRequest)
+			return
+		}
+
+		media, err := mediaService.DeleteByID(api.db, mediaID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				logInfo.Printf("Can't delete file: no such file. File ID: %s", mediaID)
+				RespondWithError(w, http.StatusBadRequest, NoSuchMedia)
+				return
+			}
+			logError.Printf("Error deleting file info. File ID: %s. Error: %s", mediaID, err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+
+		// the file is not available anymore, so failed deletion leaves an orphan file in the storage only
+		if err = api.storage.Delete(media.Key); err != nil {
+			logError.Printf("Error deleting file from storage. Key: %s. Error: %s", media.Key, err)
+		}
+		for _, variant := range mediaService.VariantsOf(media) {
+			variantKey := mediaService.VariantKey(media.Key, variant)
+			if err = api.storage.Delete(variantKey); err != nil {
+				logError.Printf("Error deleting image variant from storage. Key: %s. Error: %s", variantKey, err)
+			}
+		}
+
+		logInfo.Printf("File deleted. File: %+v", media)
+		Respond(w, http.StatusOK)
+	})
+}
+
+// setMediaHeaders - sets headers of the served file
+func setMediaHeaders(w http.ResponseWriter, media models.Media) {
+	w.Header().Set("Content-Type", media.ContentType)
+	w.Header().Set("Cache-Control", mediaCacheControl)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+}
+
+// ServeMediaHandler - this handler serves uploaded files by the key passed in 'key' route variable
+// Image variants are served by variant keys. Missing variants are generated on the first request
+func (api *MediaAPIHandler) ServeMediaHandler() http.Handler {
+	logInfo := api.logInfo
+	logError := api.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := mux.Vars(r)["key"]
+		if !storage.IsKeyValid(key) {
+			Respond(w, http.StatusNotFound)
+			return
+		}
+
+		originalKey, variant, isVariant := mediaService.ParseVariantKey(key)
+		if !isVariant {
+			originalKey = key
+		}
+		media, err := mediaService.GetByKey(api.db, originalKey)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				Respond(w, http.StatusNotFound)
+				return
+			}
+			logError.Printf("Error retrieving file info. Key: %s. Error: %s", originalKey, err)
+			Respond(w, http.StatusInternalServerError)
+			return
+		}
+		if isVariant && !mediaService.HasVariant(media, variant) {
+			Respond(w, http.StatusNotFound)
+			return
+		}
+
+		file, err := api.storage.Open(key)
+		if err == storage.ErrNotExist && isVariant {
+			logInfo.Printf("Generating missing image variant. Key: %s", key)
+			content, err := api.generateVariant(media, variant)
+			if err != nil {
+				logError.Printf("Error generating image variant. Key: %s. Error: %s", key, err)
+				Respond(w, http.StatusInternalServerError)
+				return
+			}
+			setMediaHeaders(w, media)
+			http.ServeContent(w, r, "", media.Created, bytes.NewReader(content))
+			return
+		}
+		if err != nil {
+			if err == storage.ErrNotExist {
+				logError.Printf("File is missing in storage. Key: %s", key)
+				Respond(w, http.StatusNotFound)
+				return
+			}
+			logError.Printf("Error opening file. Key: %s. Error: %s", key, err)
+			Respond(w, http.StatusInternalServerError)
+			return
+		}
+		defer file.Close()
+
+		setMediaHeaders(w, media)
+		// local files support range requests, files from remote storages are streamed as is
+		if seeker, ok := file.(io.ReadSeeker); ok {
+			http.ServeContent(w, r, "", media.Created, seeker)
+			return
+		}
+		// size of variants is not stored
+		if !isVariant {
+			w.Header().Set("Content-Length", strconv.FormatInt(media.Size, 10))
+		}
+		w.Header().Set("Last-Modified", media.Created.UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+		if _, err = io.Copy(w, file); err != nil {
+			logError.Printf("Error sending file. Key: %s. Error: %s", key, err)
+		}
+	})
+}
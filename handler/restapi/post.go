@@ -4,24 +4,33 @@ import (
 	"database/sql"
 	"encoding/json"
 	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/repository"
 	"github.com/blinky-z/Blog/service/postService"
 	"github.com/gorilla/mux"
 	"log"
+	"math"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // PostAPIHandler - used for dependency injection
 type PostAPIHandler struct {
-	db       *sql.DB
+	posts    repository.PostRepository
+	users    repository.UserRepository
+	comments repository.CommentRepository
 	logInfo  *log.Logger
 	logError *log.Logger
 }
 
-func NewPostAPIHandler(db *sql.DB, logInfo, logError *log.Logger) *PostAPIHandler {
+func NewPostAPIHandler(posts repository.PostRepository, users repository.UserRepository,
+	comments repository.CommentRepository, logInfo, logError *log.Logger) *PostAPIHandler {
 	return &PostAPIHandler{
-		db:       db,
+		posts:    posts,
+		users:    users,
+		comments: comments,
 		logInfo:  logInfo,
 		logError: logError,
 	}
@@ -49,6 +58,16 @@ var (
 	InvalidPostsRange = models.NewRequestErrorCode("INVALID_POSTS_RANGE")
 	// InvalidPostTags - invalid tags
 	InvalidPostTags = models.NewRequestErrorCode("INVALID_TAGS")
+	// InvalidPostStatus - unknown post status or scheduled post without publish time in the future
+	InvalidPostStatus = models.NewRequestErrorCode("INVALID_STATUS")
+	// InvalidPostSlug - slug contains characters other than lowercase latin letters, digits and hyphens
+	InvalidPostSlug = models.NewRequestErrorCode("INVALID_SLUG")
+	// PostSlugAlreadyExists - slug is already used by another post
+	PostSlugAlreadyExists = models.NewRequestErrorCode("SLUG_ALREADY_EXISTS")
+	// InvalidPostCoAuthors - too many co-authors or invalid co-author username
+	InvalidPostCoAuthors = models.NewRequestErrorCode("INVALID_CO_AUTHORS")
+	// InvalidSearchQuery - search query is empty or too long
+	InvalidSearchQuery = models.NewRequestErrorCode("INVALID_SEARCH_QUERY")
 )
 
 // constants for use in validator methods
@@ -84,8 +103,17 @@ const (
 	MinSnippetLen int = 10
 	// MaxSnippetLen - max length of post snippet
 	MaxSnippetLen int = 600
+
+	// MaxSearchQueryLen - max length of search query
+	MaxSearchQueryLen int = 200
+
+	// MaxCoAuthorsAmount - max allowed amount of post co-authors
+	MaxCoAuthorsAmount int = 10
 )
 
+// slugPattern - lowercase latin letters and digits separated by single hyphens
+var slugPattern = regexp.MustCompile("^[a-z0-9]+(-[a-z0-9]+)*$")
+
 // other API constants
 const (
 	DefaultPage         string = "0"
@@ -93,17 +121,19 @@ const (
 )
 
 // ValidateGetPostsRequestQueryParams - validate query params of GET request for range of posts
+// Page is limited, so that offset of the page does not overflow 32-bit integer in database query
 func ValidateGetPostsRequestQueryParams(rangeParams *GetPostsRequestQueryParams) models.RequestErrorCode {
 	pageAsString := rangeParams.Page
 	if pageAsString != "" {
-		if pageAsInt, err := strconv.Atoi(pageAsString); err != nil || pageAsInt < 0 {
+		if pageAsInt, err := strconv.Atoi(pageAsString); err != nil || pageAsInt < 0 ||
+			pageAsInt > math.MaxInt32/MaxPostsPerPage {
 			return InvalidPostsRange
 		}
 	}
 	postsPerPageAsString := rangeParams.PostsPerPage
 	if postsPerPageAsString != "" {
 		if postsPerPageAsInt, err := strconv.Atoi(rangeParams.PostsPerPage); err != nil ||
-			postsPerPageAsInt > MaxPostsPerPage || postsPerPageAsInt < 0 {
+			postsPerPageAsInt > MaxPostsPerPage || postsPerPageAsInt < 1 {
 			return InvalidPostsRange
 		}
 	}
@@ -147,11 +177,16 @@ func validatePostSnippet(snippet *string) models.RequestErrorCode {
 	return nil
 }
 
-func validatePostContent(content *string) models.RequestErrorCode {
-	if len(*content) == 0 {
+func validatePostContent(contentMD *string) models.RequestErrorCode {
+	if len(*contentMD) == 0 {
 		return InvalidPostContent
 	}
-	return nil
+
+	snippetMD, _, ok := postService.SplitContent(*contentMD)
+	if !ok {
+		return InvalidPostContent
+	}
+	return validatePostSnippet(&snippetMD)
 }
 
 func validatePostTags(tags *[]string) models.RequestErrorCode {
@@ -164,6 +199,101 @@ func validatePostTags(tags *[]string) models.RequestErrorCode {
 	return nil
 }
 
+// validatePostSlug - validates requested slug. Empty slug is valid as it means the slug should not be changed
+// Slug consisting of digits only is not allowed as it can't be distinguished from post ID
+func validatePostSlug(slug string) models.RequestErrorCode {
+	if slug == "" {
+		return nil
+	}
+	if len(slug) > postService.MaxSlugLen || !slugPattern.MatchString(slug) || IsPostIDValid(slug) {
+		return InvalidPostSlug
+	}
+	return nil
+}
+
+// ValidateSearchQuery - validates search query. Query should contain at least one non-space character
+func ValidateSearchQuery(query string) models.RequestErrorCode {
+	queryLen := len([]rune(strings.TrimSpace(query)))
+	if queryLen == 0 || queryLen > MaxSearchQueryLen {
+		return InvalidSearchQuery
+	}
+	return nil
+}
+
+// validatePostStatus - validates post status. Scheduled post should have publish time in the future
+func validatePostStatus(status models.PostStatus, publishAt *time.Time) models.RequestErrorCode {
+	switch status {
+	case models.PostStatusDraft, models.PostStatusPublished:
+		return nil
+	case models.PostStatusScheduled:
+		if publishAt == nil || !publishAt.After(time.Now()) {
+			return InvalidPostStatus
+		}
+		return nil
+	default:
+		return InvalidPostStatus
+	}
+}
+
+func validatePostCoAuthors(coAuthors []string) models.RequestErrorCode {
+	if len(coAuthors) > MaxCoAuthorsAmount {
+		return InvalidPostCoAuthors
+	}
+	for _, coAuthor := range coAuthors {
+		coAuthorLen := len([]rune(coAuthor))
+		if coAuthorLen > MaxUsernameLen || coAuthorLen < MinUsernameLen {
+			return InvalidPostCoAuthors
+		}
+	}
+	return nil
+}
+
+// normalizeCoAuthors - trims spaces in co-author usernames and removes duplicates keeping the order
+func normalizeCoAuthors(coAuthors []string) []string {
+	normalized := make([]string, 0, len(coAuthors))
+	seen := make(map[string]bool)
+	for _, coAuthor := range coAuthors {
+		coAuthor = strings.TrimSpace(coAuthor)
+		if seen[coAuthor] {
+			continue
+		}
+		seen[coAuthor] = true
+		normalized = append(normalized, coAuthor)
+	}
+	return normalized
+}
+
+// checkCoAuthorsExist - checks that all co-authors are registered users
+// Responds with error and returns false if some co-author does not exist or the check failed
+func (api *PostAPIHandler) checkCoAuthorsExist(w http.ResponseWriter, coAuthors []string) bool {
+	if len(coAuthors) == 0 {
+		return true
+	}
+
+	notExisting, err := api.users.GetNotExistingUsernames(coAuthors)
+	if err != nil {
+		api.logError.Printf("Error checking co-authors existence. Co-authors: %v. Error: %s", coAuthors, err)
+		RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+		return false
+	}
+	if len(notExisting) != 0 {
+		api.logInfo.Printf("Post co-authors are not registered users. Usernames: %v", notExisting)
+		RespondWithError(w, http.StatusBadRequest, NoSuchUser)
+		return false
+	}
+	return true
+}
+
+// normalizePostStatus - sets default status if it is missed and drops publish time of not scheduled post
+func normalizePostStatus(status *models.PostStatus, publishAt **time.Time) {
+	if *status == "" {
+		*status = models.PostStatusPublished
+	}
+	if *status != models.PostStatusScheduled {
+		*publishAt = nil
+	}
+}
+
 func validateCreatePostRequest(request *models.CreatePostRequest) models.RequestErrorCode {
 	if err := validatePostTitle(&request.Title); err != nil {
 		return err
@@ -171,13 +301,19 @@ func validateCreatePostRequest(request *models.CreatePostRequest) models.Request
 	if err := validatePostMetadata(&request.Metadata); err != nil {
 		return err
 	}
-	if err := validatePostSnippet(&request.Snippet); err != nil {
+	if err := validatePostContent(&request.ContentMD); err != nil {
 		return err
 	}
-	if err := validatePostContent(&request.Content); err != nil {
+	if err := validatePostTags(&request.Tags); err != nil {
 		return err
 	}
-	if err := validatePostTags(&request.Tags); err != nil {
+	if err := validatePostStatus(request.Status, request.PublishAt); err != nil {
+		return err
+	}
+	if err := validatePostSlug(request.Slug); err != nil {
+		return err
+	}
+	if err := validatePostCoAuthors(request.CoAuthors); err != nil {
 		return err
 	}
 
@@ -191,10 +327,16 @@ func validateUpdatePostRequest(request *models.UpdatePostRequest) models.Request
 	if err := validatePostMetadata(&request.Metadata); err != nil {
 		return err
 	}
-	if err := validatePostSnippet(&request.Snippet); err != nil {
+	if err := validatePostContent(&request.ContentMD); err != nil {
 		return err
 	}
-	if err := validatePostContent(&request.Content); err != nil {
+	if err := validatePostStatus(request.Status, request.PublishAt); err != nil {
+		return err
+	}
+	if err := validatePostSlug(request.Slug); err != nil {
+		return err
+	}
+	if err := validatePostCoAuthors(request.CoAuthors); err != nil {
 		return err
 	}
 
@@ -218,13 +360,6 @@ func (api *PostAPIHandler) CreatePostHandler() http.Handler {
 	logInfo := api.logInfo
 	logError := api.logError
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		//userRole := r.Context().Value(CtxRoleKey).(models.UserRole)
-		//if userRole != roleAdmin {
-		//	logError.Printf("User role doesn't have permissions to create posts. User role: %s", userRole)
-		//	RespondWithError(w, http.StatusForbidden, NoPermissions)
-		//	return
-		//}
-
 		request := models.CreatePostRequest{}
 		err := json.NewDecoder(r.Body).Decode(&request)
 		if err != nil {
@@ -244,6 +379,9 @@ func (api *PostAPIHandler) CreatePostHandler() http.Handler {
 			request.Metadata.Keywords[keywordIndex] = strings.TrimSpace(keyword)
 		}
 
+		normalizePostStatus(&request.Status, &request.PublishAt)
+		request.CoAuthors = normalizeCoAuthors(request.CoAuthors)
+
 		validatePostError := validateCreatePostRequest(&request)
 		if validatePostError != nil {
 			logInfo.Printf("Can't create post: invalid request. Error: %s", validatePostError)
@@ -251,16 +389,36 @@ func (api *PostAPIHandler) CreatePostHandler() http.Handler {
 			return
 		}
 
+		// users allowed to write only own drafts can't publish posts
+		if request.Status != models.PostStatusDraft && !hasPermission(r, PermissionManagePosts) {
+			logInfo.Printf("Can't create post: user doesn't have permissions to publish posts. Username: %s",
+				GetUsername(r))
+			RespondWithError(w, http.StatusForbidden, NoPermissions)
+			return
+		}
+
+		if !api.checkCoAuthorsExist(w, request.CoAuthors) {
+			return
+		}
+
 		saveRequest := &postService.SaveRequest{
 			Title:     request.Title,
-			Snippet:   request.Snippet,
-			Content:   request.Content,
+			Slug:      request.Slug,
 			ContentMD: request.ContentMD,
 			Metadata:  request.Metadata,
 			Tags:      request.Tags,
+			Status:    request.Status,
+			PublishAt: request.PublishAt,
+			Author:    GetUsername(r),
+			CoAuthors: request.CoAuthors,
 		}
-		createdPost, err := postService.Save(api.db, saveRequest)
+		createdPost, err := api.posts.Save(saveRequest)
 		if err != nil {
+			if err == postService.ErrSlugAlreadyExists {
+				logInfo.Printf("Can't create post: slug is already used. Slug: %s", request.Slug)
+				RespondWithError(w, http.StatusBadRequest, PostSlugAlreadyExists)
+				return
+			}
 			logError.Printf("Error saving post in database: %s", err)
 			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
 			return
@@ -276,12 +434,6 @@ func (api *PostAPIHandler) UpdatePostHandler() http.Handler {
 	logInfo := api.logInfo
 	logError := api.logError
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		//userRole := r.Context().Value(CtxRoleKey).(models.UserRole)
-		//if userRole != roleAdmin {
-		//	RespondWithError(w, http.StatusForbidden, NoPermissions)
-		//	return
-		//}
-
 		request := models.UpdatePostRequest{}
 		err := json.NewDecoder(r.Body).Decode(&request)
 		if err != nil {
@@ -298,6 +450,9 @@ func (api *PostAPIHandler) UpdatePostHandler() http.Handler {
 			return
 		}
 
+		normalizePostStatus(&request.Status, &request.PublishAt)
+		request.CoAuthors = normalizeCoAuthors(request.CoAuthors)
+
 		validatePostError := validateUpdatePostRequest(&request)
 		if validatePostError != nil {
 			logInfo.Printf("Can't update post: invalid request. Post ID: %s. Error: %s", postID, validatePostError)
@@ -305,6 +460,17 @@ func (api *PostAPIHandler) UpdatePostHandler() http.Handler {
 			return
 		}
 
+		if request.Status != models.PostStatusDraft && !hasPermission(r, PermissionManagePosts) {
+			logInfo.Printf("Can't update post: user doesn't have permissions to publish posts. Post ID: %s, "+
+				"username: %s", postID, GetUsername(r))
+			RespondWithError(w, http.StatusForbidden, NoPermissions)
+			return
+		}
+
+		if !api.checkCoAuthorsExist(w, request.CoAuthors) {
+			return
+		}
+
 		// trim spaces in all tags
 		for tagIndex, tag := range request.Tags {
 			request.Tags[tagIndex] = strings.TrimSpace(tag)
@@ -318,14 +484,26 @@ func (api *PostAPIHandler) UpdatePostHandler() http.Handler {
 		updateRequest := &postService.UpdateRequest{
 			ID:        postID,
 			Title:     request.Title,
-			Snippet:   request.Snippet,
-			Content:   request.Content,
+			Slug:      request.Slug,
 			ContentMD: request.ContentMD,
 			Metadata:  request.Metadata,
 			Tags:      request.Tags,
+			Status:    request.Status,
+			PublishAt: request.PublishAt,
+			CoAuthors: request.CoAuthors,
 		}
-		updatedPost, err := postService.Update(api.db, updateRequest)
+		updatedPost, err := api.posts.Update(updateRequest)
 		if err != nil {
+			switch err {
+			case sql.ErrNoRows:
+				logInfo.Printf("Can't update post: no such post. Post ID: %s", postID)
+				RespondWithError(w, http.StatusBadRequest, NoSuchPost)
+				return
+			case postService.ErrSlugAlreadyExists:
+				logInfo.Printf("Can't update post: slug is already used. Slug: %s", request.Slug)
+				RespondWithError(w, http.StatusBadRequest, PostSlugAlreadyExists)
+				return
+			}
 			logError.Printf("Error updating post in database: %s", err)
 			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
 			return
@@ -341,12 +519,6 @@ func (api *PostAPIHandler) DeletePostHandler() http.Handler {
 	logInfo := api.logInfo
 	logError := api.logError
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		//userRole := r.Context().Value(CtxRoleKey).(models.UserRole)
-		//if userRole != roleAdmin {
-		//	RespondWithError(w, http.StatusForbidden, NoPermissions)
-		//	return
-		//}
-
 		postID := mux.Vars(r)["id"]
 		logInfo.Printf("Got new post deletion request. Post ID: %s", postID)
 
@@ -356,7 +528,7 @@ func (api *PostAPIHandler) DeletePostHandler() http.Handler {
 			return
 		}
 
-		if err := postService.DeleteByID(api.db, postID); err != nil {
+		if err := api.posts.DeleteByID(postID); err != nil {
 			logError.Printf("Error deleting a post. Post ID: %s. Error: %s", postID, err)
 			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
 			return
@@ -367,8 +539,27 @@ func (api *PostAPIHandler) DeletePostHandler() http.Handler {
 	})
 }
 
-// unused
-// GetCertainPostHandler - this handler serves GET request for single post
+// RenderAllPostsHandler - this handler serves requests for re-rendering html of all posts from their markdown
+// Use it after the markdown renderer was changed
+func (api *PostAPIHandler) RenderAllPostsHandler() http.Handler {
+	logInfo := api.logInfo
+	logError := api.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logInfo.Print("Got all posts rendering request")
+
+		renderedCount, err := api.posts.RenderAll()
+		if err != nil {
+			logError.Printf("Error rendering all posts: %s", err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+
+		logInfo.Printf("All posts rendered. Rendered posts count: %d", renderedCount)
+		RespondWithBody(w, http.StatusOK, renderedCount)
+	})
+}
+
+// GetCertainPostHandler - this handler serves GET request for single post together with its comments
 func (api *PostAPIHandler) GetCertainPostHandler() http.Handler {
 	logInfo := api.logInfo
 	logError := api.logError
@@ -382,7 +573,7 @@ func (api *PostAPIHandler) GetCertainPostHandler() http.Handler {
 			return
 		}
 
-		post, err := postService.GetByID(api.db, postID)
+		post, err := api.posts.GetByID(postID)
 		if err != nil {
 			switch err {
 			case sql.ErrNoRows:
@@ -396,12 +587,21 @@ func (api *PostAPIHandler) GetCertainPostHandler() http.Handler {
 			}
 		}
 
-		RespondWithBody(w, http.StatusOK, post)
+		comments, err := api.comments.GetTreeByPostID(postID)
+		if err != nil {
+			logError.Printf("Error retrieving post comments from database. Post ID: %s. Error: %s", postID, err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+
+		RespondWithBody(w, http.StatusOK, &models.CertainPost{Post: post, Comments: comments})
 	})
 }
 
-// unused
-// GetPostsHandler - this handler serves GET request for all posts in the given range
+// GetPostsHandler - this handler serves GET request for published posts in the given range
+// Posts can be filtered by tag passed in 'tag' route variable or 'tag' query param
+// or by author passed in 'username' route variable or 'author' query param
+// Response contains pagination metadata
 func (api *PostAPIHandler) GetPostsHandler() http.Handler {
 	logInfo := api.logInfo
 	logError := api.logError
@@ -410,12 +610,21 @@ func (api *PostAPIHandler) GetPostsHandler() http.Handler {
 			Page:         r.FormValue("page"),
 			PostsPerPage: r.FormValue("posts-per-page"),
 		}
+		tag := mux.Vars(r)["tag"]
+		if tag == "" {
+			tag = r.FormValue("tag")
+		}
+		author := mux.Vars(r)["username"]
+		if author == "" {
+			author = r.FormValue("author")
+		}
 
-		logInfo.Printf("Got range of posts retrieve request. Range params: %+v", rangeParams)
+		logInfo.Printf("Got range of posts retrieve request. Range params: %+v, tag: %s, author: %s",
+			rangeParams, tag, author)
 
 		validateQueryParamsError := ValidateGetPostsRequestQueryParams(rangeParams)
 		if validateQueryParamsError != nil {
-			logError.Printf("Can't retrieve range of posts: invalid query params. Error: %s", validateQueryParamsError)
+			logInfo.Printf("Can't retrieve range of posts: invalid query params. Error: %s", validateQueryParamsError)
 			RespondWithError(w, http.StatusBadRequest, validateQueryParamsError)
 			return
 		}
@@ -435,13 +644,84 @@ func (api *PostAPIHandler) GetPostsHandler() http.Handler {
 		pageAsInt, _ := strconv.Atoi(pageAsString)
 		postsPerPageAsInt, _ := strconv.Atoi(postsPerPageAsString)
 
-		posts, err := postService.GetPostsInRange(api.db, pageAsInt, postsPerPageAsInt)
+		var posts []models.Post
+		var totalCount int
+		var err error
+		switch {
+		case tag != "":
+			posts, err = api.posts.GetPostsInRangeByTag(pageAsInt*postsPerPageAsInt, postsPerPageAsInt, tag)
+			if err == nil {
+				totalCount, err = api.posts.CountPublishedByTag(tag)
+			}
+		case author != "":
+			posts, err = api.posts.GetPostsInRangeByAuthor(pageAsInt*postsPerPageAsInt, postsPerPageAsInt,
+				author)
+			if err == nil {
+				totalCount, err = api.posts.CountPublishedByAuthor(author)
+			}
+		default:
+			posts, err = api.posts.GetPostsInRange(pageAsInt*postsPerPageAsInt, postsPerPageAsInt)
+			if err == nil {
+				totalCount, err = api.posts.CountPublished()
+			}
+		}
 		if err != nil {
 			logError.Printf("Error retrieving range of posts from database: %s", err)
 			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
 			return
 		}
+		if posts == nil {
+			posts = make([]models.Post, 0)
+		}
+
+		RespondWithPage(w, http.StatusOK, posts, NewPagination(r, pageAsInt, postsPerPageAsInt, totalCount))
+	})
+}
+
+// SearchPostsHandler - this handler serves GET request for posts matching the search query
+// Search query is passed in 'q' query param. Results are paginated the same way as range of posts
+func (api *PostAPIHandler) SearchPostsHandler() http.Handler {
+	logInfo := api.logInfo
+	logError := api.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.FormValue("q")
+		rangeParams := &GetPostsRequestQueryParams{
+			Page:         r.FormValue("page"),
+			PostsPerPage: r.FormValue("posts-per-page"),
+		}
+
+		logInfo.Printf("Got posts search request. Query: %s, range params: %+v", query, rangeParams)
+
+		if err := ValidateSearchQuery(query); err != nil {
+			logInfo.Printf("Can't search posts: invalid search query. Query: %s", query)
+			RespondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := ValidateGetPostsRequestQueryParams(rangeParams); err != nil {
+			logInfo.Printf("Can't search posts: invalid query params. Error: %s", err)
+			RespondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		// set default values if params are missed
+		if rangeParams.Page == "" {
+			rangeParams.Page = DefaultPage
+		}
+		if rangeParams.PostsPerPage == "" {
+			rangeParams.PostsPerPage = DefaultPostsPerPage
+		}
+
+		// we know that params are valid so ignore the errors
+		page, _ := strconv.Atoi(rangeParams.Page)
+		postsPerPage, _ := strconv.Atoi(rangeParams.PostsPerPage)
+
+		results, err := api.posts.Search(strings.TrimSpace(query), page*postsPerPage, postsPerPage)
+		if err != nil {
+			logError.Printf("Error searching posts in database. Query: %s. Error: %s", query, err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
 
-		RespondWithBody(w, http.StatusOK, posts)
+		RespondWithBody(w, http.StatusOK, results)
 	})
 }
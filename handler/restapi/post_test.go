@@ -0,0 +1,171 @@
+package restapi
+
+import (
+	"encoding/json"
+	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/repository/memory"
+	"github.com/stretchr/testify/require"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const testAuthor = "author"
+
+func newTestPostAPIHandler() *PostAPIHandler {
+	store := memory.NewStore()
+	users := memory.NewUserRepository(store)
+	_ = users.Save(testAuthor, "author@example.com", "password", models.RoleAdmin)
+	return NewPostAPIHandler(memory.NewPostRepository(store), users, memory.NewCommentRepository(store),
+		testLogger, testLogger)
+}
+
+func newTestCreatePostRequest(title string) models.CreatePostRequest {
+	return models.CreatePostRequest{
+		Title:     title,
+		ContentMD: strings.Repeat("snippet ", MinSnippetLen) + "<cut>" + strings.Repeat("content ", MinSnippetLen),
+		Metadata: models.MetaData{
+			Description: strings.Repeat("d", MinMetaDescriptionLen),
+			Keywords:    []string{strings.Repeat("k", MinMetaKeywordLen)},
+		},
+	}
+}
+
+func createTestPost(t *testing.T, api *PostAPIHandler, request models.CreatePostRequest) models.Post {
+	code, response := serveTestRequest(api.CreatePostHandler(), "POST", "/api/v1/posts", request,
+		testAuthor, models.RoleAdmin, nil)
+	require.Equal(t, http.StatusCreated, code)
+
+	var post models.Post
+	require.NoError(t, json.Unmarshal(response.Body, &post))
+	return post
+}
+
+func getTestPosts(t *testing.T, api *PostAPIHandler, query string) []models.Post {
+	code, response := serveTestRequest(api.GetPostsHandler(), "GET", "/api/v1/posts?page=0&posts-per-page=10"+query,
+		nil, "", "", nil)
+	require.Equal(t, http.StatusOK, code)
+
+	var posts []models.Post
+	require.NoError(t, json.Unmarshal(response.Body, &posts))
+	return posts
+}
+
+func TestCreatePostWithDuplicateLongTitle(t *testing.T) {
+	api := newTestPostAPIHandler()
+	// slug generated from the title is cut right after the hyphen, when it gets a suffix
+	title := strings.Repeat("a", 97) + " bcdef"
+
+	post := createTestPost(t, api, newTestCreatePostRequest(title))
+	require.Equal(t, strings.Repeat("a", 97)+"-bc", post.Slug)
+	post = createTestPost(t, api, newTestCreatePostRequest(title))
+	require.Equal(t, strings.Repeat("a", 97)+"-2", post.Slug)
+}
+
+func TestCreatePostRendersContentAndSetsAuthor(t *testing.T) {
+	api := newTestPostAPIHandler()
+
+	post := createTestPost(t, api, newTestCreatePostRequest("Repository Post Title"))
+	require.Equal(t, "repository-post-title", post.Slug)
+	require.Equal(t, models.PostStatusPublished, post.Status)
+	require.Contains(t, post.Snippet, "snippet")
+	require.Contains(t, post.Content, "content")
+	require.Len(t, post.Authors, 1)
+	require.Equal(t, testAuthor, post.Authors[0].Username)
+}
+
+func TestCreatePostWithTakenSlug(t *testing.T) {
+	api := newTestPostAPIHandler()
+	request := newTestCreatePostRequest("Post With Slug")
+	request.Slug = "taken-slug"
+	createTestPost(t, api, request)
+
+	code, response := serveTestRequest(api.CreatePostHandler(), "POST", "/api/v1/posts", request,
+		testAuthor, models.RoleAdmin, nil)
+	requireErrorResponse(t, code, response, http.StatusBadRequest, PostSlugAlreadyExists)
+}
+
+func TestCreatePostWithNotExistingCoAuthor(t *testing.T) {
+	api := newTestPostAPIHandler()
+	request := newTestCreatePostRequest("Post With Co-Author")
+	request.CoAuthors = []string{"nobody"}
+
+	code, response := serveTestRequest(api.CreatePostHandler(), "POST", "/api/v1/posts", request,
+		testAuthor, models.RoleAdmin, nil)
+	requireErrorResponse(t, code, response, http.StatusBadRequest, NoSuchUser)
+}
+
+func TestGetPostsReturnsOnlyPublishedPosts(t *testing.T) {
+	api := newTestPostAPIHandler()
+	published := createTestPost(t, api, newTestCreatePostRequest("Published Post"))
+	draftRequest := newTestCreatePostRequest("Draft Post")
+	draftRequest.Status = models.PostStatusDraft
+	createTestPost(t, api, draftRequest)
+
+	posts := getTestPosts(t, api, "")
+	require.Len(t, posts, 1)
+	require.Equal(t, published.ID, posts[0].ID)
+}
+
+func TestGetPostsWithInvalidRange(t *testing.T) {
+	api := newTestPostAPIHandler()
+	// offset of the too big page would overflow
+	tooBigPage := strconv.Itoa(math.MaxInt32/MaxPostsPerPage + 1)
+
+	tooManyPostsPerPage := strconv.Itoa(MaxPostsPerPage + 1)
+
+	for _, query := range []string{"posts-per-page=0", "posts-per-page=-1", "posts-per-page=" + tooManyPostsPerPage,
+		"page=-1", "page=" + tooBigPage, "page=" + tooBigPage + "&posts-per-page=1"} {
+		code, response := serveTestRequest(api.GetPostsHandler(), "GET", "/api/v1/posts?"+query, nil, "", "", nil)
+		requireErrorResponse(t, code, response, http.StatusBadRequest, InvalidPostsRange)
+	}
+}
+
+func TestGetPostsByTag(t *testing.T) {
+	api := newTestPostAPIHandler()
+	taggedRequest := newTestCreatePostRequest("Tagged Post")
+	taggedRequest.Tags = []string{"golang"}
+	tagged := createTestPost(t, api, taggedRequest)
+	createTestPost(t, api, newTestCreatePostRequest("Not Tagged Post"))
+
+	posts := getTestPosts(t, api, "&tag=golang")
+	require.Len(t, posts, 1)
+	require.Equal(t, tagged.ID, posts[0].ID)
+	require.Equal(t, []string{"golang"}, posts[0].Tags)
+}
+
+func TestUpdatePostKeepsSlugAndChangesTitle(t *testing.T) {
+	api := newTestPostAPIHandler()
+	post := createTestPost(t, api, newTestCreatePostRequest("Original Title"))
+
+	createRequest := newTestCreatePostRequest("Updated Title")
+	updateRequest := models.UpdatePostRequest{
+		Title:     createRequest.Title,
+		ContentMD: createRequest.ContentMD,
+		Metadata:  createRequest.Metadata,
+	}
+	code, response := serveTestRequest(api.UpdatePostHandler(), "PUT", "/api/v1/posts/"+post.ID, updateRequest,
+		testAuthor, models.RoleAdmin, map[string]string{"id": post.ID})
+	require.Equal(t, http.StatusCreated, code)
+
+	var updatedPost models.Post
+	require.NoError(t, json.Unmarshal(response.Body, &updatedPost))
+	require.Equal(t, "Updated Title", updatedPost.Title)
+	require.Equal(t, post.Slug, updatedPost.Slug)
+}
+
+func TestUpdateNotExistingPost(t *testing.T) {
+	api := newTestPostAPIHandler()
+	createRequest := newTestCreatePostRequest("Not Existing Post")
+	updateRequest := models.UpdatePostRequest{
+		Title:     createRequest.Title,
+		ContentMD: createRequest.ContentMD,
+		Metadata:  createRequest.Metadata,
+	}
+
+	code, response := serveTestRequest(api.UpdatePostHandler(), "PUT", "/api/v1/posts/100", updateRequest,
+		testAuthor, models.RoleAdmin, map[string]string{"id": "100"})
+	requireErrorResponse(t, code, response, http.StatusBadRequest, NoSuchPost)
+}
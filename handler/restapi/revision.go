@@ -0,0 +1,138 @@
+package restapi
+
+import (
+	"database/sql"
+	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/service/postService"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+// error codes for this API
+var (
+	// NoSuchRevision - post revision does not exist
+	NoSuchRevision = models.NewRequestErrorCode("NO_SUCH_REVISION")
+)
+
+func isRevisionIDValid(id string) bool {
+	if id == "" {
+		return false
+	}
+	num, err := strconv.Atoi(id)
+	if err != nil || num < 0 {
+		return false
+	}
+
+	return true
+}
+
+// GetPostRevisionsHandler - this handler serves GET request for all revisions of the post
+func (api *PostAPIHandler) GetPostRevisionsHandler() http.Handler {
+	logInfo := api.logInfo
+	logError := api.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		postID := mux.Vars(r)["id"]
+		logInfo.Printf("Got post revisions retrieve request. Post ID: %s", postID)
+
+		if !IsPostIDValid(postID) {
+			logInfo.Printf("Can't retrieve post revisions: invalid post ID. Post ID: %s", postID)
+			RespondWithError(w, http.StatusBadRequest, InvalidRequest)
+			return
+		}
+
+		revisions, err := api.posts.GetRevisionsByPostID(postID)
+		if err != nil {
+			logError.Printf("Error retrieving post revisions from database. Post ID: %s. Error: %s", postID, err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+
+		RespondWithBody(w, http.StatusOK, revisions)
+	})
+}
+
+// GetPostRevisionsDiffHandler - this handler serves GET request for unified diff between two revisions of the post
+// Revisions are passed in 'from' and 'to' query params
+func (api *PostAPIHandler) GetPostRevisionsDiffHandler() http.Handler {
+	logInfo := api.logInfo
+	logError := api.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		postID := mux.Vars(r)["id"]
+		fromRevisionID := r.FormValue("from")
+		toRevisionID := r.FormValue("to")
+		logInfo.Printf("Got post revisions diff request. Post ID: %s, from revision: %s, to revision: %s",
+			postID, fromRevisionID, toRevisionID)
+
+		if !IsPostIDValid(postID) || !isRevisionIDValid(fromRevisionID) || !isRevisionIDValid(toRevisionID) {
+			logInfo.Printf("Can't diff post revisions: invalid post or revision ID")
+			RespondWithError(w, http.StatusBadRequest, InvalidRequest)
+			return
+		}
+
+		revisions := make([]models.PostRevision, 0, 2)
+		for _, revisionID := range []string{fromRevisionID, toRevisionID} {
+			revision, err := api.posts.GetRevisionByID(postID, revisionID)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					logInfo.Printf("Can't diff post revisions: no such revision. Post ID: %s, revision ID: %s",
+						postID, revisionID)
+					RespondWithError(w, http.StatusNotFound, NoSuchRevision)
+					return
+				}
+				logError.Printf("Error retrieving post revision from database. Revision ID: %s. Error: %s",
+					revisionID, err)
+				RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+				return
+			}
+			revisions = append(revisions, revision)
+		}
+
+		diff, err := postService.DiffRevisions(revisions[0], revisions[1])
+		if err != nil {
+			logError.Printf("Error building post revisions diff. Post ID: %s. Error: %s", postID, err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+
+		RespondWithBody(w, http.StatusOK, &models.PostRevisionsDiff{
+			From: fromRevisionID,
+			To:   toRevisionID,
+			Diff: diff,
+		})
+	})
+}
+
+// RestorePostRevisionHandler - this handler serves requests for restoring the post to the given revision
+func (api *PostAPIHandler) RestorePostRevisionHandler() http.Handler {
+	logInfo := api.logInfo
+	logError := api.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		postID := mux.Vars(r)["id"]
+		revisionID := mux.Vars(r)["revisionID"]
+		logInfo.Printf("Got post revision restore request. Post ID: %s, revision ID: %s", postID, revisionID)
+
+		if !IsPostIDValid(postID) || !isRevisionIDValid(revisionID) {
+			logInfo.Printf("Can't restore post revision: invalid post or revision ID")
+			RespondWithError(w, http.StatusBadRequest, InvalidRequest)
+			return
+		}
+
+		restoredPost, err := api.posts.RestoreRevision(postID, revisionID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				logInfo.Printf("Can't restore post revision: no such revision. Post ID: %s, revision ID: %s",
+					postID, revisionID)
+				RespondWithError(w, http.StatusNotFound, NoSuchRevision)
+				return
+			}
+			logError.Printf("Error restoring post revision. Post ID: %s, revision ID: %s. Error: %s",
+				postID, revisionID, err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+
+		logInfo.Printf("Post revision restored. Post: %+v", restoredPost)
+		RespondWithBody(w, http.StatusCreated, restoredPost)
+	})
+}
@@ -0,0 +1,69 @@
+package restapi
+
+import (
+	"encoding/json"
+	"github.com/blinky-z/Blog/models"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func getTestPostRevisions(t *testing.T, api *PostAPIHandler, postID string) []models.PostRevision {
+	code, response := serveTestRequest(api.GetPostRevisionsHandler(), "GET", "/api/posts/"+postID+"/revisions",
+		nil, testAuthor, models.RoleAdmin, map[string]string{"id": postID})
+	require.Equal(t, http.StatusOK, code)
+
+	var revisions []models.PostRevision
+	require.NoError(t, json.Unmarshal(response.Body, &revisions))
+	return revisions
+}
+
+func TestRestorePostRevision(t *testing.T) {
+	api := newTestPostAPIHandler()
+	createRequest := newTestCreatePostRequest("Original Title")
+	createRequest.Tags = []string{"golang"}
+	post := createTestPost(t, api, createRequest)
+
+	updateRequest := models.UpdatePostRequest{
+		Title:     "Updated Title",
+		ContentMD: createRequest.ContentMD,
+		Metadata:  createRequest.Metadata,
+		Tags:      createRequest.Tags,
+	}
+	code, _ := serveTestRequest(api.UpdatePostHandler(), "PUT", "/api/v1/posts/"+post.ID, updateRequest,
+		testAuthor, models.RoleAdmin, map[string]string{"id": post.ID})
+	require.Equal(t, http.StatusCreated, code)
+
+	revisions := getTestPostRevisions(t, api, post.ID)
+	require.Len(t, revisions, 2)
+	require.Equal(t, "Updated Title", revisions[0].Title)
+	require.Equal(t, "Original Title", revisions[1].Title)
+
+	code, response := serveTestRequest(api.RestorePostRevisionHandler(), "POST",
+		"/api/posts/"+post.ID+"/revisions/"+revisions[1].ID, nil, testAuthor, models.RoleAdmin,
+		map[string]string{"id": post.ID, "revisionID": revisions[1].ID})
+	require.Equal(t, http.StatusCreated, code)
+
+	var restoredPost models.Post
+	require.NoError(t, json.Unmarshal(response.Body, &restoredPost))
+	require.Equal(t, "Original Title", restoredPost.Title)
+	require.Equal(t, post.Slug, restoredPost.Slug)
+	require.Equal(t, []string{"golang"}, restoredPost.Tags)
+
+	// restoring saves a new revision
+	revisions = getTestPostRevisions(t, api, post.ID)
+	require.Len(t, revisions, 3)
+	require.Equal(t, "Original Title", revisions[0].Title)
+}
+
+func TestRestoreRevisionOfAnotherPost(t *testing.T) {
+	api := newTestPostAPIHandler()
+	post := createTestPost(t, api, newTestCreatePostRequest("First Post Title"))
+	anotherPost := createTestPost(t, api, newTestCreatePostRequest("Second Post Title"))
+	revisionID := getTestPostRevisions(t, api, anotherPost.ID)[0].ID
+
+	code, response := serveTestRequest(api.RestorePostRevisionHandler(), "POST",
+		"/api/posts/"+post.ID+"/revisions/"+revisionID, nil, testAuthor, models.RoleAdmin,
+		map[string]string{"id": post.ID, "revisionID": revisionID})
+	requireErrorResponse(t, code, response, http.StatusNotFound, NoSuchRevision)
+}
@@ -1,12 +1,11 @@
 package restapi
 
 import (
-	"database/sql"
 	"encoding/json"
 	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/repository"
 	"github.com/blinky-z/Blog/service/tagService"
 	"github.com/gorilla/mux"
-	pg "github.com/lib/pq"
 	"log"
 	"net/http"
 	"strings"
@@ -14,14 +13,14 @@ import (
 
 // PostAPIHandler - used for dependency injection
 type TagAPIHandler struct {
-	db       *sql.DB
+	tags     repository.TagRepository
 	logInfo  *log.Logger
 	logError *log.Logger
 }
 
-func NewTagAPIHandler(db *sql.DB, logInfo, logError *log.Logger) *TagAPIHandler {
+func NewTagAPIHandler(tags repository.TagRepository, logInfo, logError *log.Logger) *TagAPIHandler {
 	return &TagAPIHandler{
-		db:       db,
+		tags:     tags,
 		logInfo:  logInfo,
 		logError: logError,
 	}
@@ -52,10 +51,10 @@ func (api *TagAPIHandler) CreateTagHandler() http.Handler {
 
 		logInfo.Printf("Got new tag creation request. Tag name: %s", tag)
 
-		createdTag, err := tagService.Save(api.db, tag)
+		createdTag, err := api.tags.Save(tag)
 		if err != nil {
 			logError.Printf("Error saving a tag. Tag: %s. Error: %s", tag, err)
-			if err.(*pg.Error).Code == "23505" {
+			if err == tagService.ErrTagAlreadyExists {
 				RespondWithError(w, http.StatusBadRequest, TagAlreadyExists)
 				return
 			}
@@ -89,10 +88,10 @@ func (api *TagAPIHandler) UpdateTagHandler() http.Handler {
 
 		logInfo.Printf("Got new tag update request. Tag ID: %s, New tag name: %s", tagID, tag)
 
-		createdTag, err := tagService.Update(api.db, tagID, tag)
+		createdTag, err := api.tags.Update(tagID, tag)
 		if err != nil {
 			logError.Printf("Error updating a tag. Tag ID: %s. Error: %s", tag, err)
-			if err.(*pg.Error).Code == "23505" {
+			if err == tagService.ErrTagAlreadyExists {
 				RespondWithError(w, http.StatusBadRequest, TagAlreadyExists)
 				return
 			}
@@ -113,7 +112,7 @@ func (api *TagAPIHandler) DeleteTagHandler() http.Handler {
 		tagID := mux.Vars(r)["id"]
 		logInfo.Printf("Got new tag deletion request. Tag ID: %s", tagID)
 
-		if err := tagService.DeleteByID(api.db, tagID); err != nil {
+		if err := api.tags.DeleteByID(tagID); err != nil {
 			logError.Printf("Error deleting a tag. Post ID: %s. Error: %s", tagID, err)
 			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
 			return
@@ -123,3 +122,18 @@ func (api *TagAPIHandler) DeleteTagHandler() http.Handler {
 		Respond(w, http.StatusOK)
 	})
 }
+
+// GetTagsHandler - this handler serves GET request for all tags together with amount of their published posts
+func (api *TagAPIHandler) GetTagsHandler() http.Handler {
+	logError := api.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tags, err := api.tags.GetAllWithPublishedPostsCount()
+		if err != nil {
+			logError.Printf("Error retrieving tags from database: %s", err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+
+		RespondWithBody(w, http.StatusOK, tags)
+	})
+}
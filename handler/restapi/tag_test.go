@@ -0,0 +1,62 @@
+package restapi
+
+import (
+	"encoding/json"
+	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/repository/memory"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func newTestTagAPIHandler() *TagAPIHandler {
+	return NewTagAPIHandler(memory.NewTagRepository(memory.NewStore()), testLogger, testLogger)
+}
+
+func createTestTag(t *testing.T, api *TagAPIHandler, name string) models.Tag {
+	code, response := serveTestRequest(api.CreateTagHandler(), "POST", "/api/v1/tags",
+		models.CreateTagRequest{Name: name}, "", models.RoleAdmin, nil)
+	require.Equal(t, http.StatusOK, code)
+
+	var tag models.Tag
+	require.NoError(t, json.Unmarshal(response.Body, &tag))
+	return tag
+}
+
+func TestCreateExistingTag(t *testing.T) {
+	api := newTestTagAPIHandler()
+	createTestTag(t, api, "golang")
+
+	code, response := serveTestRequest(api.CreateTagHandler(), "POST", "/api/v1/tags",
+		models.CreateTagRequest{Name: "golang"}, "", models.RoleAdmin, nil)
+	requireErrorResponse(t, code, response, http.StatusBadRequest, TagAlreadyExists)
+}
+
+func TestUpdateTagToExistingName(t *testing.T) {
+	api := newTestTagAPIHandler()
+	createTestTag(t, api, "golang")
+	tag := createTestTag(t, api, "rust")
+
+	code, response := serveTestRequest(api.UpdateTagHandler(), "PUT", "/api/v1/tags/"+tag.ID,
+		models.CreateTagRequest{Name: "golang"}, "", models.RoleAdmin, map[string]string{"id": tag.ID})
+	requireErrorResponse(t, code, response, http.StatusBadRequest, TagAlreadyExists)
+}
+
+func TestGetTagsAfterDeletion(t *testing.T) {
+	api := newTestTagAPIHandler()
+	createTestTag(t, api, "golang")
+	tag := createTestTag(t, api, "rust")
+
+	code, _ := serveTestRequest(api.DeleteTagHandler(), "DELETE", "/api/v1/tags/"+tag.ID, nil, "",
+		models.RoleAdmin, map[string]string{"id": tag.ID})
+	require.Equal(t, http.StatusOK, code)
+
+	code, response := serveTestRequest(api.GetTagsHandler(), "GET", "/api/v1/tags", nil, "", "", nil)
+	require.Equal(t, http.StatusOK, code)
+
+	var tags []models.TagWithPostsCount
+	require.NoError(t, json.Unmarshal(response.Body, &tags))
+	require.Len(t, tags, 1)
+	require.Equal(t, "golang", tags[0].Name)
+	require.Equal(t, 0, tags[0].PostsCount)
+}
@@ -6,41 +6,44 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"encoding/json"
+	jwtmiddleware "github.com/auth0/go-jwt-middleware"
 	"github.com/blinky-z/Blog/models"
 	"github.com/blinky-z/Blog/service/userService"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
 	pg "github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 	"log"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 )
 
-// ctxRoleKey - special type for getting user's role from request context
-type ctxRoleKey string
+// ctxKey - special type for getting authenticated user's data from request context
+type ctxKey string
 
 const (
 	// CtxRoleKey - used to get user's role from request context
-	CtxRoleKey = ctxRoleKey("role")
+	CtxRoleKey = ctxKey("role")
+	// CtxUsernameKey - used to get user's username from request context
+	CtxUsernameKey = ctxKey("username")
 )
 
 // UserAPIHandler - environment container struct to declare all auth handlers as methods
 type UserAPIHandler struct {
 	db              *sql.DB
 	jwtSecret       []byte
-	admins          *[]string
 	logInfo         *log.Logger
 	logError        *log.Logger
 	jwtUserProperty string
 }
 
-func NewUserAPIHandler(db *sql.DB, jwtSecret []byte, admins *[]string, jwtUserProperty string,
+func NewUserAPIHandler(db *sql.DB, jwtSecret []byte, jwtUserProperty string,
 	logInfo, logError *log.Logger) *UserAPIHandler {
 	return &UserAPIHandler{
 		db:              db,
 		jwtSecret:       jwtSecret,
-		admins:          admins,
 		logInfo:         logInfo,
 		logError:        logError,
 		jwtUserProperty: jwtUserProperty,
@@ -65,6 +68,18 @@ var (
 	InvalidFingerprint = models.NewRequestErrorCode("INVALID_FINGERPRINT")
 	// InvalidToken - user provided non-authentic or malformed token
 	InvalidToken = models.NewRequestErrorCode("INVALID_TOKEN")
+	// InvalidRefreshToken - user provided unknown, expired or revoked refresh token
+	InvalidRefreshToken = models.NewRequestErrorCode("INVALID_REFRESH_TOKEN")
+	// InvalidRole - unknown user role
+	InvalidRole = models.NewRequestErrorCode("INVALID_ROLE")
+	// NoSuchUser - user does not exist
+	NoSuchUser = models.NewRequestErrorCode("NO_SUCH_USER")
+	// InvalidDisplayName - too long author display name
+	InvalidDisplayName = models.NewRequestErrorCode("INVALID_DISPLAY_NAME")
+	// InvalidBio - too long author bio
+	InvalidBio = models.NewRequestErrorCode("INVALID_BIO")
+	// InvalidAvatarURL - avatar URL is too long or not an absolute http(s) URL
+	InvalidAvatarURL = models.NewRequestErrorCode("INVALID_AVATAR_URL")
 )
 
 // constants for use in validator methods
@@ -81,12 +96,34 @@ const (
 
 	// MaxEmailLen - maximum length of email
 	MaxEmailLen int = 255
+
+	// MaxDisplayNameLen - maximum length of author display name
+	MaxDisplayNameLen int = 100
+	// MaxBioLen - maximum length of author bio
+	MaxBioLen int = 2000
+	// MaxAvatarURLLen - maximum length of author avatar URL
+	MaxAvatarURLLen int = 500
+)
+
+const (
+	// accessTokenLifetime - lifetime of JWT access token. Access token can't be revoked, so it should be short-lived
+	accessTokenLifetime = 15 * time.Minute
+	// refreshTokenLifetime - lifetime of refresh token. Refresh token is replaced with a new one on every use
+	refreshTokenLifetime = 30 * 24 * time.Hour
 )
 
-// user roles
+// auth cookies
 const (
-	roleAdmin = models.UserRole("admin")
-	roleUser  = models.UserRole("user")
+	// fgpCookieName - cookie with raw fingerprint. Hashed fingerprint is stored in the access token
+	fgpCookieName = "Secure-Fgp"
+	// accessTokenCookieName - cookie with access token. Used to authenticate admin pages requests only
+	accessTokenCookieName = "Secure-Access"
+	// refreshTokenCookieName - cookie with refresh token. It is sent to the user API only
+	refreshTokenCookieName = "Secure-Refresh"
+	refreshTokenCookiePath = "/api/user"
+
+	// LoginPagePath - path of the login page. Unauthenticated users are redirected to this page from admin pages
+	LoginPagePath = "/login"
 )
 
 func validateEmail(email string) models.RequestErrorCode {
@@ -106,6 +143,26 @@ func validateUsername(username string) models.RequestErrorCode {
 	return nil
 }
 
+// validateProfile - validates author profile. All profile fields are optional
+func validateProfile(request *models.UpdateProfileRequest) models.RequestErrorCode {
+	if len([]rune(request.DisplayName)) > MaxDisplayNameLen {
+		return InvalidDisplayName
+	}
+	if len([]rune(request.Bio)) > MaxBioLen {
+		return InvalidBio
+	}
+	if request.AvatarURL != "" {
+		if len(request.AvatarURL) > MaxAvatarURLLen {
+			return InvalidAvatarURL
+		}
+		avatarURL, err := url.Parse(request.AvatarURL)
+		if err != nil || (avatarURL.Scheme != "http" && avatarURL.Scheme != "https") || avatarURL.Host == "" {
+			return InvalidAvatarURL
+		}
+	}
+	return nil
+}
+
 func validatePassword(password string) models.RequestErrorCode {
 	passwordLen := len(password)
 	if passwordLen < MinPwdLen || passwordLen > MaxPwdLen {
@@ -153,36 +210,112 @@ func validateLoginRequest(request models.LoginRequest) models.RequestErrorCode {
 	return validatePassword(password)
 }
 
+// GetUserRole - returns role of the authenticated user. Role is put in request context by FgpAuthentication
+// returns empty role if the request is not authenticated
+func GetUserRole(r *http.Request) models.UserRole {
+	userRole, _ := r.Context().Value(CtxRoleKey).(models.UserRole)
+	return userRole
+}
+
+// GetUsername - returns username of the authenticated user
+// returns empty string if the request is not authenticated
+func GetUsername(r *http.Request) string {
+	username, _ := r.Context().Value(CtxUsernameKey).(string)
+	return username
+}
+
+func validateUserRole(role models.UserRole) models.RequestErrorCode {
+	switch role {
+	case models.RoleAdmin, models.RoleEditor, models.RoleAuthor, models.RoleUser:
+		return nil
+	default:
+		return InvalidRole
+	}
+}
+
+// ExtractAccessToken - token extractor for JWT middleware
+// Access token is read from Authorization header. Browsers can't set this header while navigating admin pages, so
+// the token is also read from cookie, but only for GET requests. Requests modifying data must use the header,
+// which protects them from CSRF
+func ExtractAccessToken(r *http.Request) (string, error) {
+	token, err := jwtmiddleware.FromAuthHeader(r)
+	if err != nil || token != "" {
+		return token, err
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return "", nil
+	}
+
+	tokenCookie, err := r.Cookie(accessTokenCookieName)
+	if err != nil {
+		return "", nil
+	}
+	return tokenCookie.Value, nil
+}
+
+// authenticate - checks fingerprint of the JWT token validated by JWT middleware
+// returns a new request context with user role and username from the token in it and boolean indicating whether
+// fingerprint is valid
+func (api *UserAPIHandler) authenticate(r *http.Request) (context.Context, bool) {
+	token, ok := r.Context().Value(api.jwtUserProperty).(*jwt.Token)
+	if !ok {
+		return nil, false
+	}
+	tokenClaims := token.Claims.(jwt.MapClaims)
+
+	fgpCookie, err := r.Cookie(fgpCookieName)
+	if err != nil {
+		return nil, false
+	}
+	hashedFgp, ok := tokenClaims["fingerprint"].(string)
+	if !ok {
+		return nil, false
+	}
+	if err = bcrypt.CompareHashAndPassword([]byte(hashedFgp), []byte(fgpCookie.Value)); err != nil {
+		return nil, false
+	}
+
+	userRole, _ := tokenClaims["role"].(string)
+	username, _ := tokenClaims["sub"].(string)
+	ctx := context.WithValue(r.Context(), CtxRoleKey, models.UserRole(userRole))
+	return context.WithValue(ctx, CtxUsernameKey, username), true
+}
+
 // FgpAuthentication - middleware for checking fingerprint
 // This handler should be a next step after JWT token checking
 func (api *UserAPIHandler) FgpAuthentication(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		token := r.Context().Value(api.jwtUserProperty).(*jwt.Token)
-		tokenClaims := token.Claims.(jwt.MapClaims)
-
-		fgpCookie, err := r.Cookie("Secure-Fgp")
-		if err != nil {
+		// create a new context with user role in it. We will pass this context to the next handler
+		ctx, ok := api.authenticate(r)
+		if !ok {
 			RespondWithError(w, http.StatusUnauthorized, InvalidFingerprint)
 			return
 		}
 
-		rawFgp := []byte(fgpCookie.Value)
-		hashedFgp := []byte(tokenClaims["fingerprint"].(string))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		if err = bcrypt.CompareHashAndPassword(hashedFgp, rawFgp); err != nil {
-			RespondWithError(w, http.StatusUnauthorized, InvalidFingerprint)
+// RedirectToLoginPage - redirects user to the login page. User returns to the requested page after logging in
+// This function has signature of JWT middleware error handler
+func RedirectToLoginPage(w http.ResponseWriter, r *http.Request, err string) {
+	http.Redirect(w, r, LoginPagePath+"?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+}
+
+// AdminPageAuthentication - middleware for checking fingerprint of admin pages visitors
+// Unauthenticated users are redirected to the login page. This handler should be a next step after JWT token checking
+func (api *UserAPIHandler) AdminPageAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := api.authenticate(r)
+		if !ok {
+			RedirectToLoginPage(w, r, "invalid fingerprint")
 			return
 		}
 
-		// create a new context with user role in it. We will pass this context to the next handler
-		userRole := tokenClaims["role"].(string)
-		ctx := context.WithValue(r.Context(), CtxRoleKey, models.UserRole(userRole))
-
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// unused
 // RegisterUserHandler - serves registration requests
 // This function generates hashed password with bcrypt library before saving user in database
 // use bcrypt.CompareHashAndPassword function to compare password from login form with actual hashed password
@@ -196,7 +329,7 @@ func (api *UserAPIHandler) RegisterUserHandler() http.Handler {
 			return
 		}
 
-		logInfo.Printf("Got new user registration request. Request: %+v", request)
+		logInfo.Printf("Got new user registration request. Username: %s, email: %s", request.Username, request.Email)
 
 		validateError := validateRegistrationRequest(request)
 		if validateError != nil {
@@ -218,11 +351,13 @@ func (api *UserAPIHandler) RegisterUserHandler() http.Handler {
 			return
 		}
 
-		if err := userService.Save(api.db, username, email, string(hashedPassword)); err != nil {
+		// registered users get no permissions. Users from admins list become admins on the server start,
+		// other users get their roles from admins
+		if err := userService.Save(api.db, username, email, string(hashedPassword), models.RoleUser); err != nil {
 			logError.Printf("Error saving user in database. Username: %s. Error: %s", username, err)
 
 			// check for duplicate error
-			if err.(*pg.Error).Code == "23505" {
+			if pqErr, ok := err.(*pg.Error); ok && pqErr.Code == "23505" {
 				RespondWithError(w, http.StatusBadRequest, UserAlreadyRegistered)
 				return
 			}
@@ -235,9 +370,9 @@ func (api *UserAPIHandler) RegisterUserHandler() http.Handler {
 	})
 }
 
-// generateFingerprint - generates fingerprint
+// generateSecureToken - generates random token, such as fingerprint or refresh token
 // This function uses cryptographically secure random number generator
-func generateFingerprint() (string, error) {
+func generateSecureToken() (string, error) {
 	bytes := make([]byte, 32)
 	_, err := rand.Read(bytes)
 	if err != nil {
@@ -247,38 +382,72 @@ func generateFingerprint() (string, error) {
 	return base64.URLEncoding.EncodeToString(bytes), nil
 }
 
-// IsUserAdmin - check if the given user is an admin
-func IsUserAdmin(username string, admins *[]string) bool {
-	for _, currentAdminUsername := range *admins {
-		if username == currentAdminUsername {
-			return true
-		}
-	}
-	return false
-}
-
-// generateJwtToken - generates JWT token
+// generateJwtToken - generates JWT access token
 // Generate and hash fingerprint before calling this function
-func generateJwtToken(login, fgp string, api *UserAPIHandler) (string, error) {
+func generateJwtToken(login string, role models.UserRole, fgp string, api *UserAPIHandler) (string, error) {
 	var claims models.TokenClaims
 
 	// set required claims
-	claims.ExpiresAt = time.Now().Add(1 * time.Hour).Unix()
+	claims.Subject = login
+	claims.ExpiresAt = time.Now().Add(accessTokenLifetime).Unix()
 	claims.Fingerprint = fgp
-	if IsUserAdmin(login, api.admins) {
-		claims.Role = roleAdmin
-	} else {
-		claims.Role = roleUser
-	}
+	claims.Role = role
 
 	// generate and sign the token
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(api.jwtSecret)
 }
 
-// unused
+// setAuthCookie - sets cookie inaccessible from JS
+func setAuthCookie(w http.ResponseWriter, name, value, path string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{Name: name, Value: value, SameSite: http.SameSiteStrictMode, HttpOnly: true,
+		Secure: true, Expires: expires, Path: path})
+}
+
+// clearAuthCookies - removes all auth cookies from the browser
+func clearAuthCookies(w http.ResponseWriter) {
+	for _, cookie := range []*http.Cookie{
+		{Name: fgpCookieName, Path: "/"},
+		{Name: accessTokenCookieName, Path: "/"},
+		{Name: refreshTokenCookieName, Path: refreshTokenCookiePath},
+	} {
+		cookie.MaxAge = -1
+		cookie.SameSite = http.SameSiteStrictMode
+		cookie.HttpOnly = true
+		cookie.Secure = true
+		http.SetCookie(w, cookie)
+	}
+}
+
+// issueAccessToken - generates a new fingerprint and a new access token with the hashed fingerprint in it
+// Raw fingerprint and the access token are set in cookies. Role changes take effect when a new token is issued
+// returns the access token and error
+func (api *UserAPIHandler) issueAccessToken(w http.ResponseWriter, username string, role models.UserRole) (string, error) {
+	rawFgp, err := generateSecureToken()
+	if err != nil {
+		return "", err
+	}
+
+	// hash generated fingerprint for storing in JWT token
+	hashedFgp, err := bcrypt.GenerateFromPassword([]byte(rawFgp), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	// create JWT token with username and hashed fingerprint in it
+	token, err := generateJwtToken(username, role, string(hashedFgp), api)
+	if err != nil {
+		return "", err
+	}
+
+	expires := time.Now().Add(accessTokenLifetime)
+	setAuthCookie(w, fgpCookieName, rawFgp, "/", expires)
+	setAuthCookie(w, accessTokenCookieName, token, "/", expires)
+	return token, nil
+}
+
 // LoginUserHandler - serves user login request
-// This function sends back generated JWT token as payload
+// This function sends back generated JWT access token as payload and sets refresh token in cookie
 func (api *UserAPIHandler) LoginUserHandler() http.Handler {
 	logInfo := api.logInfo
 	logError := api.logError
@@ -289,7 +458,7 @@ func (api *UserAPIHandler) LoginUserHandler() http.Handler {
 			return
 		}
 
-		logInfo.Printf("Got new user login request. Request: %+v", request)
+		logInfo.Printf("Got new user login request. Username: %s, email: %s", request.Username, request.Email)
 
 		username := strings.TrimSpace(request.Username)
 		email := strings.TrimSpace(request.Email)
@@ -305,16 +474,17 @@ func (api *UserAPIHandler) LoginUserHandler() http.Handler {
 		logInfo.Printf("Getting hashed password from database for user login. Username: %s, email: %s", username, email)
 
 		var hashedPassword string
+		var role models.UserRole
 		var err error
 
-		// get hashed password from database
+		// get hashed password and role from database
 		if username != "" {
-			err = api.db.QueryRow("select password from users where username = $1", username).
-				Scan(&hashedPassword)
+			err = api.db.QueryRow("select password, role from users where username = $1", username).
+				Scan(&hashedPassword, &role)
 		} else {
-			// also get username for this user as we will need this later to set username in cookie
-			err = api.db.QueryRow("select username, password from users where email = $1", email).
-				Scan(&username, &hashedPassword)
+			// also get username for this user as we will need this later to set username in token
+			err = api.db.QueryRow("select username, password, role from users where email = $1", email).
+				Scan(&username, &hashedPassword, &role)
 		}
 		if err != nil {
 			if err == sql.ErrNoRows {
@@ -336,40 +506,215 @@ func (api *UserAPIHandler) LoginUserHandler() http.Handler {
 			return
 		}
 
-		logInfo.Printf("Generating fingerprint for user login. Username: %s, email: %s", username, email)
-		rawFgp, err := generateFingerprint()
+		logInfo.Printf("Generating tokens for user login. Username: %s, email: %s", username, email)
+		token, err := api.issueAccessToken(w, username, role)
 		if err != nil {
-			logError.Printf("Bad login: error generating fingerprint. Username: %s, email: %s. Error: %s",
+			logError.Printf("Bad login: error generating access token. Username: %s, email: %s. Error: %s",
 				username, email, err)
 			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
 			return
 		}
 
-		// set raw fingerprint cookie. This cookie is inaccessible from JS
-		ctxCookie := &http.Cookie{Name: "Secure-Fgp", Value: rawFgp, SameSite: http.SameSiteStrictMode, HttpOnly: true,
-			Secure: true, Expires: time.Now().Add(time.Hour * 1), Path: "/"}
-		http.SetCookie(w, ctxCookie)
-
-		// hash generated fingerprint for storing in JWT token
-		hashedFgp, err := bcrypt.GenerateFromPassword([]byte(rawFgp), bcrypt.DefaultCost)
+		refreshToken, err := generateSecureToken()
 		if err != nil {
-			logError.Printf("Bad login: error hashing generated fingerprint. Username: %s, email: %s. Error: %s",
+			logError.Printf("Bad login: error generating refresh token. Username: %s, email: %s. Error: %s",
 				username, email, err)
 			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
 			return
 		}
-
-		// create JWT token with username and hashed fingerprint in it
-		// we will save the token on the client side
-		token, err := generateJwtToken(username, string(hashedFgp), api)
-		if err != nil {
-			logError.Printf("Bad login: error generating JWT token. Username: %s, email: %s. Error: %s",
+		refreshTokenExpires := time.Now().Add(refreshTokenLifetime)
+		if err = userService.SaveRefreshToken(api.db, username, refreshToken, refreshTokenExpires); err != nil {
+			logError.Printf("Bad login: error saving refresh token. Username: %s, email: %s. Error: %s",
 				username, email, err)
 			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
 			return
 		}
+		setAuthCookie(w, refreshTokenCookieName, refreshToken, refreshTokenCookiePath, refreshTokenExpires)
 
 		logInfo.Printf("Successful login. Username: %s, email: %s", username, email)
 		RespondWithBody(w, http.StatusOK, token)
 	})
 }
+
+// RefreshTokenHandler - serves access token refresh requests
+// Refresh token is taken from cookie and replaced with a new one. This function sends back a new access token as payload
+func (api *UserAPIHandler) RefreshTokenHandler() http.Handler {
+	logInfo := api.logInfo
+	logError := api.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		refreshTokenCookie, err := r.Cookie(refreshTokenCookieName)
+		if err != nil {
+			RespondWithError(w, http.StatusUnauthorized, InvalidRefreshToken)
+			return
+		}
+
+		newRefreshToken, err := generateSecureToken()
+		if err != nil {
+			logError.Printf("Can't refresh token: error generating refresh token. Error: %s", err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+		newRefreshTokenExpires := time.Now().Add(refreshTokenLifetime)
+
+		username, err := userService.RotateRefreshToken(api.db, refreshTokenCookie.Value, newRefreshToken,
+			newRefreshTokenExpires)
+		if err != nil {
+			switch err {
+			case userService.ErrInvalidRefreshToken:
+				logInfo.Print("Can't refresh token: invalid refresh token")
+			case userService.ErrRefreshTokenReused:
+				logInfo.Printf("Can't refresh token: revoked refresh token was reused. "+
+					"All refresh tokens of the user are revoked. Username: %s", username)
+			default:
+				logError.Printf("Error rotating refresh token: %s", err)
+				RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+				return
+			}
+			clearAuthCookies(w)
+			RespondWithError(w, http.StatusUnauthorized, InvalidRefreshToken)
+			return
+		}
+
+		role, err := userService.GetRoleByUsername(api.db, username)
+		if err != nil {
+			logError.Printf("Can't refresh token: error retrieving user role. Username: %s. Error: %s", username, err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+
+		token, err := api.issueAccessToken(w, username, role)
+		if err != nil {
+			logError.Printf("Can't refresh token: error generating access token. Username: %s. Error: %s",
+				username, err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+		setAuthCookie(w, refreshTokenCookieName, newRefreshToken, refreshTokenCookiePath, newRefreshTokenExpires)
+
+		logInfo.Printf("Token refreshed. Username: %s", username)
+		RespondWithBody(w, http.StatusOK, token)
+	})
+}
+
+// LogoutUserHandler - serves user logout requests
+// This function revokes refresh token and removes auth cookies. Issued access token remains valid until it expires
+func (api *UserAPIHandler) LogoutUserHandler() http.Handler {
+	logInfo := api.logInfo
+	logError := api.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if refreshTokenCookie, err := r.Cookie(refreshTokenCookieName); err == nil {
+			if err = userService.RevokeRefreshToken(api.db, refreshTokenCookie.Value); err != nil {
+				logError.Printf("Error revoking refresh token: %s", err)
+				RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+				return
+			}
+		}
+
+		clearAuthCookies(w)
+
+		logInfo.Print("Successful logout")
+		Respond(w, http.StatusOK)
+	})
+}
+
+// UpdateUserRoleHandler - serves user role update requests
+// New role takes effect when the user refreshes access token
+func (api *UserAPIHandler) UpdateUserRoleHandler() http.Handler {
+	logInfo := api.logInfo
+	logError := api.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request models.UpdateUserRoleRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			RespondWithError(w, http.StatusBadRequest, BadRequestBody)
+			return
+		}
+
+		username := mux.Vars(r)["username"]
+		logInfo.Printf("Got new user role update request. Username: %s, role: %s", username, request.Role)
+
+		if err := validateUserRole(request.Role); err != nil {
+			logInfo.Printf("Can't update user role: invalid role. Role: %s", request.Role)
+			RespondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := userService.UpdateRole(api.db, username, request.Role); err != nil {
+			if err == sql.ErrNoRows {
+				logInfo.Printf("Can't update user role: no such user. Username: %s", username)
+				RespondWithError(w, http.StatusBadRequest, NoSuchUser)
+				return
+			}
+			logError.Printf("Error updating user role. Username: %s. Error: %s", username, err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+
+		logInfo.Printf("User role updated. Username: %s, role: %s", username, request.Role)
+		Respond(w, http.StatusOK)
+	})
+}
+
+// UpdateProfileHandler - serves profile update requests of the authenticated user
+// Profile is shown on the author page and in bylines of the author posts
+func (api *UserAPIHandler) UpdateProfileHandler() http.Handler {
+	logInfo := api.logInfo
+	logError := api.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request models.UpdateProfileRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			RespondWithError(w, http.StatusBadRequest, BadRequestBody)
+			return
+		}
+
+		username := GetUsername(r)
+		logInfo.Printf("Got new profile update request. Username: %s, request: %+v", username, request)
+
+		request.DisplayName = strings.TrimSpace(request.DisplayName)
+		request.Bio = strings.TrimSpace(request.Bio)
+		request.AvatarURL = strings.TrimSpace(request.AvatarURL)
+		if err := validateProfile(&request); err != nil {
+			logInfo.Printf("Can't update profile: invalid request. Username: %s. Error: %s", username, err)
+			RespondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		author, err := userService.UpdateProfile(api.db, username, &request)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				logInfo.Printf("Can't update profile: no such user. Username: %s", username)
+				RespondWithError(w, http.StatusBadRequest, NoSuchUser)
+				return
+			}
+			logError.Printf("Error updating profile. Username: %s. Error: %s", username, err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+
+		logInfo.Printf("Profile updated. Profile: %+v", author)
+		RespondWithBody(w, http.StatusOK, author)
+	})
+}
+
+// GetAuthorHandler - serves GET request for public profile of the user passed in 'username' route variable
+func (api *UserAPIHandler) GetAuthorHandler() http.Handler {
+	logInfo := api.logInfo
+	logError := api.logError
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username := mux.Vars(r)["username"]
+		logInfo.Printf("Got author retrieve request. Username: %s", username)
+
+		author, err := userService.GetAuthorByUsername(api.db, username)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				logInfo.Printf("Can't retrieve author: no such user. Username: %s", username)
+				RespondWithError(w, http.StatusNotFound, NoSuchUser)
+				return
+			}
+			logError.Printf("Error retrieving author. Username: %s. Error: %s", username, err)
+			RespondWithError(w, http.StatusInternalServerError, TechnicalError)
+			return
+		}
+
+		RespondWithBody(w, http.StatusOK, author)
+	})
+}
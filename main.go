@@ -2,8 +2,14 @@ package main
 
 import (
 	"github.com/blinky-z/Blog/server"
+	"os"
 )
 
 func main() {
+	// 'migrate' subcommand manages database schema instead of running the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(server.RunMigrate(os.Args[2:]))
+	}
+
 	server.RunServer()
 }
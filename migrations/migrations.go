@@ -0,0 +1,261 @@
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// files - migration scripts. Every migration consists of '<version>_<name>.up.sql' and '<version>_<name>.down.sql'
+// scripts. Versions are applied in ascending order
+//
+//go:embed sql/*.sql
+var files embed.FS
+
+// migrationsDir - directory of the embedded migration scripts
+const migrationsDir = "sql"
+
+// schemaTableName - table the applied migration versions are recorded in
+const schemaTableName = "schema_migrations"
+
+// lockID - id of the advisory lock taken while migrating, so concurrently started servers don't apply
+// the same migration twice
+const lockID = 4215307
+
+// migrationFilePattern - pattern of the migration script name
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// ErrNoAppliedMigrations - there are no applied migrations to revert
+var ErrNoAppliedMigrations = errors.New("no applied migrations")
+
+// Migration - represents versioned database schema change
+// @Up - script applying the change
+// @Down - script reverting the change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status - represents migration together with its applying state
+// @AppliedAt - time the migration was applied at. Nil if the migration is pending
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Load - returns all embedded migrations sorted by version
+func Load() ([]Migration, error) {
+	entries, err := files.ReadDir(migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		script, err := files.ReadFile(path.Join(migrationsDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has scripts with different names", version)
+		}
+		if match[3] == "up" {
+			migration.Up = string(script)
+		} else {
+			migration.Down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d must have both up and down scripts", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// beginLocked - begins a new transaction holding the migration lock
+// The schema table is created if it doesn't exist
+func beginLocked(db *sql.DB) (*sql.Tx, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.Exec("select pg_advisory_xact_lock($1)", lockID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err = tx.Exec("create table if not exists " + schemaTableName + " (" +
+		"version integer primary key, " +
+		"name character varying(100) not null, " +
+		"applied_at timestamptz not null default NOW())"); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
+
+// getAppliedVersions - returns applied migration versions with the time they were applied at
+func getAppliedVersions(tx *sql.Tx) (map[int]time.Time, error) {
+	applied := make(map[int]time.Time)
+
+	rows, err := tx.Query("select version, applied_at from " + schemaTableName)
+	if err != nil {
+		return applied, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return applied, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// Up - applies all pending migrations. Every migration is applied in a separate transaction
+// returns applied migrations and error
+func Up(db *sql.DB) ([]Migration, error) {
+	appliedNow := make([]Migration, 0)
+
+	migrations, err := Load()
+	if err != nil {
+		return appliedNow, err
+	}
+
+	for _, migration := range migrations {
+		tx, err := beginLocked(db)
+		if err != nil {
+			return appliedNow, err
+		}
+		// another server could apply the migration while the lock was waited for
+		applied, err := getAppliedVersions(tx)
+		if err != nil {
+			tx.Rollback()
+			return appliedNow, err
+		}
+		if _, ok := applied[migration.Version]; ok {
+			tx.Rollback()
+			continue
+		}
+
+		if _, err = tx.Exec(migration.Up); err != nil {
+			tx.Rollback()
+			return appliedNow, fmt.Errorf("error applying migration %d_%s: %s", migration.Version, migration.Name, err)
+		}
+		if _, err = tx.Exec("insert into "+schemaTableName+" (version, name) values ($1, $2)",
+			migration.Version, migration.Name); err != nil {
+			tx.Rollback()
+			return appliedNow, err
+		}
+		if err = tx.Commit(); err != nil {
+			return appliedNow, err
+		}
+		appliedNow = append(appliedNow, migration)
+	}
+
+	return appliedNow, nil
+}
+
+// Down - reverts the last applied migration
+// returns reverted migration and error
+// returns ErrNoAppliedMigrations error if there are no applied migrations
+func Down(db *sql.DB) (Migration, error) {
+	migrations, err := Load()
+	if err != nil {
+		return Migration{}, err
+	}
+
+	tx, err := beginLocked(db)
+	if err != nil {
+		return Migration{}, err
+	}
+
+	var lastVersion sql.NullInt64
+	if err = tx.QueryRow("select max(version) from " + schemaTableName).Scan(&lastVersion); err != nil {
+		tx.Rollback()
+		return Migration{}, err
+	}
+	if !lastVersion.Valid {
+		tx.Rollback()
+		return Migration{}, ErrNoAppliedMigrations
+	}
+
+	for _, migration := range migrations {
+		if int64(migration.Version) != lastVersion.Int64 {
+			continue
+		}
+
+		if _, err = tx.Exec(migration.Down); err != nil {
+			tx.Rollback()
+			return migration, fmt.Errorf("error reverting migration %d_%s: %s", migration.Version, migration.Name, err)
+		}
+		if _, err = tx.Exec("delete from "+schemaTableName+" where version = $1", migration.Version); err != nil {
+			tx.Rollback()
+			return migration, err
+		}
+		return migration, tx.Commit()
+	}
+
+	tx.Rollback()
+	return Migration{}, fmt.Errorf("applied migration %d is unknown to this server version", lastVersion.Int64)
+}
+
+// GetStatus - returns all migrations with their applying state
+func GetStatus(db *sql.DB) ([]Status, error) {
+	statuses := make([]Status, 0)
+
+	migrations, err := Load()
+	if err != nil {
+		return statuses, err
+	}
+
+	tx, err := beginLocked(db)
+	if err != nil {
+		return statuses, err
+	}
+	defer tx.Rollback()
+
+	applied, err := getAppliedVersions(tx)
+	if err != nil {
+		return statuses, err
+	}
+
+	for _, migration := range migrations {
+		status := Status{Migration: migration}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
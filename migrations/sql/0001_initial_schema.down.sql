@@ -0,0 +1,10 @@
+DROP TABLE if exists media;
+DROP TABLE if exists comments;
+DROP TABLE if exists post_tags;
+DROP TABLE if exists tags;
+DROP TABLE if exists post_revisions;
+DROP TABLE if exists post_coauthors;
+DROP TABLE if exists post_slugs;
+DROP TABLE if exists posts;
+DROP TABLE if exists refresh_tokens;
+DROP TABLE if exists users;
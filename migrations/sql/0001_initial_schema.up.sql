@@ -0,0 +1,160 @@
+-- initial schema. Statements are idempotent, so the migration also upgrades databases created by the former
+-- sql-scripts and marks them as migrated
+
+CREATE TABLE if not exists users
+(
+    USERNAME     CHARACTER VARYING(36)  not null,
+    EMAIL        CHARACTER VARYING(255) not null,
+    PASSWORD     CHARACTER VARYING(255) not null,
+    ROLE         CHARACTER VARYING(16)  not null DEFAULT 'user',
+    DISPLAY_NAME CHARACTER VARYING(100) not null DEFAULT '',
+    BIO          text                   not null DEFAULT '',
+    AVATAR_URL   CHARACTER VARYING(500) not null DEFAULT ''
+);
+
+CREATE UNIQUE INDEX if not exists usernameIndex ON users (USERNAME);
+CREATE UNIQUE INDEX if not exists emailIndex ON users (EMAIL);
+
+ALTER TABLE users ADD COLUMN if not exists ROLE CHARACTER VARYING(16) not null DEFAULT 'user';
+ALTER TABLE users ADD COLUMN if not exists DISPLAY_NAME CHARACTER VARYING(100) not null DEFAULT '';
+ALTER TABLE users ADD COLUMN if not exists BIO text not null DEFAULT '';
+ALTER TABLE users ADD COLUMN if not exists AVATAR_URL CHARACTER VARYING(500) not null DEFAULT '';
+
+CREATE TABLE if not exists refresh_tokens
+(
+    ID         SERIAL PRIMARY KEY,
+    USERNAME   CHARACTER VARYING(36) not null,
+    TOKEN_HASH CHARACTER(64)         not null,
+    EXPIRES_AT TIMESTAMPTZ           not null,
+    REVOKED    BOOLEAN               not null DEFAULT FALSE,
+    CREATED_AT TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX if not exists refreshTokensTokenHashIndex ON refresh_tokens (TOKEN_HASH);
+CREATE INDEX if not exists refreshTokensUsernameIndex ON refresh_tokens (USERNAME);
+
+CREATE TABLE if not exists posts
+(
+    ID         SERIAL PRIMARY KEY,
+    TITLE      CHARACTER VARYING(200) not null,
+    DATE       TIMESTAMPTZ DEFAULT NOW(),
+    UPDATED    TIMESTAMPTZ DEFAULT NOW(),
+    METADATA   text                   not null,
+    SNIPPET    text                   not null,
+    CONTENT    text                   not null,
+    CONTENT_MD text                   not null,
+    STATUS     CHARACTER VARYING(16)  not null DEFAULT 'published',
+    PUBLISH_AT TIMESTAMPTZ,
+    SLUG       CHARACTER VARYING(100),
+    -- username of the user created the post. Posts created before authorship was introduced have no author
+    AUTHOR     CHARACTER VARYING(36)
+);
+
+ALTER TABLE posts ADD COLUMN if not exists STATUS CHARACTER VARYING(16) not null DEFAULT 'published';
+ALTER TABLE posts ADD COLUMN if not exists PUBLISH_AT TIMESTAMPTZ;
+ALTER TABLE posts ADD COLUMN if not exists SLUG CHARACTER VARYING(100);
+ALTER TABLE posts ADD COLUMN if not exists UPDATED TIMESTAMPTZ;
+UPDATE posts SET UPDATED = DATE WHERE UPDATED is null;
+ALTER TABLE posts ALTER COLUMN UPDATED SET DEFAULT NOW();
+ALTER TABLE posts ADD COLUMN if not exists AUTHOR CHARACTER VARYING(36);
+
+CREATE INDEX if not exists postsStatusDateIndex ON posts (STATUS, DATE);
+CREATE UNIQUE INDEX if not exists postsSlugIndex ON posts (SLUG);
+
+-- previous slugs of renamed posts. Used to redirect old URLs
+CREATE TABLE if not exists post_slugs
+(
+    SLUG    CHARACTER VARYING(100) PRIMARY KEY,
+    POST_ID INTEGER                not null
+);
+
+CREATE INDEX if not exists postSlugsPostIdIndex ON post_slugs (POST_ID);
+
+CREATE INDEX if not exists postsAuthorIndex ON posts (AUTHOR);
+
+-- co-authors of the posts. Post author is stored in the posts table
+CREATE TABLE if not exists post_coauthors
+(
+    POST_ID  INTEGER               not null,
+    USERNAME CHARACTER VARYING(36) not null,
+    POSITION INTEGER               not null,
+    PRIMARY KEY (POST_ID, USERNAME)
+);
+
+CREATE INDEX if not exists postCoauthorsUsernameIndex ON post_coauthors (USERNAME);
+
+-- full-text search over published posts. Posts are written in russian and english, so both configurations are used
+ALTER TABLE posts ADD COLUMN if not exists SEARCH_VECTOR tsvector GENERATED ALWAYS AS (
+            setweight(to_tsvector('russian', TITLE), 'A') || setweight(to_tsvector('english', TITLE), 'A') ||
+            setweight(to_tsvector('russian', SNIPPET), 'B') || setweight(to_tsvector('english', SNIPPET), 'B') ||
+            setweight(to_tsvector('russian', CONTENT), 'C') || setweight(to_tsvector('english', CONTENT), 'C')
+    ) STORED;
+
+CREATE INDEX if not exists postsSearchVectorIndex ON posts USING GIN (SEARCH_VECTOR);
+
+CREATE TABLE if not exists post_revisions
+(
+    ID         SERIAL PRIMARY KEY,
+    POST_ID    INTEGER                not null,
+    DATE       TIMESTAMPTZ DEFAULT NOW(),
+    TITLE      CHARACTER VARYING(200) not null,
+    CONTENT_MD text                   not null,
+    METADATA   text                   not null
+);
+
+CREATE INDEX if not exists postRevisionsPostIdIndex ON post_revisions (POST_ID);
+
+-- save current version of the existing posts as their first revision
+INSERT INTO post_revisions (POST_ID, DATE, TITLE, CONTENT_MD, METADATA)
+SELECT ID, DATE, TITLE, CONTENT_MD, METADATA
+FROM posts
+WHERE NOT EXISTS(SELECT FROM post_revisions WHERE post_revisions.POST_ID = posts.ID);
+
+CREATE TABLE if not exists tags
+(
+    TAG_ID SERIAL PRIMARY KEY,
+    TAG    varchar(36) not null
+);
+
+CREATE UNIQUE INDEX if not exists tagsTagIndex ON tags (TAG);
+
+CREATE TABLE if not exists post_tags
+(
+    POST_ID INTEGER not null,
+    TAG_ID  INTEGER not null,
+    PRIMARY KEY (POST_ID, TAG_ID)
+);
+
+CREATE INDEX if not exists postTagsTagIDIndex ON post_tags (TAG_ID);
+
+CREATE TABLE if not exists comments
+(
+    ID        SERIAL PRIMARY KEY,
+    POST_ID   INTEGER                 not null,
+    PARENT_ID INTEGER,
+    AUTHOR    CHARACTER VARYING(36)   not null,
+    DATE      TIMESTAMPTZ DEFAULT NOW(),
+    CONTENT   CHARACTER VARYING(2048) not null,
+    DELETED   BOOLEAN     DEFAULT FALSE
+);
+
+CREATE INDEX if not exists postIdIndex ON comments (POST_ID);
+CREATE INDEX if not exists parentIdIndex ON comments (PARENT_ID);
+
+CREATE TABLE if not exists media
+(
+    ID           SERIAL PRIMARY KEY,
+    STORAGE_KEY  CHARACTER VARYING(100) not null,
+    FILENAME     CHARACTER VARYING(255) not null,
+    CONTENT_TYPE CHARACTER VARYING(100) not null,
+    SIZE         BIGINT                 not null,
+    WIDTH        INTEGER                not null DEFAULT 0,
+    HEIGHT       INTEGER                not null DEFAULT 0,
+    UPLOADED_BY  CHARACTER VARYING(36)  not null,
+    CREATED_AT   TIMESTAMPTZ            not null DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX if not exists mediaStorageKeyIndex ON media (STORAGE_KEY);
+
+ALTER TABLE media ADD COLUMN if not exists WIDTH INTEGER not null DEFAULT 0;
+ALTER TABLE media ADD COLUMN if not exists HEIGHT INTEGER not null DEFAULT 0;
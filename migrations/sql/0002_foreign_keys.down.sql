@@ -0,0 +1,7 @@
+ALTER TABLE comments DROP CONSTRAINT if exists commentsParentIdFk;
+ALTER TABLE post_tags DROP CONSTRAINT if exists postTagsTagIdFk;
+ALTER TABLE comments DROP CONSTRAINT if exists commentsPostIdFk;
+ALTER TABLE post_coauthors DROP CONSTRAINT if exists postCoauthorsPostIdFk;
+ALTER TABLE post_slugs DROP CONSTRAINT if exists postSlugsPostIdFk;
+ALTER TABLE post_revisions DROP CONSTRAINT if exists postRevisionsPostIdFk;
+ALTER TABLE post_tags DROP CONSTRAINT if exists postTagsPostIdFk;
@@ -0,0 +1,34 @@
+-- remove rows left behind by posts, tags and comments deleted before the foreign keys were introduced
+DELETE
+FROM post_tags
+WHERE NOT EXISTS(SELECT FROM posts WHERE posts.ID = post_tags.POST_ID)
+   OR NOT EXISTS(SELECT FROM tags WHERE tags.TAG_ID = post_tags.TAG_ID);
+DELETE FROM post_revisions WHERE NOT EXISTS(SELECT FROM posts WHERE posts.ID = post_revisions.POST_ID);
+DELETE FROM post_slugs WHERE NOT EXISTS(SELECT FROM posts WHERE posts.ID = post_slugs.POST_ID);
+DELETE FROM post_coauthors WHERE NOT EXISTS(SELECT FROM posts WHERE posts.ID = post_coauthors.POST_ID);
+DELETE FROM comments WHERE NOT EXISTS(SELECT FROM posts WHERE posts.ID = comments.POST_ID);
+-- replies to removed comments become top-level comments
+UPDATE comments
+SET PARENT_ID = NULL
+WHERE PARENT_ID is not null
+  AND NOT EXISTS(SELECT FROM comments parents WHERE parents.ID = comments.PARENT_ID);
+
+-- rows referencing a post are deleted together with the post
+ALTER TABLE post_tags
+    ADD CONSTRAINT postTagsPostIdFk FOREIGN KEY (POST_ID) REFERENCES posts (ID) ON DELETE CASCADE;
+ALTER TABLE post_revisions
+    ADD CONSTRAINT postRevisionsPostIdFk FOREIGN KEY (POST_ID) REFERENCES posts (ID) ON DELETE CASCADE;
+ALTER TABLE post_slugs
+    ADD CONSTRAINT postSlugsPostIdFk FOREIGN KEY (POST_ID) REFERENCES posts (ID) ON DELETE CASCADE;
+ALTER TABLE post_coauthors
+    ADD CONSTRAINT postCoauthorsPostIdFk FOREIGN KEY (POST_ID) REFERENCES posts (ID) ON DELETE CASCADE;
+ALTER TABLE comments
+    ADD CONSTRAINT commentsPostIdFk FOREIGN KEY (POST_ID) REFERENCES posts (ID) ON DELETE CASCADE;
+
+-- deleted tag is removed from all tagged posts
+ALTER TABLE post_tags
+    ADD CONSTRAINT postTagsTagIdFk FOREIGN KEY (TAG_ID) REFERENCES tags (TAG_ID) ON DELETE CASCADE;
+
+-- replies are kept when their parent comment is removed
+ALTER TABLE comments
+    ADD CONSTRAINT commentsParentIdFk FOREIGN KEY (PARENT_ID) REFERENCES comments (ID) ON DELETE SET NULL;
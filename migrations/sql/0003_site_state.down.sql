@@ -0,0 +1 @@
+DROP TABLE if exists site_state;
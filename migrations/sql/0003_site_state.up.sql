@@ -0,0 +1,9 @@
+-- state of the whole site. The table always has a single row
+CREATE TABLE if not exists site_state
+(
+    ID              BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (ID),
+    -- time of the last post update or deletion. Deleted and unpublished posts leave no change time of their own
+    CONTENT_CHANGED TIMESTAMPTZ not null DEFAULT NOW()
+);
+
+INSERT INTO site_state DEFAULT VALUES ON CONFLICT DO NOTHING;
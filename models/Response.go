@@ -25,7 +25,25 @@ func (e *errorString) MarshalJSON() ([]byte, error) {
 
 // Response - struct for sending payload from server and more info about occurred error
 // It behaves like Either Monad: 'Error' field is set if error occurred, otherwise 'Body' contains payload
+// 'Pagination' is set only if 'Body' contains a page of paginated list
 type Response struct {
-	Error RequestErrorCode `json:"error"`
-	Body  interface{}      `json:"body"`
+	Error      RequestErrorCode `json:"error"`
+	Body       interface{}      `json:"body"`
+	Pagination *Pagination      `json:"pagination,omitempty"`
+}
+
+// Pagination - represents position of the returned page in the paginated list
+// @Page - number of the returned page. Pages are numbered from 0
+// @PageSize - maximum amount of items on a page
+// @TotalCount - amount of items on all pages
+// @TotalPages - amount of pages
+// @Next - link to the next page. Empty if this page is the last one
+// @Prev - link to the previous page. Empty if this page is the first one
+type Pagination struct {
+	Page       int    `json:"page"`
+	PageSize   int    `json:"pageSize"`
+	TotalCount int    `json:"totalCount"`
+	TotalPages int    `json:"totalPages"`
+	Next       string `json:"next,omitempty"`
+	Prev       string `json:"prev,omitempty"`
 }
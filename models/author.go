@@ -0,0 +1,25 @@
+package models
+
+// Author - represents public profile of the user writing posts
+// @DisplayName - name shown in post bylines. Username is shown if it is not set
+type Author struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName"`
+	Bio         string `json:"bio"`
+	AvatarURL   string `json:"avatarURL"`
+}
+
+// Name - returns name of the author shown on the site
+func (author Author) Name() string {
+	if author.DisplayName != "" {
+		return author.DisplayName
+	}
+	return author.Username
+}
+
+// UpdateProfileRequest - represents user profile update request
+type UpdateProfileRequest struct {
+	DisplayName string `json:"displayName"`
+	Bio         string `json:"bio"`
+	AvatarURL   string `json:"avatarURL"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Comment - represents a comment to the blog post
+// @ID - ID created by database
+// @PostID - ID of the commented post
+// @ParentID - ID of the parent comment. Null if this comment is not a reply
+// @Author - name of the comment author
+// @Date - creation time
+// @Content - content
+// @Deleted - whether the comment was deleted. Deleted comments stay in database only if they have replies
+type Comment struct {
+	ID       string
+	PostID   string
+	ParentID NullString
+	Author   string
+	Date     time.Time
+	Content  string
+	Deleted  bool
+}
+
+// CommentWithChilds - represents a comment together with all its replies
+type CommentWithChilds struct {
+	Comment
+	Childs []*CommentWithChilds
+}
+
+// CertainPost - represents blog post together with the tree of its comments
+type CertainPost struct {
+	Post     Post
+	Comments []*CommentWithChilds
+}
+
+// CreateCommentRequest - represents comment creation request
+// ParentCommentID is either null or the string ID of the parent comment
+type CreateCommentRequest struct {
+	PostID          string      `json:"postID"`
+	ParentCommentID interface{} `json:"parentCommentID"`
+	Author          string      `json:"author"`
+	Content         string      `json:"content"`
+}
+
+// UpdateCommentRequest - represents comment update request
+type UpdateCommentRequest struct {
+	Content string `json:"content"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// MediaURLPrefix - path prefix the uploaded files are served under
+const MediaURLPrefix = "/media/"
+
+// Media - represents uploaded file, such as image
+// @Key - key the file is saved in the storage under. It is unique and is a part of the file URL
+// @URL - path the file is served under
+// @ThumbnailURL - path of the image thumbnail. It is the same as @URL if the file has no thumbnail
+// @Filename - name of the file on the uploader's computer
+// @Width, @Height - image size in pixels. Zero if the size is unknown
+// @UploadedBy - username of the user uploaded the file
+type Media struct {
+	ID           string    `json:"id"`
+	Key          string    `json:"key"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnailURL"`
+	Filename     string    `json:"filename"`
+	ContentType  string    `json:"contentType"`
+	Size         int64     `json:"size"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	UploadedBy   string    `json:"uploadedBy"`
+	Created      time.Time `json:"created"`
+}
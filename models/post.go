@@ -2,40 +2,72 @@ package models
 
 import "time"
 
+// PostStatus - represents post lifecycle status
+type PostStatus string
+
+// post statuses
+const (
+	// PostStatusDraft - post is visible only in admin dashboard
+	PostStatusDraft = PostStatus("draft")
+	// PostStatusPublished - post is visible on the site
+	PostStatusPublished = PostStatus("published")
+	// PostStatusScheduled - post will be published automatically at the publish time
+	PostStatusScheduled = PostStatus("scheduled")
+)
+
 // Post - represents blog post
 // @ID - ID created by database
 // @Title - title
 // @Date - creation time
+// @Updated - time of the last update
 // @Snippet - short description of this post
 // @Content - content
 // @Metadata - site metadata for this post. It replaces description and keywords in <head> tag
 // @Tags - tags
+// @Status - lifecycle status
+// @PublishAt - time the post is scheduled to be published at. Set only for scheduled posts
+// @Slug - unique human-readable post identifier used in post URL
+// @Authors - author of the post followed by co-authors. Posts created before authorship was introduced have no authors
 type Post struct {
-	ID       string
-	Title    string
-	Date     time.Time
-	Snippet  string
-	Content  string
-	Metadata MetaData
-	Tags     []string
+	ID        string
+	Title     string
+	Date      time.Time
+	Updated   time.Time
+	Snippet   string
+	Content   string
+	Metadata  MetaData
+	Tags      []string
+	Status    PostStatus
+	PublishAt *time.Time
+	Slug      string
+	Authors   []Author
 }
 
 //CreatePostRequest - represents post creation request
+// Post snippet and content are rendered on the server side from the markdown
+// Slug is optional. It is generated from the title if it is missed
+// Post author is the user creating the post. Co-authors are usernames of other users
 type CreatePostRequest struct {
-	Title     string   `json:"title"`
-	Snippet   string   `json:"snippet"`
-	Content   string   `json:"content"`
-	ContentMD string   `json:"contentMD"`
-	Metadata  MetaData `json:"metadata"`
-	Tags      []string `json:"tags"`
+	Title     string     `json:"title"`
+	ContentMD string     `json:"contentMD"`
+	Metadata  MetaData   `json:"metadata"`
+	Tags      []string   `json:"tags"`
+	Status    PostStatus `json:"status"`
+	PublishAt *time.Time `json:"publishAt"`
+	Slug      string     `json:"slug"`
+	CoAuthors []string   `json:"coAuthors"`
 }
 
 //UpdatePostRequest - represents post update request
+// Slug is optional. Current slug is kept if it is missed
+// Co-authors replace the current co-authors of the post. Post author can't be changed
 type UpdatePostRequest struct {
-	Title     string   `json:"title"`
-	Snippet   string   `json:"snippet"`
-	Content   string   `json:"content"`
-	ContentMD string   `json:"contentMD"`
-	Metadata  MetaData `json:"metadata"`
-	Tags      []string `json:"tags"`
+	Title     string     `json:"title"`
+	ContentMD string     `json:"contentMD"`
+	Metadata  MetaData   `json:"metadata"`
+	Tags      []string   `json:"tags"`
+	Status    PostStatus `json:"status"`
+	PublishAt *time.Time `json:"publishAt"`
+	Slug      string     `json:"slug"`
+	CoAuthors []string   `json:"coAuthors"`
 }
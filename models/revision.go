@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// PostRevision - represents a saved version of the blog post
+// @ID - ID created by database
+// @PostID - ID of the post this revision belongs to
+// @Date - time the revision was saved
+// @Title - title of the post at the moment of saving
+// @ContentMD - full content of the post as markdown
+// @Metadata - site metadata of the post at the moment of saving
+type PostRevision struct {
+	ID        string
+	PostID    string
+	Date      time.Time
+	Title     string
+	ContentMD string
+	Metadata  MetaData
+}
+
+// PostRevisionsDiff - represents unified diff between two revisions of the blog post
+// @From - ID of the old revision
+// @To - ID of the new revision
+// @Diff - unified diff of the revisions markdown
+type PostRevisionsDiff struct {
+	From string
+	To   string
+	Diff string
+}
@@ -0,0 +1,11 @@
+package models
+
+// PostSearchResult - represents blog post found by search query
+// @Post - found post
+// @Headline - fragments of the post content with highlighted query words. Words are wrapped into <mark> tag
+// @Rank - relevance of the post to the search query. Greater is more relevant
+type PostSearchResult struct {
+	Post     Post
+	Headline string
+	Rank     float64
+}
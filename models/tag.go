@@ -6,6 +6,12 @@ type Tag struct {
 	Name string `json:"name"`
 }
 
+// TagWithPostsCount - represents a tag together with amount of published posts with this tag
+type TagWithPostsCount struct {
+	Tag
+	PostsCount int `json:"postsCount"`
+}
+
 // CreateTagRequest- represents tag creation or update HTTP request
 type CreateTagRequest struct {
 	Name string `json:"name"`
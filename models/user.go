@@ -3,6 +3,18 @@ package models
 // UserRole - represents user role
 type UserRole string
 
+// user roles
+const (
+	// RoleAdmin - manages everything, including users
+	RoleAdmin = UserRole("admin")
+	// RoleEditor - publishes and edits any posts, manages tags and comments
+	RoleEditor = UserRole("editor")
+	// RoleAuthor - creates drafts and edits own drafts
+	RoleAuthor = UserRole("author")
+	// RoleUser - registered user without access to the admin dashboard
+	RoleUser = UserRole("user")
+)
+
 // User - represents user without password. Use it to work with user when you don't need secret information
 type User struct {
 	Username string
@@ -23,3 +35,8 @@ type RegistrationRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
+
+// UpdateUserRoleRequest - represents user role update request
+type UpdateUserRoleRequest struct {
+	Role UserRole `json:"role"`
+}
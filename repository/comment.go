@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/service/commentService"
+)
+
+// CommentRepository - storage of post comments
+type CommentRepository interface {
+	// GetTreeByPostID - retrieves all comments of the post as a tree
+	// comments on each level of the tree are sorted by creation time in ascending order
+	GetTreeByPostID(postID string) ([]*models.CommentWithChilds, error)
+}
+
+// PostgresCommentRepository - stores comments in postgres database
+type PostgresCommentRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresCommentRepository(db *sql.DB) *PostgresCommentRepository {
+	return &PostgresCommentRepository{db: db}
+}
+
+func (repo *PostgresCommentRepository) GetTreeByPostID(postID string) ([]*models.CommentWithChilds, error) {
+	return commentService.GetTreeByPostID(repo.db, postID)
+}
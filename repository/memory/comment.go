@@ -0,0 +1,19 @@
+package memory
+
+import (
+	"github.com/blinky-z/Blog/models"
+)
+
+// CommentRepository - comments of the posts stored in memory
+// Comments are created through the comments API only, so the posts stored in memory have no comments
+type CommentRepository struct {
+	store *Store
+}
+
+func NewCommentRepository(store *Store) *CommentRepository {
+	return &CommentRepository{store: store}
+}
+
+func (repo *CommentRepository) GetTreeByPostID(postID string) ([]*models.CommentWithChilds, error) {
+	return make([]*models.CommentWithChilds, 0), nil
+}
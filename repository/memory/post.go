@@ -0,0 +1,488 @@
+package memory
+
+import (
+	"database/sql"
+	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/service/postService"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// searchHeadlineWords - max amount of words in the headline of the found post
+const searchHeadlineWords = 35
+
+// htmlTagPattern - pattern of html tags removed from the found post before building the headline
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// PostRepository - stores posts in memory
+// Uploaded images in the post content are rendered without their size and responsive variants
+type PostRepository struct {
+	store *Store
+}
+
+func NewPostRepository(store *Store) *PostRepository {
+	return &PostRepository{store: store}
+}
+
+// isSlugTaken - checks if the slug is used by a post other than the given one, now or in the past
+func (repo *PostRepository) isSlugTaken(slug, postID string) bool {
+	for id, stored := range repo.store.posts {
+		if id != postID && stored.post.Slug == slug {
+			return true
+		}
+	}
+	oldSlugPostID, ok := repo.store.oldSlugs[slug]
+	return ok && oldSlugPostID != postID
+}
+
+// resolveSlug - returns slug the post should be saved with. See postService.Save for the rules
+func (repo *PostRepository) resolveSlug(requestedSlug, currentSlug, title, postID string) (string, error) {
+	if requestedSlug != "" {
+		if repo.isSlugTaken(requestedSlug, postID) {
+			return "", postService.ErrSlugAlreadyExists
+		}
+		return requestedSlug, nil
+	}
+	if currentSlug != "" {
+		return currentSlug, nil
+	}
+
+	baseSlug := postService.MakeSlug(title)
+	slug := baseSlug
+	for suffix := 2; repo.isSlugTaken(slug, postID); suffix++ {
+		slug = postService.SuffixedSlug(baseSlug, suffix)
+	}
+	return slug, nil
+}
+
+// withoutAuthor - returns co-authors without the post author, as the author can't be a co-author of the same post
+func withoutAuthor(coAuthors []string, author string) []string {
+	result := make([]string, 0, len(coAuthors))
+	for _, coAuthor := range coAuthors {
+		if coAuthor != author {
+			result = append(result, coAuthor)
+		}
+	}
+	return result
+}
+
+func (repo *PostRepository) Save(request *postService.SaveRequest) (*models.Post, error) {
+	snippet, content, err := postService.RenderContent(nil, request.ContentMD)
+	if err != nil {
+		return &models.Post{}, err
+	}
+
+	repo.store.mutex.Lock()
+	defer repo.store.mutex.Unlock()
+
+	slug, err := repo.resolveSlug(request.Slug, "", request.Title, "")
+	if err != nil {
+		return &models.Post{}, err
+	}
+
+	now := time.Now()
+	stored := &storedPost{
+		post: models.Post{
+			ID:        repo.store.nextID(),
+			Title:     request.Title,
+			Date:      now,
+			Updated:   now,
+			Snippet:   snippet,
+			Content:   content,
+			Metadata:  request.Metadata,
+			Status:    request.Status,
+			PublishAt: request.PublishAt,
+			Slug:      slug,
+		},
+		contentMD: request.ContentMD,
+		author:    request.Author,
+		coAuthors: withoutAuthor(request.CoAuthors, request.Author),
+		tagIDs:    repo.store.saveTags(request.Tags),
+	}
+	// scheduled post is dated by the time it will be published at
+	if request.Status == models.PostStatusScheduled && request.PublishAt != nil {
+		stored.post.Date = *request.PublishAt
+	}
+	repo.store.posts[stored.post.ID] = stored
+	repo.store.saveRevision(stored, now)
+
+	createdPost := repo.store.getPost(stored)
+	return &createdPost, nil
+}
+
+func (repo *PostRepository) Update(request *postService.UpdateRequest) (*models.Post, error) {
+	snippet, content, err := postService.RenderContent(nil, request.ContentMD)
+	if err != nil {
+		return &models.Post{}, err
+	}
+
+	repo.store.mutex.Lock()
+	defer repo.store.mutex.Unlock()
+
+	stored, ok := repo.store.posts[request.ID]
+	if !ok {
+		return &models.Post{}, sql.ErrNoRows
+	}
+	return repo.update(stored, request, snippet, content)
+}
+
+// update - updates the stored post with the rendered content. Store must be locked by the caller
+func (repo *PostRepository) update(stored *storedPost, request *postService.UpdateRequest,
+	snippet, content string) (*models.Post, error) {
+	currentSlug := stored.post.Slug
+	slug, err := repo.resolveSlug(request.Slug, currentSlug, request.Title, request.ID)
+	if err != nil {
+		return &models.Post{}, err
+	}
+
+	now := time.Now()
+	// post is re-dated when it gets published or scheduled
+	if request.Status == models.PostStatusScheduled && request.PublishAt != nil {
+		stored.post.Date = *request.PublishAt
+	} else if request.Status == models.PostStatusPublished && !isPublished(stored) {
+		stored.post.Date = now
+	}
+	stored.post.Title = request.Title
+	stored.post.Updated = now
+	stored.post.Snippet = snippet
+	stored.post.Content = content
+	stored.post.Metadata = request.Metadata
+	stored.post.Status = request.Status
+	stored.post.PublishAt = request.PublishAt
+	stored.post.Slug = slug
+	stored.contentMD = request.ContentMD
+	stored.coAuthors = withoutAuthor(request.CoAuthors, stored.author)
+	stored.tagIDs = repo.store.saveTags(request.Tags)
+
+	// old slug is remembered to redirect old URLs, and the post might get its old slug back
+	if currentSlug != "" && currentSlug != slug {
+		repo.store.oldSlugs[currentSlug] = stored.post.ID
+	}
+	delete(repo.store.oldSlugs, slug)
+	repo.store.saveRevision(stored, now)
+	repo.store.contentChanged = now
+
+	updatedPost := repo.store.getPost(stored)
+	return &updatedPost, nil
+}
+
+func (repo *PostRepository) DeleteByID(postID string) error {
+	repo.store.mutex.Lock()
+	defer repo.store.mutex.Unlock()
+
+	delete(repo.store.posts, postID)
+	for oldSlug, oldSlugPostID := range repo.store.oldSlugs {
+		if oldSlugPostID == postID {
+			delete(repo.store.oldSlugs, oldSlug)
+		}
+	}
+	revisions := repo.store.revisions[:0]
+	for _, revision := range repo.store.revisions {
+		if revision.PostID != postID {
+			revisions = append(revisions, revision)
+		}
+	}
+	repo.store.revisions = revisions
+	repo.store.contentChanged = time.Now()
+	return nil
+}
+
+// getPublished - returns the first published post matching the filter
+func (repo *PostRepository) getPublished(filter func(stored *storedPost) bool) (models.Post, error) {
+	repo.store.mutex.RLock()
+	defer repo.store.mutex.RUnlock()
+
+	for _, stored := range repo.store.posts {
+		if isPublished(stored) && filter(stored) {
+			return repo.store.getPost(stored), nil
+		}
+	}
+	return models.Post{}, sql.ErrNoRows
+}
+
+func (repo *PostRepository) GetByID(postID string) (models.Post, error) {
+	return repo.getPublished(func(stored *storedPost) bool {
+		return stored.post.ID == postID
+	})
+}
+
+func (repo *PostRepository) GetBySlug(slug string) (models.Post, error) {
+	return repo.getPublished(func(stored *storedPost) bool {
+		return stored.post.Slug == slug
+	})
+}
+
+func (repo *PostRepository) GetByIDWithMarkdownContent(postID string) (models.Post, error) {
+	repo.store.mutex.RLock()
+	defer repo.store.mutex.RUnlock()
+
+	stored, ok := repo.store.posts[postID]
+	if !ok {
+		return models.Post{}, sql.ErrNoRows
+	}
+	post := repo.store.getPost(stored)
+	post.Content = stored.contentMD
+	return post, nil
+}
+
+func (repo *PostRepository) GetSlugByID(postID string) (string, error) {
+	post, err := repo.GetByID(postID)
+	return post.Slug, err
+}
+
+func (repo *PostRepository) GetSlugByOldSlug(oldSlug string) (string, error) {
+	repo.store.mutex.RLock()
+	postID, ok := repo.store.oldSlugs[oldSlug]
+	repo.store.mutex.RUnlock()
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return repo.GetSlugByID(postID)
+}
+
+func (repo *PostRepository) GetCoAuthorsByPostID(postID string) ([]string, error) {
+	repo.store.mutex.RLock()
+	defer repo.store.mutex.RUnlock()
+
+	stored, ok := repo.store.posts[postID]
+	if !ok {
+		return nil, nil
+	}
+	return append([]string(nil), stored.coAuthors...), nil
+}
+
+// getInRange - returns posts in the given range matching the filter
+func (repo *PostRepository) getInRange(offset, postsPerPage int,
+	filter func(stored *storedPost) bool) ([]models.Post, error) {
+	repo.store.mutex.RLock()
+	defer repo.store.mutex.RUnlock()
+
+	return repo.store.postsInRange(repo.store.filterPosts(filter), offset, postsPerPage), nil
+}
+
+// count - returns amount of posts matching the filter
+func (repo *PostRepository) count(filter func(stored *storedPost) bool) (int, error) {
+	repo.store.mutex.RLock()
+	defer repo.store.mutex.RUnlock()
+
+	return len(repo.store.filterPosts(filter)), nil
+}
+
+func (repo *PostRepository) GetPostsInRange(offset, postsPerPage int) ([]models.Post, error) {
+	return repo.getInRange(offset, postsPerPage, isPublished)
+}
+
+func (repo *PostRepository) GetPostsInRangeByTag(offset, postsPerPage int, tag string) ([]models.Post, error) {
+	return repo.getInRange(offset, postsPerPage, func(stored *storedPost) bool {
+		return isPublished(stored) && repo.store.hasTag(stored, tag)
+	})
+}
+
+func (repo *PostRepository) GetPostsInRangeByAuthor(offset, postsPerPage int,
+	username string) ([]models.Post, error) {
+	return repo.getInRange(offset, postsPerPage, func(stored *storedPost) bool {
+		return isPublished(stored) && isWrittenBy(stored, username)
+	})
+}
+
+func (repo *PostRepository) GetPostsInRangeWithAnyStatus(offset, postsPerPage int) ([]models.Post, error) {
+	return repo.getInRange(offset, postsPerPage, func(stored *storedPost) bool {
+		return true
+	})
+}
+
+func (repo *PostRepository) GetPostsInRangeWithAnyStatusByAuthor(offset, postsPerPage int,
+	username string) ([]models.Post, error) {
+	return repo.getInRange(offset, postsPerPage, func(stored *storedPost) bool {
+		return isWrittenBy(stored, username)
+	})
+}
+
+func (repo *PostRepository) CountPublished() (int, error) {
+	return repo.count(isPublished)
+}
+
+func (repo *PostRepository) CountPublishedByTag(tag string) (int, error) {
+	return repo.count(func(stored *storedPost) bool {
+		return isPublished(stored) && repo.store.hasTag(stored, tag)
+	})
+}
+
+func (repo *PostRepository) CountPublishedByAuthor(username string) (int, error) {
+	return repo.count(func(stored *storedPost) bool {
+		return isPublished(stored) && isWrittenBy(stored, username)
+	})
+}
+
+// Search - retrieves published posts containing all words of the query
+// Unlike the postgres search, words are matched as is, without stemming and web search syntax
+// Posts are ranked by amount of found words. Words found in the title are ranked higher
+func (repo *PostRepository) Search(query string, offset, limit int) ([]models.PostSearchResult, error) {
+	words := strings.Fields(strings.ToLower(query))
+	results := make([]models.PostSearchResult, 0)
+	if len(words) == 0 {
+		return results, nil
+	}
+
+	repo.store.mutex.RLock()
+	defer repo.store.mutex.RUnlock()
+
+	for _, stored := range repo.store.filterPosts(isPublished) {
+		title := strings.ToLower(stored.post.Title)
+		text := htmlTagPattern.ReplaceAllString(stored.post.Snippet+" "+stored.post.Content, " ")
+
+		var rank float64
+		for _, word := range words {
+			titleCount := strings.Count(title, word)
+			textCount := strings.Count(strings.ToLower(html.UnescapeString(text)), word)
+			if titleCount+textCount == 0 {
+				rank = 0
+				break
+			}
+			rank += float64(2*titleCount + textCount)
+		}
+		if rank == 0 {
+			continue
+		}
+
+		results = append(results, models.PostSearchResult{
+			Post:     repo.store.getPost(stored),
+			Headline: postService.SanitizeHeadline(makeHeadline(text, words)),
+			Rank:     rank,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Rank > results[j].Rank
+	})
+	if offset > len(results) {
+		offset = len(results)
+	}
+	if offset+limit < len(results) {
+		return results[offset : offset+limit], nil
+	}
+	return results[offset:], nil
+}
+
+// makeHeadline - returns the first words of the text with the query words wrapped into <mark> tag
+// Text should have html entities escaped
+func makeHeadline(text string, queryWords []string) string {
+	words := strings.Fields(text)
+	if len(words) > searchHeadlineWords {
+		words = words[:searchHeadlineWords]
+	}
+	for index, word := range words {
+		for _, queryWord := range queryWords {
+			if strings.Contains(strings.ToLower(html.UnescapeString(word)), queryWord) {
+				words[index] = "<mark>" + word + "</mark>"
+				break
+			}
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func (repo *PostRepository) RenderAll() (int, error) {
+	repo.store.mutex.Lock()
+	defer repo.store.mutex.Unlock()
+
+	renderedCount := 0
+	for _, stored := range repo.store.posts {
+		snippet, content, err := postService.RenderContent(nil, stored.contentMD)
+		if err == postService.ErrNoCutMarker {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if stored.post.Snippet != snippet || stored.post.Content != content {
+			stored.post.Snippet = snippet
+			stored.post.Content = content
+			stored.post.Updated = time.Now()
+		}
+		renderedCount++
+	}
+	return renderedCount, nil
+}
+
+func (repo *PostRepository) GetPostsLastModified() ([]postService.PageLastModified, error) {
+	repo.store.mutex.RLock()
+	defer repo.store.mutex.RUnlock()
+
+	pages := make([]postService.PageLastModified, 0)
+	for _, stored := range repo.store.filterPosts(isPublished) {
+		pages = append(pages, postService.PageLastModified{Name: stored.post.Slug, LastModified: lastModified(stored)})
+	}
+	return pages, nil
+}
+
+func (repo *PostRepository) GetContentChanged() (time.Time, error) {
+	repo.store.mutex.RLock()
+	defer repo.store.mutex.RUnlock()
+	return repo.store.contentChanged, nil
+}
+
+func (repo *PostRepository) GetRevisionsByPostID(postID string) ([]models.PostRevision, error) {
+	repo.store.mutex.RLock()
+	defer repo.store.mutex.RUnlock()
+
+	// revisions are stored in order of their creation, the newest goes first in the result
+	revisions := make([]models.PostRevision, 0)
+	for index := len(repo.store.revisions) - 1; index >= 0; index-- {
+		if repo.store.revisions[index].PostID == postID {
+			revisions = append(revisions, repo.store.revisions[index])
+		}
+	}
+	return revisions, nil
+}
+
+// getRevision - returns revision of the post. Store must be locked by the caller
+func (repo *PostRepository) getRevision(postID, revisionID string) (models.PostRevision, error) {
+	for _, revision := range repo.store.revisions {
+		if revision.ID == revisionID && revision.PostID == postID {
+			return revision, nil
+		}
+	}
+	return models.PostRevision{}, sql.ErrNoRows
+}
+
+func (repo *PostRepository) GetRevisionByID(postID, revisionID string) (models.PostRevision, error) {
+	repo.store.mutex.RLock()
+	defer repo.store.mutex.RUnlock()
+	return repo.getRevision(postID, revisionID)
+}
+
+func (repo *PostRepository) RestoreRevision(postID, revisionID string) (*models.Post, error) {
+	repo.store.mutex.Lock()
+	defer repo.store.mutex.Unlock()
+
+	revision, err := repo.getRevision(postID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	stored, ok := repo.store.posts[postID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	snippet, content, err := postService.RenderContent(nil, revision.ContentMD)
+	if err != nil {
+		return nil, err
+	}
+
+	// slug, tags, co-authors and status are left unchanged
+	return repo.update(stored, &postService.UpdateRequest{
+		ID:        postID,
+		Title:     revision.Title,
+		ContentMD: revision.ContentMD,
+		Metadata:  revision.Metadata,
+		Status:    stored.post.Status,
+		PublishAt: stored.post.PublishAt,
+		Slug:      stored.post.Slug,
+		Tags:      repo.store.postTags(stored),
+		CoAuthors: stored.coAuthors,
+	}, snippet, content)
+}
@@ -0,0 +1,193 @@
+package memory
+
+import (
+	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/repository"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Store - in-memory storage shared by the repositories, so that posts refer to the stored tags and users
+// It is intended for unit tests: data is kept only while the process is running
+type Store struct {
+	mutex sync.RWMutex
+	posts map[string]*storedPost
+	// oldSlugs - post IDs by the slugs posts had before
+	oldSlugs map[string]string
+	tags     map[string]models.Tag
+	users    map[string]storedUser
+	// revisions - saved versions of all posts in order of their creation
+	revisions []models.PostRevision
+	lastID    int
+	// contentChanged - time of the last post update or deletion
+	contentChanged time.Time
+}
+
+// check that the repositories implement the interfaces
+var (
+	_ repository.PostRepository    = (*PostRepository)(nil)
+	_ repository.TagRepository     = (*TagRepository)(nil)
+	_ repository.UserRepository    = (*UserRepository)(nil)
+	_ repository.CommentRepository = (*CommentRepository)(nil)
+)
+
+// storedPost - post together with the fields that are not a part of models.Post
+// Tags are stored by ID, so that renamed tags are renamed in the posts too
+type storedPost struct {
+	post      models.Post
+	contentMD string
+	author    string
+	coAuthors []string
+	tagIDs    []string
+}
+
+// storedUser - registered user
+type storedUser struct {
+	author   models.Author
+	email    string
+	password string
+	role     models.UserRole
+}
+
+func NewStore() *Store {
+	return &Store{
+		posts:    make(map[string]*storedPost),
+		oldSlugs: make(map[string]string),
+		tags:     make(map[string]models.Tag),
+		users:    make(map[string]storedUser),
+		// changes made before the store creation are unknown
+		contentChanged: time.Now(),
+	}
+}
+
+// nextID - returns a new unique ID. IDs are numeric like the database ones
+func (store *Store) nextID() string {
+	store.lastID++
+	return strconv.Itoa(store.lastID)
+}
+
+// saveRevision - saves the current version of the post as a new revision
+func (store *Store) saveRevision(stored *storedPost, date time.Time) {
+	store.revisions = append(store.revisions, models.PostRevision{
+		ID:        store.nextID(),
+		PostID:    stored.post.ID,
+		Date:      date,
+		Title:     stored.post.Title,
+		ContentMD: stored.contentMD,
+		Metadata:  stored.post.Metadata,
+	})
+}
+
+// getTagID - returns ID of the tag with the given name
+// returns boolean indicating whether the tag exists
+func (store *Store) getTagID(name string) (string, bool) {
+	for id, tag := range store.tags {
+		if tag.Name == name {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// saveTags - saves new tags and returns IDs of all the given tags
+func (store *Store) saveTags(names []string) []string {
+	ids := make([]string, 0, len(names))
+	for _, name := range names {
+		id, exists := store.getTagID(name)
+		if !exists {
+			id = store.nextID()
+			store.tags[id] = models.Tag{ID: id, Name: name}
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// postTags - returns tag names of the post
+func (store *Store) postTags(stored *storedPost) []string {
+	var names []string
+	for _, id := range stored.tagIDs {
+		names = append(names, store.tags[id].Name)
+	}
+	return names
+}
+
+// postAuthors - returns authors of the post. Post author goes first, then co-authors
+func (store *Store) postAuthors(stored *storedPost) []models.Author {
+	var authors []models.Author
+	for _, username := range append([]string{stored.author}, stored.coAuthors...) {
+		if user, ok := store.users[username]; ok {
+			authors = append(authors, user.author)
+		}
+	}
+	return authors
+}
+
+// getPost - returns copy of the stored post with tags and authors filled
+func (store *Store) getPost(stored *storedPost) models.Post {
+	post := stored.post
+	post.Tags = store.postTags(stored)
+	post.Authors = store.postAuthors(stored)
+	return post
+}
+
+// hasTag - checks whether the post has the tag with the given name
+func (store *Store) hasTag(stored *storedPost, name string) bool {
+	for _, tag := range store.postTags(stored) {
+		if tag == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isWrittenBy - checks whether the post is written or co-authored by the user
+func isWrittenBy(stored *storedPost, username string) bool {
+	if stored.author == username {
+		return true
+	}
+	for _, coAuthor := range stored.coAuthors {
+		if coAuthor == username {
+			return true
+		}
+	}
+	return false
+}
+
+// isPublished - checks whether the post is visible on the public site
+func isPublished(stored *storedPost) bool {
+	return stored.post.Status == models.PostStatusPublished
+}
+
+// lastModified - returns time of the last change of the post
+func lastModified(stored *storedPost) time.Time {
+	if stored.post.Updated.After(stored.post.Date) {
+		return stored.post.Updated
+	}
+	return stored.post.Date
+}
+
+// filterPosts - returns stored posts matching the filter sorted by date in descending order
+func (store *Store) filterPosts(filter func(stored *storedPost) bool) []*storedPost {
+	posts := make([]*storedPost, 0)
+	for _, stored := range store.posts {
+		if filter(stored) {
+			posts = append(posts, stored)
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].post.Date.After(posts[j].post.Date)
+	})
+	return posts
+}
+
+// postsInRange - returns posts in the given range of the stored posts with tags and authors filled
+func (store *Store) postsInRange(posts []*storedPost, offset, limit int) []models.Post {
+	result := make([]models.Post, 0)
+	for index := offset; index < len(posts) && index < offset+limit; index++ {
+		result = append(result, store.getPost(posts[index]))
+	}
+	return result
+}
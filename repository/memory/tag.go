@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"database/sql"
+	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/service/postService"
+	"github.com/blinky-z/Blog/service/tagService"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TagRepository - stores tags in memory
+type TagRepository struct {
+	store *Store
+}
+
+func NewTagRepository(store *Store) *TagRepository {
+	return &TagRepository{store: store}
+}
+
+func (repo *TagRepository) GetAll() ([]models.Tag, error) {
+	repo.store.mutex.RLock()
+	defer repo.store.mutex.RUnlock()
+
+	var tags []models.Tag
+	for _, tag := range repo.store.tags {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		leftID, _ := strconv.Atoi(tags[i].ID)
+		rightID, _ := strconv.Atoi(tags[j].ID)
+		return leftID > rightID
+	})
+	return tags, nil
+}
+
+func (repo *TagRepository) GetAllWithPublishedPostsCount() ([]models.TagWithPostsCount, error) {
+	repo.store.mutex.RLock()
+	defer repo.store.mutex.RUnlock()
+
+	tags := make([]models.TagWithPostsCount, 0)
+	for _, tag := range repo.store.tags {
+		postsCount := len(repo.store.filterPosts(func(stored *storedPost) bool {
+			return isPublished(stored) && repo.store.hasTag(stored, tag.Name)
+		}))
+		tags = append(tags, models.TagWithPostsCount{Tag: tag, PostsCount: postsCount})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
+
+func (repo *TagRepository) GetAllLastModified() ([]postService.PageLastModified, error) {
+	repo.store.mutex.RLock()
+	defer repo.store.mutex.RUnlock()
+
+	pages := make([]postService.PageLastModified, 0)
+	for _, tag := range repo.store.tags {
+		var tagLastModified time.Time
+		for _, stored := range repo.store.filterPosts(isPublished) {
+			if repo.store.hasTag(stored, tag.Name) && lastModified(stored).After(tagLastModified) {
+				tagLastModified = lastModified(stored)
+			}
+		}
+		if !tagLastModified.IsZero() {
+			pages = append(pages, postService.PageLastModified{Name: tag.Name, LastModified: tagLastModified})
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].Name < pages[j].Name
+	})
+	return pages, nil
+}
+
+func (repo *TagRepository) Save(tag string) (models.Tag, error) {
+	repo.store.mutex.Lock()
+	defer repo.store.mutex.Unlock()
+
+	if _, exists := repo.store.getTagID(tag); exists {
+		return models.Tag{}, tagService.ErrTagAlreadyExists
+	}
+	savedTag := models.Tag{ID: repo.store.nextID(), Name: tag}
+	repo.store.tags[savedTag.ID] = savedTag
+	return savedTag, nil
+}
+
+func (repo *TagRepository) Update(tagID, tag string) (models.Tag, error) {
+	repo.store.mutex.Lock()
+	defer repo.store.mutex.Unlock()
+
+	if _, ok := repo.store.tags[tagID]; !ok {
+		return models.Tag{}, sql.ErrNoRows
+	}
+	if existingID, exists := repo.store.getTagID(tag); exists && existingID != tagID {
+		return models.Tag{}, tagService.ErrTagAlreadyExists
+	}
+	updatedTag := models.Tag{ID: tagID, Name: tag}
+	repo.store.tags[tagID] = updatedTag
+	return updatedTag, nil
+}
+
+func (repo *TagRepository) DeleteByID(tagID string) error {
+	repo.store.mutex.Lock()
+	defer repo.store.mutex.Unlock()
+
+	delete(repo.store.tags, tagID)
+	for _, stored := range repo.store.posts {
+		tagIDs := make([]string, 0, len(stored.tagIDs))
+		for _, id := range stored.tagIDs {
+			if id != tagID {
+				tagIDs = append(tagIDs, id)
+			}
+		}
+		stored.tagIDs = tagIDs
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"database/sql"
+	"github.com/blinky-z/Blog/models"
+)
+
+// UserRepository - stores users in memory
+type UserRepository struct {
+	store *Store
+}
+
+func NewUserRepository(store *Store) *UserRepository {
+	return &UserRepository{store: store}
+}
+
+func (repo *UserRepository) Save(username, email, password string, role models.UserRole) error {
+	repo.store.mutex.Lock()
+	defer repo.store.mutex.Unlock()
+
+	repo.store.users[username] = storedUser{
+		author:   models.Author{Username: username},
+		email:    email,
+		password: password,
+		role:     role,
+	}
+	return nil
+}
+
+func (repo *UserRepository) GetAuthorByUsername(username string) (models.Author, error) {
+	repo.store.mutex.RLock()
+	defer repo.store.mutex.RUnlock()
+
+	user, ok := repo.store.users[username]
+	if !ok {
+		return models.Author{}, sql.ErrNoRows
+	}
+	return user.author, nil
+}
+
+func (repo *UserRepository) GetNotExistingUsernames(usernames []string) ([]string, error) {
+	repo.store.mutex.RLock()
+	defer repo.store.mutex.RUnlock()
+
+	notExisting := make([]string, 0)
+	for _, username := range usernames {
+		if _, ok := repo.store.users[username]; !ok {
+			notExisting = append(notExisting, username)
+		}
+	}
+	return notExisting, nil
+}
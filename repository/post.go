@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/service/postService"
+	"time"
+)
+
+// PostRepository - storage of blog posts
+// Methods retrieving a single post return sql.ErrNoRows error if the post does not exist
+type PostRepository interface {
+	// Save - saves a new post. Returns postService.ErrSlugAlreadyExists error if requested slug is taken
+	Save(request *postService.SaveRequest) (*models.Post, error)
+	// Update - updates post. Returns postService.ErrSlugAlreadyExists error if requested slug is taken
+	Update(request *postService.UpdateRequest) (*models.Post, error)
+	// DeleteByID - deletes post together with its tags, revisions and comments
+	DeleteByID(postID string) error
+	// GetByID - retrieves published post with the given ID
+	GetByID(postID string) (models.Post, error)
+	// GetBySlug - retrieves published post with the given slug
+	GetBySlug(slug string) (models.Post, error)
+	// GetByIDWithMarkdownContent - retrieves post with the given ID in any status, the content as markdown
+	GetByIDWithMarkdownContent(postID string) (models.Post, error)
+	// GetSlugByID - retrieves current slug of the published post with the given ID
+	GetSlugByID(postID string) (string, error)
+	// GetSlugByOldSlug - retrieves current slug of the published post which had the given slug before
+	GetSlugByOldSlug(oldSlug string) (string, error)
+	// GetCoAuthorsByPostID - returns usernames of the post co-authors in their order
+	GetCoAuthorsByPostID(postID string) ([]string, error)
+	// GetPostsInRange - retrieves published posts in the given range sorted by date in descending order
+	GetPostsInRange(offset, postsPerPage int) ([]models.Post, error)
+	// GetPostsInRangeByTag - retrieves published posts with the given tag in the given range
+	GetPostsInRangeByTag(offset, postsPerPage int, tag string) ([]models.Post, error)
+	// GetPostsInRangeByAuthor - retrieves published posts written or co-authored by the user in the given range
+	GetPostsInRangeByAuthor(offset, postsPerPage int, username string) ([]models.Post, error)
+	// GetPostsInRangeWithAnyStatus - retrieves posts in the given range regardless of their status
+	GetPostsInRangeWithAnyStatus(offset, postsPerPage int) ([]models.Post, error)
+	// GetPostsInRangeWithAnyStatusByAuthor - retrieves posts written or co-authored by the user in the given range
+	// regardless of their status
+	GetPostsInRangeWithAnyStatusByAuthor(offset, postsPerPage int, username string) ([]models.Post, error)
+	// CountPublished - returns amount of all published posts
+	CountPublished() (int, error)
+	// CountPublishedByTag - returns amount of published posts with the given tag
+	CountPublishedByTag(tag string) (int, error)
+	// CountPublishedByAuthor - returns amount of published posts written or co-authored by the user
+	CountPublishedByAuthor(username string) (int, error)
+	// Search - retrieves published posts matching the search query sorted by relevance
+	Search(query string, offset, limit int) ([]models.PostSearchResult, error)
+	// RenderAll - re-renders html of all posts from their markdown. Returns amount of re-rendered posts
+	RenderAll() (int, error)
+	// GetPostsLastModified - retrieves slugs of all published posts together with time of their last change
+	GetPostsLastModified() ([]postService.PageLastModified, error)
+	// GetContentChanged - returns time of the last post update or deletion
+	// Deleted and unpublished posts leave no change time, so pages listing posts are not older than this time
+	GetContentChanged() (time.Time, error)
+	// GetRevisionsByPostID - retrieves all revisions of the post sorted by creation time in descending order
+	GetRevisionsByPostID(postID string) ([]models.PostRevision, error)
+	// GetRevisionByID - retrieves revision of the post. Returns sql.ErrNoRows error if the revision does not exist
+	// or belongs to another post
+	GetRevisionByID(postID, revisionID string) (models.PostRevision, error)
+	// RestoreRevision - sets title, content and metadata of the revision as the current post version
+	// Returns sql.ErrNoRows error if the revision does not exist or belongs to another post
+	RestoreRevision(postID, revisionID string) (*models.Post, error)
+}
+
+// PostgresPostRepository - stores posts in postgres database
+type PostgresPostRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresPostRepository(db *sql.DB) *PostgresPostRepository {
+	return &PostgresPostRepository{db: db}
+}
+
+func (repo *PostgresPostRepository) Save(request *postService.SaveRequest) (*models.Post, error) {
+	return postService.Save(repo.db, request)
+}
+
+func (repo *PostgresPostRepository) Update(request *postService.UpdateRequest) (*models.Post, error) {
+	return postService.Update(repo.db, request)
+}
+
+func (repo *PostgresPostRepository) DeleteByID(postID string) error {
+	return postService.DeleteByID(repo.db, postID)
+}
+
+func (repo *PostgresPostRepository) GetByID(postID string) (models.Post, error) {
+	return postService.GetByID(repo.db, postID)
+}
+
+func (repo *PostgresPostRepository) GetBySlug(slug string) (models.Post, error) {
+	return postService.GetBySlug(repo.db, slug)
+}
+
+func (repo *PostgresPostRepository) GetByIDWithMarkdownContent(postID string) (models.Post, error) {
+	return postService.GetByIDWithMarkdownContent(repo.db, postID)
+}
+
+func (repo *PostgresPostRepository) GetSlugByID(postID string) (string, error) {
+	return postService.GetSlugByID(repo.db, postID)
+}
+
+func (repo *PostgresPostRepository) GetSlugByOldSlug(oldSlug string) (string, error) {
+	return postService.GetSlugByOldSlug(repo.db, oldSlug)
+}
+
+func (repo *PostgresPostRepository) GetCoAuthorsByPostID(postID string) ([]string, error) {
+	return postService.GetCoAuthorsByPostID(repo.db, postID)
+}
+
+func (repo *PostgresPostRepository) GetPostsInRange(offset, postsPerPage int) ([]models.Post, error) {
+	return postService.GetPostsInRange(repo.db, offset, postsPerPage)
+}
+
+func (repo *PostgresPostRepository) GetPostsInRangeByTag(offset, postsPerPage int, tag string) ([]models.Post, error) {
+	return postService.GetPostsInRangeByTag(repo.db, offset, postsPerPage, tag)
+}
+
+func (repo *PostgresPostRepository) GetPostsInRangeByAuthor(offset, postsPerPage int,
+	username string) ([]models.Post, error) {
+	return postService.GetPostsInRangeByAuthor(repo.db, offset, postsPerPage, username)
+}
+
+func (repo *PostgresPostRepository) GetPostsInRangeWithAnyStatus(offset, postsPerPage int) ([]models.Post, error) {
+	return postService.GetPostsInRangeWithAnyStatus(repo.db, offset, postsPerPage)
+}
+
+func (repo *PostgresPostRepository) GetPostsInRangeWithAnyStatusByAuthor(offset, postsPerPage int,
+	username string) ([]models.Post, error) {
+	return postService.GetPostsInRangeWithAnyStatusByAuthor(repo.db, offset, postsPerPage, username)
+}
+
+func (repo *PostgresPostRepository) CountPublished() (int, error) {
+	return postService.CountPublished(repo.db)
+}
+
+func (repo *PostgresPostRepository) CountPublishedByTag(tag string) (int, error) {
+	return postService.CountPublishedByTag(repo.db, tag)
+}
+
+func (repo *PostgresPostRepository) CountPublishedByAuthor(username string) (int, error) {
+	return postService.CountPublishedByAuthor(repo.db, username)
+}
+
+func (repo *PostgresPostRepository) Search(query string, offset, limit int) ([]models.PostSearchResult, error) {
+	return postService.Search(repo.db, query, offset, limit)
+}
+
+func (repo *PostgresPostRepository) RenderAll() (int, error) {
+	return postService.RenderAll(repo.db)
+}
+
+func (repo *PostgresPostRepository) GetPostsLastModified() ([]postService.PageLastModified, error) {
+	return postService.GetPostsLastModified(repo.db)
+}
+
+func (repo *PostgresPostRepository) GetContentChanged() (time.Time, error) {
+	return postService.GetContentChanged(repo.db)
+}
+
+func (repo *PostgresPostRepository) GetRevisionsByPostID(postID string) ([]models.PostRevision, error) {
+	return postService.GetRevisionsByPostID(repo.db, postID)
+}
+
+func (repo *PostgresPostRepository) GetRevisionByID(postID, revisionID string) (models.PostRevision, error) {
+	return postService.GetRevisionByID(repo.db, postID, revisionID)
+}
+
+func (repo *PostgresPostRepository) RestoreRevision(postID, revisionID string) (*models.Post, error) {
+	return postService.RestoreRevision(repo.db, postID, revisionID)
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/service/postService"
+	"github.com/blinky-z/Blog/service/tagService"
+)
+
+// TagRepository - storage of post tags
+type TagRepository interface {
+	// GetAll - returns all tags sorted by ID in descending order
+	GetAll() ([]models.Tag, error)
+	// GetAllWithPublishedPostsCount - returns all tags together with amount of their published posts sorted by name
+	GetAllWithPublishedPostsCount() ([]models.TagWithPostsCount, error)
+	// GetAllLastModified - returns tags having published posts together with time of the last change of their posts
+	GetAllLastModified() ([]postService.PageLastModified, error)
+	// Save - saves a new tag. Returns tagService.ErrTagAlreadyExists error if the tag exists
+	Save(tag string) (models.Tag, error)
+	// Update - renames the tag. Returns tagService.ErrTagAlreadyExists error if another tag has the same name
+	// and sql.ErrNoRows error if the tag does not exist
+	Update(tagID, tag string) (models.Tag, error)
+	// DeleteByID - deletes the tag and removes it from all posts
+	DeleteByID(tagID string) error
+}
+
+// PostgresTagRepository - stores tags in postgres database
+type PostgresTagRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresTagRepository(db *sql.DB) *PostgresTagRepository {
+	return &PostgresTagRepository{db: db}
+}
+
+func (repo *PostgresTagRepository) GetAll() ([]models.Tag, error) {
+	return tagService.GetAll(repo.db)
+}
+
+func (repo *PostgresTagRepository) GetAllWithPublishedPostsCount() ([]models.TagWithPostsCount, error) {
+	return postService.GetTagsWithPublishedPostsCount(repo.db)
+}
+
+func (repo *PostgresTagRepository) GetAllLastModified() ([]postService.PageLastModified, error) {
+	return postService.GetTagsLastModified(repo.db)
+}
+
+func (repo *PostgresTagRepository) Save(tag string) (models.Tag, error) {
+	return tagService.Save(repo.db, tag)
+}
+
+func (repo *PostgresTagRepository) Update(tagID, tag string) (models.Tag, error) {
+	return tagService.Update(repo.db, tagID, tag)
+}
+
+func (repo *PostgresTagRepository) DeleteByID(tagID string) error {
+	return tagService.DeleteByID(repo.db, tagID)
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"database/sql"
+	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/service/userService"
+)
+
+// UserRepository - storage of registered users
+type UserRepository interface {
+	// Save - saves a new user. Password should be already hashed
+	Save(username, email, password string, role models.UserRole) error
+	// GetAuthorByUsername - returns public profile of the user. Returns sql.ErrNoRows error if the user does not exist
+	GetAuthorByUsername(username string) (models.Author, error)
+	// GetNotExistingUsernames - returns usernames from the given ones that are not registered
+	GetNotExistingUsernames(usernames []string) ([]string, error)
+}
+
+// PostgresUserRepository - stores users in postgres database
+type PostgresUserRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresUserRepository(db *sql.DB) *PostgresUserRepository {
+	return &PostgresUserRepository{db: db}
+}
+
+func (repo *PostgresUserRepository) Save(username, email, password string, role models.UserRole) error {
+	return userService.Save(repo.db, username, email, password, role)
+}
+
+func (repo *PostgresUserRepository) GetAuthorByUsername(username string) (models.Author, error) {
+	return userService.GetAuthorByUsername(repo.db, username)
+}
+
+func (repo *PostgresUserRepository) GetNotExistingUsernames(usernames []string) ([]string, error) {
+	return userService.GetNotExistingUsernames(repo.db, usernames)
+}
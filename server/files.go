@@ -0,0 +1,57 @@
+package server
+
+import (
+	"embed"
+	"github.com/blinky-z/Blog/themes"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// embeddedFiles - default files served in the site root, compiled into the server binary
+//
+//go:embed robots_admin.txt
+var embeddedFiles embed.FS
+
+// overrideThemesDir - directory of the themes in the override directory
+const overrideThemesDir = "themes"
+
+// newFiles - returns file system of the files served in the site root, e.g. robots.txt, and file system of the themes
+// Files of the override directory replace the embedded ones. If the override directory is not set,
+// only the embedded files are used
+func newFiles(overrideDir string) (fs.FS, fs.FS) {
+	if overrideDir == "" {
+		return embeddedFiles, themes.Embedded()
+	}
+	return themes.Overlay(os.DirFS(overrideDir), embeddedFiles),
+		themes.Overlay(os.DirFS(filepath.Join(overrideDir, overrideThemesDir)), themes.Embedded())
+}
+
+// overrideTemplateDirs - returns directories of the override directory holding the theme templates
+func overrideTemplateDirs(overrideDir string, siteTheme *themes.Theme) []string {
+	if overrideDir == "" {
+		return nil
+	}
+	var dirs []string
+	for _, dir := range siteTheme.TemplateDirs() {
+		diskDir := filepath.Join(overrideDir, overrideThemesDir, filepath.FromSlash(dir))
+		if info, err := os.Stat(diskDir); err == nil && info.IsDir() {
+			dirs = append(dirs, diskDir)
+		}
+	}
+	return dirs
+}
+
+// hasFile - checks whether the file system has the file with the given name
+func hasFile(files fs.FS, name string) bool {
+	info, err := fs.Stat(files, name)
+	return err == nil && !info.IsDir()
+}
+
+// serveFile - returns handler serving the file with the given name
+func serveFile(files fs.FS, name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFileFS(w, r, files, name)
+	})
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"fmt"
+	"github.com/blinky-z/Blog/migrations"
+	"os"
+	"strconv"
+)
+
+// migrateUsage - usage of the migrate command
+const migrateUsage = `usage: serverRun migrate <command>
+
+commands:
+  up        apply all pending migrations
+  down [n]  revert the last n applied migrations (default 1)
+  status    list migrations and their state`
+
+// RunMigrate - runs the migrate command with the given arguments
+// Database is configured with the same config file and env variables as the server
+// returns process exit code
+func RunMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	revertCount := 1
+	switch args[0] {
+	case "up", "status":
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+	case "down":
+		if len(args) > 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		if len(args) == 2 {
+			count, err := strconv.Atoi(args[1])
+			if err != nil || count < 1 {
+				fmt.Fprintln(os.Stderr, migrateUsage)
+				return 2
+			}
+			revertCount = count
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	_, cfg, err := loadConfig()
+	if err != nil {
+		logError.Printf("Error loading config: %s", err)
+		return 1
+	}
+	db, err := openDatabase(cfg.DB)
+	if err != nil {
+		logError.Printf("Error opening database: %s", err)
+		return 1
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		appliedMigrations, err := migrations.Up(db)
+		for _, migration := range appliedMigrations {
+			logInfo.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			logError.Printf("Error applying migrations: %s", err)
+			return 1
+		}
+		if len(appliedMigrations) == 0 {
+			logInfo.Print("No pending migrations")
+		}
+	case "down":
+		for i := 0; i < revertCount; i++ {
+			migration, err := migrations.Down(db)
+			if err == migrations.ErrNoAppliedMigrations {
+				logInfo.Print("No applied migrations to revert")
+				break
+			}
+			if err != nil {
+				logError.Printf("Error reverting migration: %s", err)
+				return 1
+			}
+			logInfo.Printf("Reverted migration %d_%s", migration.Version, migration.Name)
+		}
+	case "status":
+		statuses, err := migrations.GetStatus(db)
+		if err != nil {
+			logError.Printf("Error retrieving migrations status: %s", err)
+			return 1
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied at " + status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+	}
+	return 0
+}
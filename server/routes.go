@@ -0,0 +1,226 @@
+package server
+
+import (
+	jwtmiddleware "github.com/auth0/go-jwt-middleware"
+	"github.com/blinky-z/Blog/handler/renderapi"
+	"github.com/blinky-z/Blog/handler/restapi"
+	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/repository"
+	"github.com/blinky-z/Blog/storage"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
+)
+
+// newRouter - creates api handlers and routes requests to them
+func (server *Server) newRouter(domain *url.URL, jwtSecret []byte, mediaStorage storage.Storage) http.Handler {
+	// create JWT Middleware
+	// it intercepts requests on secured paths and checks jwt token
+	jwtUserProperty := "user"
+	jwtMiddleware := jwtmiddleware.New(jwtmiddleware.Options{
+		UserProperty: jwtUserProperty,
+		ValidationKeyGetter: func(token *jwt.Token) (interface{}, error) {
+			return jwtSecret, nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err string) {
+			restapi.RespondWithError(w, http.StatusUnauthorized, restapi.InvalidToken)
+		},
+		Extractor:     restapi.ExtractAccessToken,
+		SigningMethod: jwt.SigningMethodHS256,
+	})
+	// the same middleware for admin pages, but it redirects unauthenticated users to the login page
+	jwtPageMiddleware := jwtmiddleware.New(jwtmiddleware.Options{
+		UserProperty: jwtUserProperty,
+		ValidationKeyGetter: func(token *jwt.Token) (interface{}, error) {
+			return jwtSecret, nil
+		},
+		ErrorHandler:  restapi.RedirectToLoginPage,
+		Extractor:     restapi.ExtractAccessToken,
+		SigningMethod: jwt.SigningMethodHS256,
+	})
+
+	posts := repository.NewPostgresPostRepository(server.db)
+	tags := repository.NewPostgresTagRepository(server.db)
+	users := repository.NewPostgresUserRepository(server.db)
+	comments := repository.NewPostgresCommentRepository(server.db)
+
+	postAPIHandler := restapi.NewPostAPIHandler(posts,
+		users,
+		comments,
+		log.New(os.Stdout, "[restApi.post] INFO: ", log.Ltime),
+		log.New(os.Stderr, "[restApi.post] ERROR: ", log.Ltime))
+	tagAPIHandler := restapi.NewTagAPIHandler(tags,
+		log.New(os.Stdout, "[restApi.tag] INFO: ", log.Ltime),
+		log.New(os.Stderr, "[restApi.tag] ERROR: ", log.Ltime))
+	commentAPIHandler := restapi.NewCommentAPIHandler(server.db,
+		log.New(os.Stdout, "[restApi.comment] INFO: ", log.Ltime),
+		log.New(os.Stderr, "[restApi.comment] ERROR: ", log.Ltime))
+	userAPIHandler := restapi.NewUserAPIHandler(server.db,
+		jwtSecret,
+		jwtUserProperty,
+		log.New(os.Stdout, "[restApi.user] INFO: ", log.Ltime),
+		log.New(os.Stderr, "[restApi.user] ERROR: ", log.Ltime))
+	mediaAPIHandler := restapi.NewMediaAPIHandler(server.db,
+		mediaStorage,
+		log.New(os.Stdout, "[restApi.media] INFO: ", log.Ltime),
+		log.New(os.Stderr, "[restApi.media] ERROR: ", log.Ltime))
+	renderAPIHandler := renderapi.NewRenderAPIHandler(server.db,
+		posts,
+		tags,
+		users,
+		server.templates,
+		domain,
+		server.site,
+		log.New(os.Stdout, "[renderApi.render] INFO: ", log.Ltime),
+		log.New(os.Stderr, "[renderApi.render] ERROR: ", log.Ltime))
+
+	router := mux.NewRouter()
+	mainRouter := router.Host(domain.Host).Subrouter()
+
+	router.HandleFunc("/api/hc", func(w http.ResponseWriter, r *http.Request) {
+		if err := server.db.Ping(); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(200)
+	}).Methods("GET")
+	// readiness check fails during shutdown, so that load balancer stops routing requests to the server
+	router.HandleFunc("/api/ready", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&server.isDraining) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if err := server.db.Ping(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}).Methods("GET")
+
+	// set auth handlers
+	// registered users get no permissions, so registration should be enabled only to register new team members
+	// Users from admins list become admins on the server start
+	if server.config.RegistrationEnabled {
+		router.Handle("/api/user/register", userAPIHandler.RegisterUserHandler()).Methods("POST")
+	}
+	router.Handle("/api/user/login", userAPIHandler.LoginUserHandler()).Methods("POST")
+	router.Handle("/api/user/refresh", userAPIHandler.RefreshTokenHandler()).Methods("POST")
+	router.Handle("/api/user/logout", userAPIHandler.LogoutUserHandler()).Methods("POST")
+
+	// secured - requires valid access token for rest api requests and user role having any of the permissions
+	secured := func(handler http.Handler, permissions ...restapi.Permission) http.Handler {
+		return jwtMiddleware.Handler(userAPIHandler.FgpAuthentication(
+			userAPIHandler.Authorization(permissions...)(handler)))
+	}
+	// securedPage - requires user to be logged in for admin pages and user role having any of the permissions
+	securedPage := func(handler http.Handler, permissions ...restapi.Permission) http.Handler {
+		return jwtPageMiddleware.Handler(userAPIHandler.AdminPageAuthentication(
+			userAPIHandler.Authorization(permissions...)(handler)))
+	}
+	// posts are accessible to users managing posts and to authors writing their own drafts
+	postsPermissions := []restapi.Permission{restapi.PermissionManagePosts, restapi.PermissionWriteOwnDrafts}
+
+	// set theme static files paths. Files missing in the theme are served from the default theme
+	staticFiles := server.assets.Handler()
+	router.PathPrefix("/css/").Handler(staticFiles)
+	router.PathPrefix("/js/").Handler(staticFiles)
+	router.PathPrefix("/images/").Handler(staticFiles)
+	// uploaded files are served on both site and admin dashboard, so that editor preview shows them
+	router.Path(models.MediaURLPrefix + "{key}").Handler(mediaAPIHandler.ServeMediaHandler()).Methods("GET")
+
+	// set pages rendering handlers
+	mainRouter.Path("/posts").Handler(renderAPIHandler.RenderAllPostsPageHandler()).Methods("GET")
+	mainRouter.Path("/posts/{slug}").Handler(renderAPIHandler.RenderPostPageHandler()).Methods("GET")
+	mainRouter.Path("/tags").Handler(renderAPIHandler.RenderAllTagsPageHandler()).Methods("GET")
+	mainRouter.Path("/tags/{tag}").Handler(renderAPIHandler.RenderAllPostsPageHandler()).Methods("GET")
+	mainRouter.Path("/authors/{username}").Handler(renderAPIHandler.RenderAuthorPageHandler()).Methods("GET")
+	mainRouter.Path("/feed.xml").Handler(renderAPIHandler.RenderRSSFeedHandler()).Methods("GET")
+	mainRouter.Path("/atom.xml").Handler(renderAPIHandler.RenderAtomFeedHandler()).Methods("GET")
+	mainRouter.Path("/feed.json").Handler(renderAPIHandler.RenderJSONFeedHandler()).Methods("GET")
+	mainRouter.Path("/tags/{tag}/feed.xml").Handler(renderAPIHandler.RenderRSSFeedHandler()).Methods("GET")
+	mainRouter.Path("/tags/{tag}/atom.xml").Handler(renderAPIHandler.RenderAtomFeedHandler()).Methods("GET")
+	mainRouter.Path("/tags/{tag}/feed.json").Handler(renderAPIHandler.RenderJSONFeedHandler()).Methods("GET")
+	mainRouter.Path("/search").Handler(renderAPIHandler.RenderSearchPageHandler()).Methods("GET")
+	mainRouter.Path("/api/search").Handler(postAPIHandler.SearchPostsHandler()).Methods("GET")
+	mainRouter.Path("/about").Handler(renderAPIHandler.RenderAboutPageHandler()).Methods("GET")
+	mainRouter.Path("/index").Handler(renderAPIHandler.RenderIndexPageHandler()).Methods("GET")
+	mainRouter.Path("/").Handler(renderAPIHandler.RenderIndexPageHandler()).Methods("GET")
+	mainRouter.Path("/api/comments").Handler(commentAPIHandler.CreateCommentHandler()).Methods("POST")
+
+	// set public read-only api
+	apiV1Router := mainRouter.PathPrefix("/api/v1").Subrouter()
+	apiV1Router.Path("/posts").Handler(postAPIHandler.GetPostsHandler()).Methods("GET")
+	apiV1Router.Path("/posts/{id}").Handler(postAPIHandler.GetCertainPostHandler()).Methods("GET")
+	apiV1Router.Path("/tags").Handler(tagAPIHandler.GetTagsHandler()).Methods("GET")
+	apiV1Router.Path("/tags/{tag}/posts").Handler(postAPIHandler.GetPostsHandler()).Methods("GET")
+	apiV1Router.Path("/authors/{username}").Handler(userAPIHandler.GetAuthorHandler()).Methods("GET")
+	apiV1Router.Path("/authors/{username}/posts").Handler(postAPIHandler.GetPostsHandler()).Methods("GET")
+
+	// robots.txt is generated unless it is set in the override directory
+	if hasFile(server.rootFiles, "robots.txt") {
+		mainRouter.Path("/robots.txt").Handler(serveFile(server.rootFiles, "robots.txt")).Methods("GET")
+	} else {
+		mainRouter.Path("/robots.txt").Handler(renderAPIHandler.RenderRobotsHandler()).Methods("GET")
+	}
+	mainRouter.Path("/sitemap.xml").Handler(renderAPIHandler.RenderSitemapHandler()).Methods("GET")
+	mainRouter.Path("/sitemap-{page:[0-9]+}.xml").Handler(renderAPIHandler.RenderSitemapPageHandler()).Methods("GET")
+	// old sitemap location
+	mainRouter.Path("/sitemap").Handler(http.RedirectHandler("/sitemap.xml", http.StatusMovedPermanently)).
+		Methods("GET")
+
+	adminRouter := router.Host("admin." + domain.Host).Subrouter()
+	adminRouter.Path(restapi.LoginPagePath).Handler(renderAPIHandler.RenderAdminLoginPageHandler()).Methods("GET")
+	adminRouter.Path("/").Handler(securedPage(renderAPIHandler.RenderAdminPageHandler(), postsPermissions...)).
+		Methods("GET")
+	adminRouter.Path("/editor").Handler(securedPage(renderAPIHandler.RenderAdminEditorPageHandler(),
+		postsPermissions...)).Methods("GET")
+	adminRouter.Path("/manage-posts").Handler(securedPage(renderAPIHandler.RenderAdminManagePostsPageHandler(),
+		postsPermissions...)).Methods("GET")
+	adminRouter.Path("/manage-tags").Handler(securedPage(renderAPIHandler.RenderAdminManageTagsPageHandler(),
+		restapi.PermissionManageTags)).Methods("GET")
+	adminRouter.Path("/manage-media").Handler(securedPage(renderAPIHandler.RenderAdminManageMediaPageHandler(),
+		restapi.PermissionUploadMedia)).Methods("GET")
+	adminRouter.Path("/robots.txt").Handler(serveFile(server.rootFiles, "robots_admin.txt")).Methods("GET")
+
+	// set blog posts related rest api
+	adminRouter.Handle("/api/posts", secured(postAPIHandler.CreatePostHandler(), postsPermissions...)).
+		Methods("POST")
+	adminRouter.Handle("/api/posts/render", secured(postAPIHandler.RenderAllPostsHandler(),
+		restapi.PermissionManageSite)).Methods("POST")
+	adminRouter.Handle("/api/posts/{id}", secured(postAPIHandler.UpdatePostHandler(), postsPermissions...)).
+		Methods("PUT")
+	adminRouter.Handle("/api/posts/{id}", secured(postAPIHandler.DeletePostHandler(),
+		restapi.PermissionManagePosts)).Methods("DELETE")
+	adminRouter.Handle("/api/posts/{id}/revisions", secured(postAPIHandler.GetPostRevisionsHandler(),
+		postsPermissions...)).Methods("GET")
+	adminRouter.Handle("/api/posts/{id}/revisions/diff", secured(postAPIHandler.GetPostRevisionsDiffHandler(),
+		postsPermissions...)).Methods("GET")
+	adminRouter.Handle("/api/posts/{id}/revisions/{revisionID}/restore",
+		secured(postAPIHandler.RestorePostRevisionHandler(), postsPermissions...)).Methods("POST")
+	adminRouter.Handle("/api/comments/{id}", secured(commentAPIHandler.UpdateCommentHandler(),
+		restapi.PermissionManageComments)).Methods("PUT")
+	adminRouter.Handle("/api/comments/{id}", secured(commentAPIHandler.DeleteCommentHandler(),
+		restapi.PermissionManageComments)).Methods("DELETE")
+	adminRouter.Handle("/api/tags", secured(tagAPIHandler.CreateTagHandler(), restapi.PermissionManageTags)).
+		Methods("POST")
+	adminRouter.Handle("/api/tags/{id}", secured(tagAPIHandler.UpdateTagHandler(), restapi.PermissionManageTags)).
+		Methods("PUT")
+	adminRouter.Handle("/api/tags/{id}", secured(tagAPIHandler.DeleteTagHandler(), restapi.PermissionManageTags)).
+		Methods("DELETE")
+	adminRouter.Handle("/api/media", secured(mediaAPIHandler.UploadMediaHandler(), restapi.PermissionUploadMedia)).
+		Methods("POST")
+	adminRouter.Handle("/api/media", secured(mediaAPIHandler.GetMediaHandler(), restapi.PermissionUploadMedia)).
+		Methods("GET")
+	adminRouter.Handle("/api/media/{id}", secured(mediaAPIHandler.DeleteMediaHandler(),
+		restapi.PermissionManageMedia)).Methods("DELETE")
+	adminRouter.Handle("/api/user/profile", secured(userAPIHandler.UpdateProfileHandler(),
+		restapi.PermissionEditOwnProfile)).Methods("PUT")
+	adminRouter.Handle("/api/users/{username}/role", secured(userAPIHandler.UpdateUserRoleHandler(),
+		restapi.PermissionManageUsers)).Methods("PUT")
+
+	return router
+}
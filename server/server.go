@@ -0,0 +1,285 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/blinky-z/Blog/assets"
+	"github.com/blinky-z/Blog/config"
+	"github.com/blinky-z/Blog/handler/renderapi"
+	"github.com/blinky-z/Blog/migrations"
+	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/service/postService"
+	"github.com/blinky-z/Blog/service/userService"
+	"github.com/blinky-z/Blog/storage"
+	"github.com/blinky-z/Blog/themes"
+	_ "github.com/lib/pq" // import postgres driver
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Server - blog server. It serves both the site and the admin dashboard
+// Use Handler to embed the blog into another http server or Start to serve requests on the configured port
+type Server struct {
+	config     config.Config
+	site       *config.LiveSite
+	db         *sql.DB
+	rootFiles  fs.FS
+	theme      *themes.Theme
+	assets     *assets.Assets
+	templates  *renderapi.Templates
+	handler    http.Handler
+	httpServer *http.Server
+	// isStarted - set to 1 when the server starts serving requests on the configured port
+	isStarted int32
+	// isDraining - set to 1 on shutdown to fail readiness check
+	isDraining int32
+	// stopPublisher - stops scheduled posts publisher
+	stopPublisher chan struct{}
+	// publisher - waits for scheduled posts publisher to stop, so that database is closed after it
+	publisher sync.WaitGroup
+	// shutdownOnce - guards shutdown from running twice
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
+// New - creates blog server with the given config
+// Database is opened and migrated, but requests are not served until the server is started
+func New(cfg config.Config) (*Server, error) {
+	domain, err := url.Parse(cfg.Domain)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing domain: %s", err)
+	}
+	jwtSecret := []byte(cfg.JWTSecretKey)
+	if len(jwtSecret) == 0 {
+		return nil, errors.New("JWT secret key is not set")
+	}
+
+	if len(cfg.Admins) == 0 {
+		logError.Print("Admins list is empty: admin dashboard is inaccessible")
+	}
+	mediaStorage, err := newStorage(cfg.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("error creating storage of uploaded files: %s", err)
+	}
+
+	if cfg.OverrideDir != "" {
+		logInfo.Printf("Using files of %s instead of the embedded ones", cfg.OverrideDir)
+	}
+	rootFiles, themeFiles := newFiles(cfg.OverrideDir)
+	siteTheme, err := themes.Load(themeFiles, cfg.Theme)
+	if err != nil {
+		return nil, fmt.Errorf("error loading theme: %s", err)
+	}
+	logInfo.Printf("Using theme %s", siteTheme.Name())
+	// in development mode static files may change, so they are served by the plain names
+	siteAssets, err := assets.New(siteTheme.StaticFiles(), !cfg.DevMode)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := newTemplates(siteTheme, siteAssets, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := openDatabase(cfg.DB)
+	if err != nil {
+		templates.Close()
+		return nil, fmt.Errorf("error opening database: %s", err)
+	}
+	if err = prepareDatabase(db, cfg); err != nil {
+		templates.Close()
+		db.Close()
+		return nil, err
+	}
+
+	server := &Server{
+		config:        cfg,
+		site:          config.NewLiveSite(cfg.Site),
+		db:            db,
+		rootFiles:     rootFiles,
+		theme:         siteTheme,
+		assets:        siteAssets,
+		templates:     templates,
+		stopPublisher: make(chan struct{}),
+	}
+	server.handler = server.newRouter(domain, jwtSecret, mediaStorage)
+	server.httpServer = &http.Server{
+		// omitting host will run server on all interfaces
+		Addr:           ":" + cfg.Server.Port,
+		Handler:        server.handler,
+		ReadTimeout:    cfg.Server.ReadTimeout,
+		WriteTimeout:   cfg.Server.WriteTimeout,
+		IdleTimeout:    cfg.Server.IdleTimeout,
+		MaxHeaderBytes: cfg.Server.MaxHeaderBytes,
+		ErrorLog:       logError,
+	}
+	return server, nil
+}
+
+// newTemplates - parses page templates of the theme
+// In development mode templates are parsed again on changes of their files in the override directory
+func newTemplates(siteTheme *themes.Theme, siteAssets *assets.Assets,
+	cfg config.Config) (*renderapi.Templates, error) {
+	templates, err := renderapi.NewTemplates(siteTheme, siteAssets,
+		log.New(os.Stdout, "[renderApi.templates] INFO: ", log.Ltime),
+		log.New(os.Stderr, "[renderApi.templates] ERROR: ", log.Ltime))
+	if err != nil {
+		return nil, fmt.Errorf("error loading page templates: %s", err)
+	}
+	if !cfg.DevMode {
+		return templates, nil
+	}
+	// embedded templates can't change, so only the templates of the override directory are watched
+	templateDirs := overrideTemplateDirs(cfg.OverrideDir, siteTheme)
+	if len(templateDirs) == 0 {
+		logError.Print("Development mode: override directory has no templates of the theme, " +
+			"templates are not reloaded on changes")
+		return templates, nil
+	}
+	if err = templates.Watch(templateDirs); err != nil {
+		return nil, fmt.Errorf("error watching page templates: %s", err)
+	}
+	logInfo.Print("Development mode: templates are reloaded on changes")
+	return templates, nil
+}
+
+// newStorage - creates storage of uploaded files with the configured backend
+func newStorage(storageConfig config.Storage) (storage.Storage, error) {
+	switch storageConfig.Backend {
+	case config.LocalStorageBackend:
+		logInfo.Printf("Using local storage of uploaded files in %s", storageConfig.MediaDir)
+		return storage.NewLocalStorage(storageConfig.MediaDir)
+	case config.S3StorageBackend:
+		logInfo.Printf("Using S3 storage of uploaded files. Endpoint: %s, bucket: %s",
+			storageConfig.S3.Endpoint, storageConfig.S3.Bucket)
+		return storage.NewS3Storage(storageConfig.S3)
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", storageConfig.Backend)
+	}
+}
+
+// openDatabase - opens the configured database and validates the connection
+func openDatabase(dbConfig config.DB) (*sql.DB, error) {
+	connString := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		dbConfig.Host, dbConfig.Port, dbConfig.User, dbConfig.Password, dbConfig.Name)
+	logInfo.Printf("Opening database on host=%s, port=%s, user=%s, db name=%s...",
+		dbConfig.Host, dbConfig.Port, dbConfig.User, dbConfig.Name)
+	db, err := sql.Open("postgres", connString)
+	if err != nil {
+		return nil, err
+	}
+	// validate data source
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("invalid data source: %s", err)
+	}
+	logInfo.Print("Database successfully opened")
+	return db, nil
+}
+
+// prepareDatabase - applies migrations and updates data created by the previous server versions
+func prepareDatabase(db *sql.DB, cfg config.Config) error {
+	if cfg.AutoMigrate {
+		appliedMigrations, err := migrations.Up(db)
+		if err != nil {
+			return fmt.Errorf("error applying database migrations: %s", err)
+		}
+		for _, migration := range appliedMigrations {
+			logInfo.Printf("Applied database migration %d_%s", migration.Version, migration.Name)
+		}
+	}
+
+	// generate slugs for posts created before slugs were introduced
+	if filledCount, err := postService.FillMissingSlugs(db); err != nil {
+		return fmt.Errorf("error generating missing post slugs: %s", err)
+	} else if filledCount != 0 {
+		logInfo.Printf("Generated slugs for %d posts", filledCount)
+	}
+
+	// registration gives no permissions, so users from admins list get admin role here
+	if grantedCount, err := userService.GrantRole(db, cfg.Admins, models.RoleAdmin); err != nil {
+		return fmt.Errorf("error granting admin role to admins: %s", err)
+	} else if grantedCount != 0 {
+		logInfo.Printf("Granted admin role to %d users", grantedCount)
+	}
+	return nil
+}
+
+// Handler - returns handler serving all blog requests
+func (server *Server) Handler() http.Handler {
+	return server.handler
+}
+
+// DB - returns database connections pool of the server
+func (server *Server) DB() *sql.DB {
+	return server.db
+}
+
+// Site - returns site settings of the server. Settings set here are applied to the next requests
+func (server *Server) Site() *config.LiveSite {
+	return server.site
+}
+
+// Start - starts publishing scheduled posts and serving requests on the configured port
+// Blocks until the server is shut down. Returns nil if the server was shut down with Shutdown
+func (server *Server) Start() error {
+	server.publisher.Add(1)
+	go func() {
+		defer server.publisher.Done()
+		postService.RunScheduledPublisher(server.db, scheduledPublisherCheckInterval, server.stopPublisher,
+			log.New(os.Stdout, "[postService.publisher] INFO: ", log.Ltime),
+			log.New(os.Stderr, "[postService.publisher] ERROR: ", log.Ltime))
+	}()
+
+	logInfo.Printf("Starting server on port %s", server.config.Server.Port)
+	listener, err := net.Listen("tcp", server.httpServer.Addr)
+	if err != nil {
+		return err
+	}
+	// drain delay is waited out on shutdown only if the server could accept requests
+	atomic.StoreInt32(&server.isStarted, 1)
+	if err := server.httpServer.Serve(listener); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown - gracefully stops the server and closes the database
+// Readiness check starts failing at once, while new requests are still accepted during the configured drain delay,
+// so that load balancer stops routing requests to the server. Then the server waits for the active requests
+// to finish until the context is done. Drain delay is a part of the shutdown, so it is cut short by the context too
+func (server *Server) Shutdown(ctx context.Context) error {
+	server.shutdownOnce.Do(func() {
+		atomic.StoreInt32(&server.isDraining, 1)
+		server.httpServer.SetKeepAlivesEnabled(false)
+		// load balancer notices failing readiness check only on the next check
+		if atomic.LoadInt32(&server.isStarted) == 1 {
+			select {
+			case <-time.After(server.config.Server.DrainDelay):
+			case <-ctx.Done():
+			}
+		}
+		if err := server.httpServer.Shutdown(ctx); err != nil {
+			server.shutdownErr = fmt.Errorf("error draining requests: %s", err)
+		}
+
+		close(server.stopPublisher)
+		server.publisher.Wait()
+		if err := server.templates.Close(); err != nil {
+			logError.Printf("Error stopping templates watcher: %s", err)
+		}
+		if err := server.db.Close(); err != nil && server.shutdownErr == nil {
+			server.shutdownErr = fmt.Errorf("error closing database: %s", err)
+		}
+	})
+	return server.shutdownErr
+}
@@ -0,0 +1,12 @@
+package commentService
+
+import (
+	"github.com/blinky-z/Blog/models"
+)
+
+type SaveRequest struct {
+	PostID   string
+	ParentID models.NullString
+	Author   string
+	Content  string
+}
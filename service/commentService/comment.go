@@ -0,0 +1,171 @@
+package commentService
+
+import (
+	"database/sql"
+	"github.com/blinky-z/Blog/models"
+)
+
+const (
+	// commentsInsertFields - fields that should be filled while inserting a new entity
+	commentsInsertFields = "post_id, parent_id, author, content"
+	// commentsAllFields - all entity fields
+	commentsAllFields = "id, post_id, parent_id, author, date, content, deleted"
+)
+
+// DeletedCommentContent - content that replaces the content of deleted comment if this comment has replies
+const DeletedCommentContent = "This comment was deleted"
+
+func scanComment(row interface{ Scan(...interface{}) error }, comment *models.Comment) error {
+	return row.Scan(&comment.ID, &comment.PostID, &comment.ParentID, &comment.Author, &comment.Date,
+		&comment.Content, &comment.Deleted)
+}
+
+// Save - saves a new comment
+// returns a created comment pointed to by 'createdComment' and error
+func Save(db *sql.DB, request *SaveRequest) (*models.Comment, error) {
+	createdComment := &models.Comment{}
+
+	row := db.QueryRow("insert into comments ("+commentsInsertFields+") values ($1, $2, $3, $4) "+
+		"returning "+commentsAllFields,
+		request.PostID, sql.NullString(request.ParentID), request.Author, request.Content)
+	err := scanComment(row, createdComment)
+	return createdComment, err
+}
+
+// Update - updates content of the comment
+// if comment does not exist or was deleted, err.SqlNoRows error will be returned
+func Update(db *sql.DB, commentID, content string) (*models.Comment, error) {
+	updatedComment := &models.Comment{}
+
+	row := db.QueryRow("update comments set content = $1 where id = $2 and deleted = false "+
+		"returning "+commentsAllFields,
+		content, commentID)
+	err := scanComment(row, updatedComment)
+	return updatedComment, err
+}
+
+// GetByID - retrieves comment with the given ID
+// if comment does not exist, err.SqlNoRows error will be returned
+func GetByID(db *sql.DB, commentID string) (models.Comment, error) {
+	var comment models.Comment
+
+	row := db.QueryRow("select "+commentsAllFields+" from comments where id = $1", commentID)
+	err := scanComment(row, &comment)
+	return comment, err
+}
+
+// GetAllByPostID - retrieves all comments of the given post
+// the returned slice is sorted by comment creation time in ascending order
+func GetAllByPostID(db *sql.DB, postID string) ([]models.Comment, error) {
+	var comments []models.Comment
+
+	rows, err := db.Query("select "+commentsAllFields+" from comments where post_id = $1 order by date ASC, id ASC",
+		postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var comment models.Comment
+		if err = scanComment(rows, &comment); err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+// DeleteByID - deletes comment with the given ID
+// Comment that has replies is not removed from database: its content is replaced with DeletedCommentContent instead,
+// so the replies stay in the tree. Deleted comments that have no more replies left are removed
+func DeleteByID(db *sql.DB, commentID string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	var hasChilds bool
+	if err = tx.QueryRow("select exists(select from comments where parent_id = $1)", commentID).
+		Scan(&hasChilds); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if hasChilds {
+		if _, err = tx.Exec("update comments set (content, deleted) = ($1, true) where id = $2",
+			DeletedCommentContent, commentID); err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	}
+
+	// remove the comment and then walk up the tree removing deleted ancestors that have no more replies
+	currentID := commentID
+	for {
+		var parentID sql.NullString
+		err = tx.QueryRow("delete from comments where id = $1 returning parent_id", currentID).Scan(&parentID)
+		if err == sql.ErrNoRows {
+			// deletion is idempotent
+			break
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if !parentID.Valid {
+			break
+		}
+
+		var isParentRemovable bool
+		if err = tx.QueryRow("select deleted and not exists(select from comments where parent_id = $1) "+
+			"from comments where id = $1", parentID.String).Scan(&isParentRemovable); err != nil {
+			if err == sql.ErrNoRows {
+				break
+			}
+			tx.Rollback()
+			return err
+		}
+		if !isParentRemovable {
+			break
+		}
+		currentID = parentID.String
+	}
+
+	return tx.Commit()
+}
+
+// BuildTree - builds the tree of comments from the flat slice of comments
+// Order of comments on each level of the tree is the same as in the given slice
+func BuildTree(comments []models.Comment) []*models.CommentWithChilds {
+	roots := make([]*models.CommentWithChilds, 0)
+	nodes := make(map[string]*models.CommentWithChilds, len(comments))
+	for _, comment := range comments {
+		nodes[comment.ID] = &models.CommentWithChilds{Comment: comment, Childs: make([]*models.CommentWithChilds, 0)}
+	}
+
+	for _, comment := range comments {
+		node := nodes[comment.ID]
+		if comment.ParentID.Valid {
+			if parent, ok := nodes[comment.ParentID.String]; ok {
+				parent.Childs = append(parent.Childs, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	return roots
+}
+
+// GetTreeByPostID - retrieves all comments of the given post as a tree
+// comments on each level of the tree are sorted by creation time in ascending order
+func GetTreeByPostID(db *sql.DB, postID string) ([]*models.CommentWithChilds, error) {
+	comments, err := GetAllByPostID(db, postID)
+	if err != nil {
+		return nil, err
+	}
+	return BuildTree(comments), nil
+}
@@ -0,0 +1,15 @@
+package commentService
+
+import (
+	"github.com/microcosm-cc/bluemonday"
+	"html"
+)
+
+// contentPolicy - removes all html from comments. Comments are plain text and are escaped when rendered
+var contentPolicy = bluemonday.StrictPolicy()
+
+// SanitizeContent - removes all html elements from the comment content
+// Html entities are unescaped back, so that the content is kept as plain text
+func SanitizeContent(content string) string {
+	return html.UnescapeString(contentPolicy.Sanitize(content))
+}
@@ -0,0 +1,15 @@
+package commentService
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSanitizeContentRemovesHTML(t *testing.T) {
+	require.Equal(t, "bold and ", SanitizeContent(`<b>bold</b> and <script>alert("comment")</script>`))
+	require.Equal(t, "", SanitizeContent(`<img src="/x.png" onerror="alert('comment')">`))
+}
+
+func TestSanitizeContentKeepsPlainText(t *testing.T) {
+	require.Equal(t, `a < b && "c" > 'd'`, SanitizeContent(`a < b && "c" > 'd'`))
+}
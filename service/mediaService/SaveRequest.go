@@ -0,0 +1,11 @@
+package mediaService
+
+type SaveRequest struct {
+	Key         string
+	Filename    string
+	ContentType string
+	Size        int64
+	Width       int
+	Height      int
+	UploadedBy  string
+}
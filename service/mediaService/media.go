@@ -0,0 +1,113 @@
+package mediaService
+
+import (
+	"database/sql"
+	"github.com/blinky-z/Blog/models"
+	pg "github.com/lib/pq"
+)
+
+const (
+	// mediaInsertFields - fields that should be filled while inserting a new entity
+	mediaInsertFields = "storage_key, filename, content_type, size, width, height, uploaded_by"
+	// mediaAllFields - all entity fields
+	mediaAllFields = "id, storage_key, filename, content_type, size, width, height, uploaded_by, created_at"
+)
+
+// scanner - common interface of sql.Row and sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanMedia - scans media fields listed in mediaAllFields
+func scanMedia(row scanner, media *models.Media) error {
+	if err := row.Scan(&media.ID, &media.Key, &media.Filename, &media.ContentType, &media.Size, &media.Width,
+		&media.Height, &media.UploadedBy, &media.Created); err != nil {
+		return err
+	}
+
+	media.URL = models.MediaURLPrefix + media.Key
+	media.ThumbnailURL = media.URL
+	if HasVariants(*media) {
+		media.ThumbnailURL = models.MediaURLPrefix + VariantKey(media.Key, ThumbnailVariant)
+	}
+	return nil
+}
+
+// Save - saves uploaded file info. File itself should be saved in the storage
+func Save(db *sql.DB, request *SaveRequest) (models.Media, error) {
+	var media models.Media
+	err := scanMedia(db.QueryRow("insert into media ("+mediaInsertFields+") values ($1, $2, $3, $4, $5, $6, $7) "+
+		"returning "+mediaAllFields,
+		request.Key, request.Filename, request.ContentType, request.Size, request.Width, request.Height,
+		request.UploadedBy), &media)
+	return media, err
+}
+
+// GetByKey - retrieves file info by the storage key
+// if file does not exist, err.SqlNoRows error will be returned
+func GetByKey(db *sql.DB, key string) (models.Media, error) {
+	var media models.Media
+	err := scanMedia(db.QueryRow("select "+mediaAllFields+" from media where storage_key = $1", key), &media)
+	return media, err
+}
+
+// GetByKeys - retrieves info of files with the given storage keys
+// not existing keys are skipped
+func GetByKeys(db *sql.DB, keys []string) ([]models.Media, error) {
+	media := make([]models.Media, 0)
+
+	rows, err := db.Query("select "+mediaAllFields+" from media where storage_key = any($1::varchar[])", pg.Array(keys))
+	if err != nil {
+		return media, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var currentMedia models.Media
+		if err = scanMedia(rows, &currentMedia); err != nil {
+			return media, err
+		}
+		media = append(media, currentMedia)
+	}
+
+	return media, rows.Err()
+}
+
+// GetInRange - retrieves uploaded files in the given range
+// the returned slice is sorted by upload time in descending order
+func GetInRange(db *sql.DB, offset, limit int) ([]models.Media, error) {
+	media := make([]models.Media, 0)
+
+	rows, err := db.Query("select "+mediaAllFields+" from media order by created_at DESC, id DESC offset $1 limit $2",
+		offset, limit)
+	if err != nil {
+		return media, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var currentMedia models.Media
+		if err = scanMedia(rows, &currentMedia); err != nil {
+			return media, err
+		}
+		media = append(media, currentMedia)
+	}
+
+	return media, rows.Err()
+}
+
+// Count - returns amount of all uploaded files
+func Count(db *sql.DB) (int, error) {
+	var count int
+	err := db.QueryRow("select count(*) from media").Scan(&count)
+	return count, err
+}
+
+// DeleteByID - deletes file info. File itself should be deleted from the storage
+// returns deleted file info and error
+// if file does not exist, err.SqlNoRows error will be returned
+func DeleteByID(db *sql.DB, mediaID string) (models.Media, error) {
+	var media models.Media
+	err := scanMedia(db.QueryRow("delete from media where id = $1 returning "+mediaAllFields, mediaID), &media)
+	return media, err
+}
@@ -0,0 +1,280 @@
+package mediaService
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+)
+
+const (
+	// jpegMarkerPrefix - first byte of every JPEG marker
+	jpegMarkerPrefix = 0xFF
+	// jpegStartOfImage - marker JPEG image starts with
+	jpegStartOfImage = 0xD8
+	// jpegStartOfScan - marker of compressed image data. Image data is not split into segments
+	jpegStartOfScan = 0xDA
+	// jpegExifSegment - APP1 segment containing EXIF or XMP metadata
+	jpegExifSegment = 0xE1
+	// jpegIPTCSegment - APP13 segment containing IPTC metadata
+	jpegIPTCSegment = 0xED
+	// jpegCommentSegment - segment containing text comment
+	jpegCommentSegment = 0xFE
+	// exifHeader - header of EXIF metadata in APP1 segment
+	exifHeader = "Exif\x00\x00"
+	// exifOrientationTag - EXIF tag of image orientation
+	exifOrientationTag = 0x0112
+	// orientedJPEGQuality - quality of JPEG images re-encoded to apply EXIF orientation
+	orientedJPEGQuality = 90
+
+	// pngSignature - signature PNG image starts with
+	pngSignature = "\x89PNG\r\n\x1a\n"
+	// pngEndChunk - last chunk of PNG image
+	pngEndChunk = "IEND"
+
+	// webpHeaderLen - length of RIFF header of WebP image
+	webpHeaderLen = 12
+	// webpExtendedChunk - chunk containing flags of WebP image features
+	webpExtendedChunk = "VP8X"
+	// webpMetadataFlags - flags of EXIF and XMP metadata in the extended chunk
+	webpMetadataFlags = 0x08 | 0x04
+)
+
+// pngMetadataChunks - PNG chunks containing text metadata and EXIF
+var pngMetadataChunks = map[string]bool{
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"eXIf": true,
+	"tIME": true,
+}
+
+// webpMetadataChunks - WebP chunks containing EXIF and XMP metadata
+var webpMetadataChunks = map[string]bool{
+	"EXIF": true,
+	"XMP ": true,
+}
+
+// StripMetadata - removes EXIF and other metadata, such as camera model and GPS location, from the image
+// Image data is kept as is. JPEG images rotated by EXIF orientation are re-encoded with the orientation applied,
+// as the orientation is removed together with EXIF
+// ErrInvalidImage error is returned if the image is malformed
+func StripMetadata(content []byte, contentType string) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEGMetadata(content)
+	case "image/png":
+		return stripPNGMetadata(content)
+	case "image/webp":
+		return stripWebPMetadata(content)
+	default:
+		return content, nil
+	}
+}
+
+// stripJPEGMetadata - removes metadata segments from JPEG image
+func stripJPEGMetadata(content []byte) ([]byte, error) {
+	if len(content) < 2 || content[0] != jpegMarkerPrefix || content[1] != jpegStartOfImage {
+		return nil, ErrInvalidImage
+	}
+
+	stripped := make([]byte, 0, len(content))
+	stripped = append(stripped, content[:2]...)
+	orientation := 1
+	position := 2
+	for {
+		if position+1 >= len(content) || content[position] != jpegMarkerPrefix {
+			return nil, ErrInvalidImage
+		}
+		marker := content[position+1]
+		if marker == jpegMarkerPrefix {
+			// fill byte
+			position++
+			continue
+		}
+		if marker == jpegStartOfScan {
+			stripped = append(stripped, content[position:]...)
+			break
+		}
+		// standalone markers have no length
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			stripped = append(stripped, content[position:position+2]...)
+			position += 2
+			continue
+		}
+
+		if position+4 > len(content) {
+			return nil, ErrInvalidImage
+		}
+		segmentEnd := position + 2 + int(binary.BigEndian.Uint16(content[position+2:]))
+		if segmentEnd > len(content) {
+			return nil, ErrInvalidImage
+		}
+
+		switch marker {
+		case jpegExifSegment:
+			if segmentOrientation, ok := parseExifOrientation(content[position+4 : segmentEnd]); ok {
+				orientation = segmentOrientation
+			}
+		case jpegIPTCSegment, jpegCommentSegment:
+		default:
+			stripped = append(stripped, content[position:segmentEnd]...)
+		}
+		position = segmentEnd
+	}
+
+	if orientation <= 1 || orientation > 8 {
+		return stripped, nil
+	}
+
+	decoded, err := jpeg.Decode(bytes.NewReader(stripped))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	var encoded bytes.Buffer
+	if err = jpeg.Encode(&encoded, applyOrientation(toRGBA(decoded, decoded.Bounds()), orientation),
+		&jpeg.Options{Quality: orientedJPEGQuality}); err != nil {
+		return nil, err
+	}
+	return encoded.Bytes(), nil
+}
+
+// parseExifOrientation - parses image orientation from EXIF metadata of APP1 segment
+// returns orientation and boolean indicating whether the orientation was found
+func parseExifOrientation(segment []byte) (int, bool) {
+	if !bytes.HasPrefix(segment, []byte(exifHeader)) {
+		return 0, false
+	}
+	tiff := segment[len(exifHeader):]
+	if len(tiff) < 8 {
+		return 0, false
+	}
+
+	var byteOrder binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		byteOrder = binary.LittleEndian
+	case "MM":
+		byteOrder = binary.BigEndian
+	default:
+		return 0, false
+	}
+
+	ifdOffset := int(byteOrder.Uint32(tiff[4:]))
+	if ifdOffset < 8 || ifdOffset+2 > len(tiff) {
+		return 0, false
+	}
+	entriesCount := int(byteOrder.Uint16(tiff[ifdOffset:]))
+	for i := 0; i < entriesCount; i++ {
+		entry := ifdOffset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0, false
+		}
+		if byteOrder.Uint16(tiff[entry:]) == exifOrientationTag {
+			return int(byteOrder.Uint16(tiff[entry+8:])), true
+		}
+	}
+	return 0, false
+}
+
+// applyOrientation - rotates and flips the image as described by EXIF orientation
+func applyOrientation(src *image.RGBA, orientation int) *image.RGBA {
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	dstWidth, dstHeight := width, height
+	// orientations from 5 to 8 swap width and height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			var srcX, srcY int
+			switch orientation {
+			case 2:
+				srcX, srcY = width-1-x, y
+			case 3:
+				srcX, srcY = width-1-x, height-1-y
+			case 4:
+				srcX, srcY = x, height-1-y
+			case 5:
+				srcX, srcY = y, x
+			case 6:
+				srcX, srcY = y, height-1-x
+			case 7:
+				srcX, srcY = width-1-y, height-1-x
+			case 8:
+				srcX, srcY = width-1-y, x
+			default:
+				srcX, srcY = x, y
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(srcX, srcY):])
+		}
+	}
+	return dst
+}
+
+// stripPNGMetadata - removes text and EXIF chunks from PNG image
+func stripPNGMetadata(content []byte) ([]byte, error) {
+	if !bytes.HasPrefix(content, []byte(pngSignature)) {
+		return nil, ErrInvalidImage
+	}
+
+	stripped := make([]byte, 0, len(content))
+	stripped = append(stripped, pngSignature...)
+	position := len(pngSignature)
+	for {
+		if position+8 > len(content) {
+			return nil, ErrInvalidImage
+		}
+		// chunk consists of data length, type, data and CRC
+		chunkEnd := position + 12 + int(binary.BigEndian.Uint32(content[position:]))
+		if chunkEnd > len(content) || chunkEnd < position {
+			return nil, ErrInvalidImage
+		}
+
+		chunkType := string(content[position+4 : position+8])
+		if !pngMetadataChunks[chunkType] {
+			stripped = append(stripped, content[position:chunkEnd]...)
+		}
+		position = chunkEnd
+		if chunkType == pngEndChunk {
+			return stripped, nil
+		}
+	}
+}
+
+// stripWebPMetadata - removes EXIF and XMP chunks from WebP image
+func stripWebPMetadata(content []byte) ([]byte, error) {
+	if len(content) < webpHeaderLen || string(content[:4]) != "RIFF" || string(content[8:12]) != "WEBP" {
+		return nil, ErrInvalidImage
+	}
+
+	stripped := make([]byte, 0, len(content))
+	stripped = append(stripped, content[:webpHeaderLen]...)
+	position := webpHeaderLen
+	for position < len(content) {
+		if position+8 > len(content) {
+			return nil, ErrInvalidImage
+		}
+		// chunk consists of type, data length and data padded to even length
+		dataLen := int(binary.LittleEndian.Uint32(content[position+4:]))
+		chunkEnd := position + 8 + dataLen + dataLen%2
+		if chunkEnd > len(content) || chunkEnd < position {
+			return nil, ErrInvalidImage
+		}
+
+		chunkType := string(content[position : position+4])
+		if !webpMetadataChunks[chunkType] {
+			chunkStart := len(stripped)
+			stripped = append(stripped, content[position:chunkEnd]...)
+			if chunkType == webpExtendedChunk && dataLen > 0 {
+				stripped[chunkStart+8] &^= webpMetadataFlags
+			}
+		}
+		position = chunkEnd
+	}
+
+	binary.LittleEndian.PutUint32(stripped[4:], uint32(len(stripped)-8))
+	return stripped, nil
+}
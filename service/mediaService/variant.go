@@ -0,0 +1,281 @@
+package mediaService
+
+import (
+	"bytes"
+	"errors"
+	"github.com/blinky-z/Blog/models"
+	"image"
+	"image/draw"
+	_ "image/gif" // register GIF decoder for retrieving image size
+	"image/jpeg"
+	"image/png"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+// ImageVariant - resized copy of an uploaded image
+// @Name - name of the variant. It is a part of the variant key
+// @Width - width of the variant. Images are never upscaled, so the variant is generated only for wider images
+// @Square - the variant is cropped to a square, so the variant is generated for images of any size
+type ImageVariant struct {
+	Name   string
+	Width  int
+	Square bool
+}
+
+var (
+	// ThumbnailVariant - small square image for image previews
+	ThumbnailVariant = ImageVariant{Name: "thumb", Width: 320, Square: true}
+	// ResponsiveVariants - variants for displaying the image on screens of different sizes. Sorted by width
+	ResponsiveVariants = []ImageVariant{
+		{Name: "w480", Width: 480},
+		{Name: "w960", Width: 960},
+		{Name: "w1920", Width: 1920},
+	}
+	// AllVariants - all variants generated for the uploaded image
+	AllVariants = append([]ImageVariant{ThumbnailVariant}, ResponsiveVariants...)
+)
+
+const (
+	// MaxImagePixels - max amount of pixels in an image variants are generated for
+	// Decoded images take 4 bytes per pixel, so the amount is limited to protect the server memory
+	MaxImagePixels = 25000000
+	// variantKeySeparator - separates original image key and variant name in the variant key
+	variantKeySeparator = "_"
+	// variantJPEGQuality - quality of the generated JPEG variants
+	variantJPEGQuality = 85
+)
+
+var (
+	// ErrInvalidImage - image content can't be decoded
+	ErrInvalidImage = errors.New("invalid image")
+	// ErrImageTooLarge - image has more pixels than allowed by MaxImagePixels
+	ErrImageTooLarge = errors.New("image is too large")
+)
+
+// variantTypes - image types variants are generated for
+// Animated GIF images would lose the animation and WebP images can't be encoded, so they are served as is
+var variantTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+}
+
+// HasVariants - checks whether variants are generated for the uploaded file
+func HasVariants(media models.Media) bool {
+	return variantTypes[media.ContentType] && media.Width > 0 && media.Height > 0
+}
+
+// VariantsOf - returns variants that should be generated for the uploaded file
+func VariantsOf(media models.Media) []ImageVariant {
+	variants := make([]ImageVariant, 0)
+	if !HasVariants(media) {
+		return variants
+	}
+
+	for _, variant := range AllVariants {
+		if variant.Square || variant.Width < media.Width {
+			variants = append(variants, variant)
+		}
+	}
+	return variants
+}
+
+// HasVariant - checks whether the given variant should be generated for the uploaded file
+func HasVariant(media models.Media, variant ImageVariant) bool {
+	for _, current := range VariantsOf(media) {
+		if current == variant {
+			return true
+		}
+	}
+	return false
+}
+
+// VariantKey - returns storage key of the image variant
+// The variant key keeps the extension of the original image key, e.g. 'abc_w480.jpg' for 'abc.jpg'
+func VariantKey(key string, variant ImageVariant) string {
+	extension := filepath.Ext(key)
+	return strings.TrimSuffix(key, extension) + variantKeySeparator + variant.Name + extension
+}
+
+// ParseVariantKey - parses storage key of the image variant
+// returns storage key of the original image, the variant and boolean indicating whether the key is a variant key
+func ParseVariantKey(key string) (string, ImageVariant, bool) {
+	extension := filepath.Ext(key)
+	base := strings.TrimSuffix(key, extension)
+	separatorIndex := strings.LastIndex(base, variantKeySeparator)
+	if separatorIndex == -1 {
+		return "", ImageVariant{}, false
+	}
+
+	name := base[separatorIndex+len(variantKeySeparator):]
+	for _, variant := range AllVariants {
+		if variant.Name == name {
+			return base[:separatorIndex] + extension, variant, true
+		}
+	}
+	return "", ImageVariant{}, false
+}
+
+// ImageSize - returns size of the image of the given type in pixels
+// ErrInvalidImage error is returned if the image can't be decoded
+// Size of WebP images is unknown and is returned as zero
+func ImageSize(content []byte, contentType string) (int, int, error) {
+	if contentType == "image/webp" {
+		return 0, 0, nil
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return 0, 0, ErrInvalidImage
+	}
+	return config.Width, config.Height, nil
+}
+
+// DecodeImage - decodes the uploaded image for generating its variants
+// returns ErrImageTooLarge error if the image has too many pixels and ErrInvalidImage error if it can't be decoded
+func DecodeImage(content []byte, contentType string) (*image.RGBA, error) {
+	if !variantTypes[contentType] {
+		return nil, ErrInvalidImage
+	}
+	width, height, err := ImageSize(content, contentType)
+	if err != nil {
+		return nil, err
+	}
+	if int64(width)*int64(height) > MaxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	return toRGBA(decoded, decoded.Bounds()), nil
+}
+
+// EncodeVariant - generates the image variant from the decoded image and encodes it into the given type
+func EncodeVariant(decoded *image.RGBA, contentType string, variant ImageVariant) ([]byte, error) {
+	bounds := decoded.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	var resized *image.RGBA
+	if variant.Square {
+		side := width
+		if height < side {
+			side = height
+		}
+		cropped := image.Rect(0, 0, side, side).Add(bounds.Min).
+			Add(image.Pt((width-side)/2, (height-side)/2))
+		if variant.Width < side {
+			side = variant.Width
+		}
+		resized = resize(toRGBA(decoded, cropped), side, side)
+	} else {
+		targetWidth, targetHeight := width, height
+		if variant.Width < width {
+			targetWidth = variant.Width
+			targetHeight = int(math.Max(1, math.Round(float64(height)*float64(variant.Width)/float64(width))))
+		}
+		resized = resize(decoded, targetWidth, targetHeight)
+	}
+
+	var encoded bytes.Buffer
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&encoded, resized, &jpeg.Options{Quality: variantJPEGQuality})
+	case "image/png":
+		err = png.Encode(&encoded, resized)
+	default:
+		err = ErrInvalidImage
+	}
+	return encoded.Bytes(), err
+}
+
+// toRGBA - copies the given rectangle of the image into a new RGBA image with bounds starting at (0, 0)
+func toRGBA(src image.Image, rect image.Rectangle) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, rect.Min, draw.Src)
+	return dst
+}
+
+// contribution - weight of the source pixel in the resized pixel
+type contribution struct {
+	index  int
+	weight float64
+}
+
+// contributions - returns source pixels contributing to every resized pixel along one axis
+// Every resized pixel is the average of the source pixels it covers, which gives smooth results for downscaling
+func contributions(srcSize, dstSize int) [][]contribution {
+	scale := float64(srcSize) / float64(dstSize)
+	result := make([][]contribution, dstSize)
+	for i := range result {
+		start := float64(i) * scale
+		end := start + scale
+		for j := int(start); j < srcSize && float64(j) < end; j++ {
+			if weight := math.Min(end, float64(j+1)) - math.Max(start, float64(j)); weight > 0 {
+				result[i] = append(result[i], contribution{index: j, weight: weight / scale})
+			}
+		}
+	}
+	return result
+}
+
+// resize - scales the image with bounds starting at (0, 0) to the given size
+// Width and height are resized separately, so the intermediate image is kept only for one axis
+func resize(src *image.RGBA, width, height int) *image.RGBA {
+	srcBounds := src.Bounds()
+	if srcBounds.Dx() == width && srcBounds.Dy() == height {
+		return src
+	}
+
+	horizontal := image.NewRGBA(image.Rect(0, 0, width, srcBounds.Dy()))
+	columns := contributions(srcBounds.Dx(), width)
+	for y := 0; y < srcBounds.Dy(); y++ {
+		srcRow := src.Pix[y*src.Stride:]
+		dstRow := horizontal.Pix[y*horizontal.Stride:]
+		for x, pixels := range columns {
+			var sum [4]float64
+			for _, pixel := range pixels {
+				for channel := 0; channel < 4; channel++ {
+					sum[channel] += float64(srcRow[pixel.index*4+channel]) * pixel.weight
+				}
+			}
+			for channel := 0; channel < 4; channel++ {
+				dstRow[x*4+channel] = clampChannel(sum[channel])
+			}
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	rows := contributions(srcBounds.Dy(), height)
+	for y, pixels := range rows {
+		dstRow := dst.Pix[y*dst.Stride:]
+		for x := 0; x < width; x++ {
+			var sum [4]float64
+			for _, pixel := range pixels {
+				srcOffset := pixel.index*horizontal.Stride + x*4
+				for channel := 0; channel < 4; channel++ {
+					sum[channel] += float64(horizontal.Pix[srcOffset+channel]) * pixel.weight
+				}
+			}
+			for channel := 0; channel < 4; channel++ {
+				dstRow[x*4+channel] = clampChannel(sum[channel])
+			}
+		}
+	}
+	return dst
+}
+
+// clampChannel - rounds color channel value to the nearest byte
+func clampChannel(value float64) uint8 {
+	value = math.Round(value)
+	if value < 0 {
+		return 0
+	}
+	if value > 255 {
+		return 255
+	}
+	return uint8(value)
+}
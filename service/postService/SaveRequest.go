@@ -2,13 +2,17 @@ package postService
 
 import (
 	"github.com/blinky-z/Blog/models"
+	"time"
 )
 
 type SaveRequest struct {
 	Title     string
-	Snippet   string
-	Content   string
+	Slug      string
 	ContentMD string
 	Metadata  models.MetaData
 	Tags      []string
+	Status    models.PostStatus
+	PublishAt *time.Time
+	Author    string
+	CoAuthors []string
 }
@@ -2,14 +2,17 @@ package postService
 
 import (
 	"github.com/blinky-z/Blog/models"
+	"time"
 )
 
 type UpdateRequest struct {
 	ID        string
 	Title     string
-	Snippet   string
-	Content   string
+	Slug      string
 	ContentMD string
 	Metadata  models.MetaData
 	Tags      []string
+	Status    models.PostStatus
+	PublishAt *time.Time
+	CoAuthors []string
 }
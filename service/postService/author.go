@@ -0,0 +1,153 @@
+package postService
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/service/tagService"
+	pg "github.com/lib/pq"
+	"strings"
+)
+
+const (
+	// postCoAuthorsInsertFields - fields that should be filled while inserting a new co-author
+	postCoAuthorsInsertFields = "post_id, username, position"
+	// authorPostsCondition - condition that filters posts written by the user passed as $1 argument
+	authorPostsCondition = "(author = $1 or id in (select post_id from post_coauthors where username = $1))"
+)
+
+// savePostCoAuthors - replaces co-authors of the post. Co-authors are ordered as passed to this method
+// Post author is skipped as the author can't be a co-author of the same post
+func savePostCoAuthors(tx *sql.Tx, postID, author string, coAuthors []string) error {
+	if _, err := tx.Exec("delete from post_coauthors where post_id = $1", postID); err != nil {
+		return err
+	}
+
+	query := "insert into post_coauthors (" + postCoAuthorsInsertFields + ") values "
+	args := make([]interface{}, 0, len(coAuthors)*3)
+	position := 1
+	for _, coAuthor := range coAuthors {
+		if coAuthor == author {
+			continue
+		}
+		query += fmt.Sprintf("($%d, $%d, $%d),", len(args)+1, len(args)+2, len(args)+3)
+		args = append(args, postID, coAuthor, position)
+		position++
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(strings.TrimSuffix(query, ","), args...)
+	return err
+}
+
+// GetCoAuthorsByPostID - returns usernames of the post co-authors in their order
+// Querier is sql.DB or sql.Tx, so that co-authors can be read inside of a transaction
+func GetCoAuthorsByPostID(db tagService.Querier, postID string) ([]string, error) {
+	var coAuthors []string
+
+	rows, err := db.Query("select username from post_coauthors where post_id = $1 order by position", postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var coAuthor string
+		if err = rows.Scan(&coAuthor); err != nil {
+			return nil, err
+		}
+		coAuthors = append(coAuthors, coAuthor)
+	}
+
+	return coAuthors, rows.Err()
+}
+
+// getAuthorsOfPosts - retrieves authors of the range of posts
+// returns map of post ID to post authors. Post author goes first, then co-authors
+func getAuthorsOfPosts(db *sql.DB, postIDs []string) (map[string][]models.Author, error) {
+	rows, err := db.Query("select post_authors.post_id, users.username, users.display_name, users.bio, users.avatar_url "+
+		"from (select id as post_id, author as username, 0 as position from posts where id = any($1) and author is not null "+
+		"union all select post_id, username, position from post_coauthors where post_id = any($1)) post_authors "+
+		"join users on users.username = post_authors.username "+
+		"order by post_authors.post_id, post_authors.position", pg.Array(postIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	postAuthors := make(map[string][]models.Author)
+	for rows.Next() {
+		var postID string
+		var author models.Author
+		if err = rows.Scan(&postID, &author.Username, &author.DisplayName, &author.Bio, &author.AvatarURL); err != nil {
+			return nil, err
+		}
+		postAuthors[postID] = append(postAuthors[postID], author)
+	}
+
+	return postAuthors, rows.Err()
+}
+
+func fillAuthors(db *sql.DB, posts []models.Post) ([]models.Post, error) {
+	postIDs := make([]string, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+	postAuthors, err := getAuthorsOfPosts(db, postIDs)
+	if err != nil {
+		return posts, err
+	}
+
+	for postIndex, post := range posts {
+		posts[postIndex].Authors = postAuthors[post.ID]
+	}
+
+	return posts, nil
+}
+
+// fillPostAuthors - retrieves authors of the single post
+func fillPostAuthors(db *sql.DB, post *models.Post) error {
+	postAuthors, err := getAuthorsOfPosts(db, []string{post.ID})
+	if err != nil {
+		return err
+	}
+	post.Authors = postAuthors[post.ID]
+	return nil
+}
+
+// IsDraftOfAuthor - checks if the post is a draft written by the user. Both post author and co-authors are
+// considered as writers of the post
+func IsDraftOfAuthor(db *sql.DB, postID, username string) (bool, error) {
+	var isDraftOfAuthor bool
+	err := db.QueryRow("select exists(select from posts where "+authorPostsCondition+" and id = $2 and status = $3)",
+		username, postID, models.PostStatusDraft).Scan(&isDraftOfAuthor)
+	return isDraftOfAuthor, err
+}
+
+// GetPostsInRangeByAuthor - retrieves all published posts in the given range written by the user
+// Posts the user is a co-author of are included as well
+// the returned slice is sorted by post creation time in descending order
+func GetPostsInRangeByAuthor(db *sql.DB, offset, postsPerPage int, username string) ([]models.Post, error) {
+	return queryPosts(db, "select "+postsAllFieldsWithHtmlContent+" from posts "+
+		"where "+authorPostsCondition+" and "+publishedPostsCondition+" order by date DESC offset $2 limit $3",
+		username, offset, postsPerPage)
+}
+
+// GetPostsInRangeWithAnyStatusByAuthor - retrieves posts in the given range written by the user regardless of
+// their status. Posts the user is a co-author of are included as well
+// the returned slice is sorted by post creation time in descending order
+func GetPostsInRangeWithAnyStatusByAuthor(db *sql.DB, offset, postsPerPage int,
+	username string) ([]models.Post, error) {
+	return queryPosts(db, "select "+postsAllFieldsWithHtmlContent+" from posts "+
+		"where "+authorPostsCondition+" order by date DESC offset $2 limit $3", username, offset, postsPerPage)
+}
+
+// CountPublishedByAuthor - returns amount of published posts written by the user
+func CountPublishedByAuthor(db *sql.DB, username string) (int, error) {
+	var count int
+	err := db.QueryRow("select count(*) from posts where "+authorPostsCondition+" and "+publishedPostsCondition,
+		username).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,221 @@
+package postService
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/service/mediaService"
+	"gopkg.in/russross/blackfriday.v2"
+	"html"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	// cutMarker - separates post snippet from the rest of the post content in markdown
+	cutMarker = "<cut>"
+	// cutMarkerClosingTag - optional closing tag of the cut marker. Some editors close the marker automatically
+	cutMarkerClosingTag = "</cut>"
+)
+
+// ErrNoCutMarker - post markdown does not contain the cut marker
+var ErrNoCutMarker = errors.New("post content has no cut marker")
+
+// markdownExtensions - markdown extensions enabled for post rendering
+const markdownExtensions = blackfriday.CommonExtensions | blackfriday.AutoHeadingIDs | blackfriday.Footnotes
+
+// SplitContent - splits post markdown at the cut marker
+// returns markdown of the snippet, markdown of the rest content and boolean indicating whether the cut marker was found
+func SplitContent(contentMD string) (string, string, bool) {
+	cutIndex := strings.Index(contentMD, cutMarker)
+	if cutIndex == -1 {
+		return "", "", false
+	}
+
+	snippetMD := contentMD[:cutIndex]
+	restMD := strings.TrimPrefix(contentMD[cutIndex+len(cutMarker):], cutMarkerClosingTag)
+	return snippetMD, restMD, true
+}
+
+// responsiveImageSizes - 'sizes' attribute of the uploaded images. Post content is at most 800px wide
+const responsiveImageSizes = "(max-width: 800px) 100vw, 800px"
+
+// mediaImageRenderer - renders markdown into html adding size and responsive variants to the uploaded images
+type mediaImageRenderer struct {
+	*blackfriday.HTMLRenderer
+	// images - uploaded images used in markdown by their URL
+	images map[string]models.Media
+	// imageDepth - depth of the currently rendered image. Images nested into image alt text are rendered as text
+	imageDepth int
+}
+
+// RenderNode - renders markdown node. Uploaded images are rendered with 'srcset' attribute
+func (r *mediaImageRenderer) RenderNode(w io.Writer, node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+	if node.Type != blackfriday.Image || r.Flags&blackfriday.SkipImages != 0 {
+		return r.HTMLRenderer.RenderNode(w, node, entering)
+	}
+	if !entering {
+		r.imageDepth--
+		return r.HTMLRenderer.RenderNode(w, node, entering)
+	}
+
+	r.imageDepth++
+	media, ok := r.images[string(node.LinkData.Destination)]
+	if !ok || r.imageDepth > 1 || media.Width == 0 {
+		return r.HTMLRenderer.RenderNode(w, node, entering)
+	}
+
+	// the default renderer keeps track of the image alt text, so it is called with its output discarded.
+	// Alt text and closing of the tag are rendered by the default renderer
+	r.HTMLRenderer.RenderNode(ioutil.Discard, node, entering)
+	fmt.Fprintf(w, `<img src="%s" width="%d" height="%d"`, html.EscapeString(media.URL), media.Width, media.Height)
+	if srcset := imageSrcset(media); srcset != "" {
+		fmt.Fprintf(w, ` srcset="%s" sizes="%s"`, html.EscapeString(srcset), responsiveImageSizes)
+	}
+	io.WriteString(w, ` alt="`)
+	return blackfriday.GoToNext
+}
+
+// imageSrcset - returns 'srcset' attribute value listing responsive variants and the original image
+// returns empty string if the image has no responsive variants
+func imageSrcset(media models.Media) string {
+	candidates := make([]string, 0)
+	for _, variant := range mediaService.ResponsiveVariants {
+		if mediaService.HasVariant(media, variant) {
+			candidates = append(candidates, fmt.Sprintf("%s%s %dw",
+				models.MediaURLPrefix, mediaService.VariantKey(media.Key, variant), variant.Width))
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	return strings.Join(append(candidates, fmt.Sprintf("%s %dw", media.URL, media.Width)), ", ")
+}
+
+// getUploadedImages - retrieves info of the uploaded images used in the markdown
+// returns uploaded images by their URL
+func getUploadedImages(db *sql.DB, ast *blackfriday.Node) (map[string]models.Media, error) {
+	images := make(map[string]models.Media)
+
+	keys := make([]string, 0)
+	ast.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		destination := string(node.LinkData.Destination)
+		if node.Type == blackfriday.Image && entering && strings.HasPrefix(destination, models.MediaURLPrefix) {
+			keys = append(keys, strings.TrimPrefix(destination, models.MediaURLPrefix))
+		}
+		return blackfriday.GoToNext
+	})
+	// without database images are rendered as plain images
+	if len(keys) == 0 || db == nil {
+		return images, nil
+	}
+
+	media, err := mediaService.GetByKeys(db, keys)
+	if err != nil {
+		return images, err
+	}
+	for _, currentMedia := range media {
+		images[currentMedia.URL] = currentMedia
+	}
+	return images, nil
+}
+
+// renderMarkdown - renders markdown into sanitized html
+// Images uploaded to the media library are rendered with their size and responsive variants
+func renderMarkdown(db *sql.DB, markdown string) (string, error) {
+	// normalize line endings as blackfriday doesn't treat '\r' as a line break
+	markdown = strings.Replace(markdown, "\r\n", "\n", -1)
+	ast := blackfriday.New(blackfriday.WithExtensions(markdownExtensions)).Parse([]byte(markdown))
+
+	images, err := getUploadedImages(db, ast)
+	if err != nil {
+		return "", err
+	}
+	renderer := &mediaImageRenderer{
+		HTMLRenderer: blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{
+			Flags: blackfriday.CommonHTMLFlags,
+		}),
+		images: images,
+	}
+
+	var rendered bytes.Buffer
+	renderer.RenderHeader(&rendered, ast)
+	ast.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		return renderer.RenderNode(&rendered, node, entering)
+	})
+	renderer.RenderFooter(&rendered, ast)
+	return sanitizeContent(rendered.String()), nil
+}
+
+// RenderContent - renders post markdown into html snippet and html content. Both are sanitized
+// Snippet is a part of the post before the cut marker. ErrNoCutMarker error is returned if there is no cut marker
+// Pass nil database to render uploaded images without their size and responsive variants
+func RenderContent(db *sql.DB, contentMD string) (snippet, content string, err error) {
+	snippetMD, restMD, ok := SplitContent(contentMD)
+	if !ok {
+		return "", "", ErrNoCutMarker
+	}
+
+	if snippet, err = renderMarkdown(db, snippetMD); err != nil {
+		return "", "", err
+	}
+	if content, err = renderMarkdown(db, restMD); err != nil {
+		return "", "", err
+	}
+	return snippet, content, nil
+}
+
+// RenderAll - re-renders snippet and content of all posts from their markdown
+// Call this function after changing the markdown renderer or the html sanitizer
+// Posts which html has changed get the update time, so that feeds and sitemap are not served from the client caches
+// returns amount of re-rendered posts and error
+func RenderAll(db *sql.DB) (int, error) {
+	rows, err := db.Query("select id, content_md from posts")
+	if err != nil {
+		return 0, err
+	}
+
+	contents := make(map[string]string)
+	for rows.Next() {
+		var postID, contentMD string
+		if err = rows.Scan(&postID, &contentMD); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		contents[postID] = contentMD
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	renderedCount := 0
+	for postID, contentMD := range contents {
+		snippet, content, err := RenderContent(db, contentMD)
+		if err == ErrNoCutMarker {
+			// posts without cut marker keep their old html
+			continue
+		}
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+
+		if _, err = tx.Exec("update posts set (snippet, content) = ($1, $2), updated = NOW() "+
+			"where id = $3 and (snippet, content) is distinct from ($1, $2)", snippet, content, postID); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		renderedCount++
+	}
+
+	return renderedCount, tx.Commit()
+}
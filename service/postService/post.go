@@ -10,18 +10,45 @@ import (
 
 const (
 	// postsInsertFields - fields that should be filled while inserting a new entity
-	postsInsertFields = "title, snippet, content, content_md, metadata"
+	postsInsertFields = "title, snippet, content, content_md, metadata, status, publish_at, slug"
 	// postsAllFieldsWithHtmlContent - all entity fields with content as html
-	postsAllFieldsWithHtmlContent = "id, title, date, snippet, content, metadata"
+	postsAllFieldsWithHtmlContent = "id, title, date, updated, snippet, content, metadata, status, publish_at, slug"
 	// postsAllFieldsWithMarkdownContent - all entity fields with content as markdown
-	postsAllFieldsWithMarkdownContent = "id, title, date, snippet, content_md, metadata"
+	postsAllFieldsWithMarkdownContent = "id, title, date, updated, snippet, content_md, metadata, status, publish_at, slug"
+	// publishedPostsCondition - condition that filters out posts that are not visible on the public site
+	publishedPostsCondition = "status = '" + string(models.PostStatusPublished) + "'"
 )
 
-// Save - saves a new post
+// scanner - common interface of sql.Row and sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPost - scans post fields listed in postsAllFieldsWithHtmlContent or postsAllFieldsWithMarkdownContent
+func scanPost(row scanner, post *models.Post) error {
+	var metadataAsJSONString string
+	if err := row.Scan(&post.ID, &post.Title, &post.Date, &post.Updated, &post.Snippet, &post.Content, &metadataAsJSONString,
+		&post.Status, &post.PublishAt, &post.Slug); err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(metadataAsJSONString), &post.Metadata)
+}
+
+// Save - saves a new post together with its first revision
+// snippet and content are rendered from the post markdown
+// Slug is generated from the title unless it is requested. Requested slug must not be used by another post,
+// otherwise ErrSlugAlreadyExists error is returned
+// Co-authors should be registered users
 // returns a created post pointed to by 'createdPost' and error
 func Save(db *sql.DB, request *SaveRequest) (*models.Post, error) {
 	createdPost := &models.Post{}
 
+	snippet, content, err := RenderContent(db, request.ContentMD)
+	if err != nil {
+		return createdPost, err
+	}
+
 	encodedMetadata, err := json.Marshal(request.Metadata)
 	if err != nil {
 		return createdPost, err
@@ -32,16 +59,24 @@ func Save(db *sql.DB, request *SaveRequest) (*models.Post, error) {
 		return createdPost, err
 	}
 
-	var metadataAsJSONString string
-	if err = tx.QueryRow("insert into posts ("+postsInsertFields+") values($1, $2, $3, $4, $5) "+
+	slug, err := resolveSlug(tx, request.Slug, "", request.Title, "")
+	if err != nil {
+		tx.Rollback()
+		return createdPost, err
+	}
+
+	// scheduled post is dated by the time it will be published at
+	if err = scanPost(tx.QueryRow("insert into posts ("+postsInsertFields+", author, date) "+
+		"values($1, $2, $3, $4, $5, $6, $7, $8, $9, case when $6 = '"+string(models.PostStatusScheduled)+"' then $7 else NOW() end) "+
 		"RETURNING "+postsAllFieldsWithHtmlContent,
-		request.Title, request.Snippet, request.Content, request.ContentMD, encodedMetadata).
-		Scan(&createdPost.ID, &createdPost.Title, &createdPost.Date, &createdPost.Snippet, &createdPost.Content,
-			&metadataAsJSONString); err != nil {
+		request.Title, snippet, content, request.ContentMD, encodedMetadata, request.Status, request.PublishAt, slug,
+		request.Author),
+		createdPost); err != nil {
+		tx.Rollback()
 		return createdPost, err
 	}
 
-	if err = json.Unmarshal([]byte(metadataAsJSONString), &createdPost.Metadata); err != nil {
+	if err = saveRevision(tx, createdPost.ID, request.Title, request.ContentMD, encodedMetadata); err != nil {
 		tx.Rollback()
 		return createdPost, err
 	}
@@ -52,13 +87,54 @@ func Save(db *sql.DB, request *SaveRequest) (*models.Post, error) {
 		return createdPost, err
 	}
 
+	if err = savePostCoAuthors(tx, createdPost.ID, request.Author, request.CoAuthors); err != nil {
+		tx.Rollback()
+		return createdPost, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return createdPost, err
+	}
+
 	createdPost.Tags = request.Tags
-	return createdPost, tx.Commit()
+	return createdPost, fillPostAuthors(db, createdPost)
 }
 
 // Update - updates post
+// snippet and content are rendered from the post markdown. Every update saves a new post revision
+// Slug is kept unless a new one is requested. Old slug is saved to redirect old URLs
+// returns ErrSlugAlreadyExists error if requested slug is used by another post
+// if post does not exist, err.SqlNoRows error will be returned
+// Post is re-dated when it gets published or scheduled
+// Co-authors are replaced with the requested ones
 // returns an updated post pointed to by 'updatedPost' and error
 func Update(db *sql.DB, request *UpdateRequest) (*models.Post, error) {
+	snippet, content, err := RenderContent(db, request.ContentMD)
+	if err != nil {
+		return &models.Post{}, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return &models.Post{}, err
+	}
+
+	updatedPost, err := update(tx, request, snippet, content)
+	if err != nil {
+		tx.Rollback()
+		return updatedPost, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return updatedPost, err
+	}
+
+	return updatedPost, fillPostAuthors(db, updatedPost)
+}
+
+// update - updates post in the given transaction with the already rendered snippet and content
+// Transaction should be rolled back by the caller if error is returned
+func update(tx *sql.Tx, request *UpdateRequest, snippet, content string) (*models.Post, error) {
 	updatedPost := &models.Post{}
 
 	encodedMetadata, err := json.Marshal(request.Metadata)
@@ -66,92 +142,116 @@ func Update(db *sql.DB, request *UpdateRequest) (*models.Post, error) {
 		return updatedPost, err
 	}
 
-	tx, err := db.Begin()
-	if err != nil {
+	var currentSlug, author sql.NullString
+	if err = tx.QueryRow("select slug, author from posts where id = $1 for update", request.ID).
+		Scan(&currentSlug, &author); err != nil {
 		return updatedPost, err
 	}
 
-	var metadataAsJSONString string
-	if err = tx.QueryRow("UPDATE posts SET ("+postsInsertFields+") = ($1, $2, $3, $4, $5) "+
-		"WHERE id = $6 RETURNING "+postsAllFieldsWithHtmlContent,
-		request.Title, request.Snippet, request.Content, request.ContentMD, encodedMetadata, request.ID).
-		Scan(&updatedPost.ID, &updatedPost.Title, &updatedPost.Date, &updatedPost.Snippet, &updatedPost.Content,
-			&metadataAsJSONString); err != nil {
+	slug, err := resolveSlug(tx, request.Slug, currentSlug.String, request.Title, request.ID)
+	if err != nil {
 		return updatedPost, err
 	}
 
-	if err = json.Unmarshal([]byte(metadataAsJSONString), &updatedPost.Metadata); err != nil {
-		tx.Rollback()
+	if err = scanPost(tx.QueryRow("UPDATE posts SET ("+postsInsertFields+") = ($1, $2, $3, $4, $5, $6, $7, $8), "+
+		"date = case "+
+		"when $6 = '"+string(models.PostStatusScheduled)+"' then $7 "+
+		"when $6 = '"+string(models.PostStatusPublished)+"' and not "+publishedPostsCondition+" then NOW() "+
+		"else date end, "+
+		"updated = NOW() "+
+		"WHERE id = $9 RETURNING "+postsAllFieldsWithHtmlContent,
+		request.Title, snippet, content, request.ContentMD, encodedMetadata, request.Status, request.PublishAt,
+		slug, request.ID), updatedPost); err != nil {
 		return updatedPost, err
 	}
 
-	err = tagService.SavePostTags(tx, updatedPost.ID, request.Tags)
-	if err != nil {
-		tx.Rollback()
+	if err = saveSlugHistory(tx, updatedPost.ID, currentSlug.String, slug); err != nil {
 		return updatedPost, err
 	}
 
-	updatedPost.Tags = request.Tags
-	return updatedPost, tx.Commit()
-}
+	if err = saveRevision(tx, updatedPost.ID, request.Title, request.ContentMD, encodedMetadata); err != nil {
+		return updatedPost, err
+	}
 
-// DeleteByID - deletes post from database
-func DeleteByID(db *sql.DB, postID string) error {
-	tx, err := db.Begin()
-	if err != nil {
-		return err
+	if err = tagService.SavePostTags(tx, updatedPost.ID, request.Tags); err != nil {
+		return updatedPost, err
 	}
 
-	if _, err := tx.Exec("DELETE FROM posts WHERE id = $1", postID); err != nil {
-		return err
+	if err = savePostCoAuthors(tx, updatedPost.ID, author.String, request.CoAuthors); err != nil {
+		return updatedPost, err
 	}
 
-	if err = tagService.DeletePostTags(tx, postID); err != nil {
-		tx.Rollback()
-		return err
+	// post might be unpublished, so that it leaves the site without a change time of its own
+	if _, err = tx.Exec(touchContentQuery); err != nil {
+		return updatedPost, err
 	}
 
-	return tx.Commit()
+	updatedPost.Tags = request.Tags
+	return updatedPost, nil
 }
 
-// GetByID - retrieves post with the given ID
-// if post does not exist, err.SqlNoRows error will be returned
+// DeleteByID - deletes post from database
+// Tags, revisions, old slugs, co-authors and comments of the post are deleted by the database cascades
+// Deletion time is remembered as the content change time
+func DeleteByID(db *sql.DB, postID string) error {
+	_, err := db.Exec("with deleted as (DELETE FROM posts WHERE id = $1 RETURNING id) "+
+		touchContentQuery+" where exists(select from deleted)", postID)
+	return err
+}
+
+// GetByID - retrieves published post with the given ID
+// if post does not exist or is not published yet, err.SqlNoRows error will be returned
 func GetByID(db *sql.DB, postID string) (models.Post, error) {
 	var post models.Post
 
-	var metadataAsJSONString string
-	if err := db.QueryRow("select "+postsAllFieldsWithHtmlContent+" from posts where id = $1", postID).
-		Scan(&post.ID, &post.Title, &post.Date, &post.Snippet, &post.Content, &metadataAsJSONString); err != nil {
+	if err := scanPost(db.QueryRow("select "+postsAllFieldsWithHtmlContent+" from posts "+
+		"where id = $1 and "+publishedPostsCondition, postID), &post); err != nil {
 		return post, err
 	}
 
-	err := json.Unmarshal([]byte(metadataAsJSONString), &post.Metadata)
-	if err != nil {
+	if tags, err := tagService.GetAllByPostID(db, postID); err != nil {
 		return post, err
+	} else {
+		post.Tags = tags
 	}
 
-	if tags, err := tagService.GetAllByPostID(db, postID); err != nil {
+	return post, fillPostAuthors(db, &post)
+}
+
+// GetBySlug - retrieves published post with the given slug
+// if post does not exist or is not published yet, err.SqlNoRows error will be returned
+func GetBySlug(db *sql.DB, slug string) (models.Post, error) {
+	var post models.Post
+
+	if err := scanPost(db.QueryRow("select "+postsAllFieldsWithHtmlContent+" from posts "+
+		"where slug = $1 and "+publishedPostsCondition, slug), &post); err != nil {
+		return post, err
+	}
+
+	if tags, err := tagService.GetAllByPostID(db, post.ID); err != nil {
 		return post, err
 	} else {
 		post.Tags = tags
 	}
 
-	return post, nil
+	return post, fillPostAuthors(db, &post)
+}
+
+// ExistsPublishedByID - checks if published post with the given ID exists
+func ExistsPublishedByID(db *sql.DB, postID string) (bool, error) {
+	var exists bool
+	err := db.QueryRow("select exists(select from posts where id = $1 and "+publishedPostsCondition+")", postID).
+		Scan(&exists)
+	return exists, err
 }
 
-// GetByIDWithMarkdownContent - retrieves post with the given ID, the content as markdown
+// GetByIDWithMarkdownContent - retrieves post with the given ID in any status, the content as markdown
 // if post does not exist, err.SqlNoRows error will be returned
 func GetByIDWithMarkdownContent(db *sql.DB, postID string) (models.Post, error) {
 	var post models.Post
 
-	var metadataAsJSONString string
-	if err := db.QueryRow("select "+postsAllFieldsWithMarkdownContent+" from posts where id = $1", postID).
-		Scan(&post.ID, &post.Title, &post.Date, &post.Snippet, &post.Content, &metadataAsJSONString); err != nil {
-		return post, err
-	}
-
-	err := json.Unmarshal([]byte(metadataAsJSONString), &post.Metadata)
-	if err != nil {
+	if err := scanPost(db.QueryRow("select "+postsAllFieldsWithMarkdownContent+" from posts where id = $1", postID),
+		&post); err != nil {
 		return post, err
 	}
 
@@ -161,7 +261,7 @@ func GetByIDWithMarkdownContent(db *sql.DB, postID string) (models.Post, error)
 		post.Tags = tags
 	}
 
-	return post, nil
+	return post, fillPostAuthors(db, &post)
 }
 
 func fillTags(db *sql.DB, posts []models.Post) ([]models.Post, error) {
@@ -181,69 +281,105 @@ func fillTags(db *sql.DB, posts []models.Post) ([]models.Post, error) {
 	return posts, nil
 }
 
-// TODO: тесты
-// GetPostsInRangeByTag - retrieves all posts in the given range with the given tag
-// the returned slice is sorted by post creation time in descending order
-func GetPostsInRangeByTag(db *sql.DB, offset, postsPerPage int, tag string) ([]models.Post, error) {
+// queryPosts - retrieves posts with the given query. Query should select postsAllFieldsWithHtmlContent fields
+func queryPosts(db *sql.DB, query string, args ...interface{}) ([]models.Post, error) {
 	var posts []models.Post
 
-	postIds, err := tagService.GetAllPostIDsByTag(db, tag)
-	if err != nil {
-		return posts, err
-	}
-
-	rows, err := db.Query("select "+postsAllFieldsWithHtmlContent+" from posts where id = any($1) order by date DESC offset $2 limit $3",
-		pg.Array(postIds), offset, postsPerPage)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return posts, err
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		var currentPost models.Post
-		var metadataAsJSONString string
-		if err = rows.Scan(&currentPost.ID, &currentPost.Title, &currentPost.Date,
-			&currentPost.Snippet, &currentPost.Content, &metadataAsJSONString); err != nil {
-			return posts, err
-		}
-
-		if err = json.Unmarshal([]byte(metadataAsJSONString), &currentPost.Metadata); err != nil {
+		if err = scanPost(rows, &currentPost); err != nil {
 			return posts, err
 		}
 
 		posts = append(posts, currentPost)
 	}
+	if err = rows.Err(); err != nil {
+		return posts, err
+	}
 
-	return fillTags(db, posts)
+	if posts, err = fillTags(db, posts); err != nil {
+		return posts, err
+	}
+	return fillAuthors(db, posts)
 }
 
 // TODO: тесты
-// GetPostsInRange - retrieves all posts in the given range
+// GetPostsInRangeByTag - retrieves all published posts in the given range with the given tag
+// the returned slice is sorted by post creation time in descending order
+func GetPostsInRangeByTag(db *sql.DB, offset, postsPerPage int, tag string) ([]models.Post, error) {
+	postIds, err := tagService.GetAllPostIDsByTag(db, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return queryPosts(db, "select "+postsAllFieldsWithHtmlContent+" from posts "+
+		"where id = any($1) and "+publishedPostsCondition+" order by date DESC offset $2 limit $3",
+		pg.Array(postIds), offset, postsPerPage)
+}
+
+// TODO: тесты
+// GetPostsInRange - retrieves all published posts in the given range
 // Range is described by page and entities per page args
 // returns slice which len is equal to `postsPerPage` and error
 // the returned slice is sorted by post creation time in descending order
 func GetPostsInRange(db *sql.DB, offset, postsPerPage int) ([]models.Post, error) {
-	var posts []models.Post
-
-	rows, err := db.Query("select "+postsAllFieldsWithHtmlContent+" from posts order by date DESC offset $1 limit $2",
+	return queryPosts(db, "select "+postsAllFieldsWithHtmlContent+" from posts "+
+		"where "+publishedPostsCondition+" order by date DESC offset $1 limit $2",
 		offset, postsPerPage)
+}
+
+// CountPublished - returns amount of all published posts
+func CountPublished(db *sql.DB) (int, error) {
+	var count int
+	err := db.QueryRow("select count(*) from posts where " + publishedPostsCondition).Scan(&count)
+	return count, err
+}
+
+// CountPublishedByTag - returns amount of published posts with the given tag
+func CountPublishedByTag(db *sql.DB, tag string) (int, error) {
+	var count int
+	err := db.QueryRow("select count(*) from posts "+
+		"join post_tags on post_tags.post_id = posts.id "+
+		"join tags on tags.tag_id = post_tags.tag_id "+
+		"where tags.tag = $1 and posts."+publishedPostsCondition, tag).Scan(&count)
+	return count, err
+}
+
+// GetTagsWithPublishedPostsCount - retrieves all tags together with amount of published posts with each tag
+// the returned slice is sorted by tag name
+func GetTagsWithPublishedPostsCount(db *sql.DB) ([]models.TagWithPostsCount, error) {
+	tags := make([]models.TagWithPostsCount, 0)
+
+	rows, err := db.Query("select tags.tag_id, tags.tag, count(posts.id) from tags " +
+		"left join post_tags on post_tags.tag_id = tags.tag_id " +
+		"left join posts on posts.id = post_tags.post_id and posts." + publishedPostsCondition + " " +
+		"group by tags.tag_id, tags.tag order by tags.tag")
 	if err != nil {
-		return posts, err
+		return tags, err
 	}
+	defer rows.Close()
 
 	for rows.Next() {
-		var currentPost models.Post
-		var metadataAsJSONString string
-		if err = rows.Scan(&currentPost.ID, &currentPost.Title, &currentPost.Date,
-			&currentPost.Snippet, &currentPost.Content, &metadataAsJSONString); err != nil {
-			return posts, err
+		var tag models.TagWithPostsCount
+		if err = rows.Scan(&tag.ID, &tag.Name, &tag.PostsCount); err != nil {
+			return tags, err
 		}
-
-		if err = json.Unmarshal([]byte(metadataAsJSONString), &currentPost.Metadata); err != nil {
-			return posts, err
-		}
-
-		posts = append(posts, currentPost)
+		tags = append(tags, tag)
 	}
 
-	return fillTags(db, posts)
+	return tags, rows.Err()
+}
+
+// GetPostsInRangeWithAnyStatus - retrieves posts in the given range regardless of their status
+// Use it for admin dashboard only
+// the returned slice is sorted by post creation time in descending order
+func GetPostsInRangeWithAnyStatus(db *sql.DB, offset, postsPerPage int) ([]models.Post, error) {
+	return queryPosts(db, "select "+postsAllFieldsWithHtmlContent+" from posts order by date DESC offset $1 limit $2",
+		offset, postsPerPage)
 }
@@ -0,0 +1,66 @@
+package postService
+
+import (
+	"database/sql"
+	"github.com/blinky-z/Blog/models"
+	"log"
+	"time"
+)
+
+// PublishScheduled - publishes all scheduled posts which publish time has come
+// Published post is dated by the time it was scheduled to be published at
+// returns amount of published posts and error
+func PublishScheduled(db *sql.DB) (int64, error) {
+	result, err := db.Exec("update posts set status = $1, date = publish_at, updated = publish_at "+
+		"where status = $2 and publish_at <= NOW()",
+		models.PostStatusPublished, models.PostStatusScheduled)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// getNextPublishTime - returns the nearest publish time among all scheduled posts
+// returns nil if there are no scheduled posts
+func getNextPublishTime(db *sql.DB) (*time.Time, error) {
+	var nextPublishTime *time.Time
+	err := db.QueryRow("select min(publish_at) from posts where status = $1", models.PostStatusScheduled).
+		Scan(&nextPublishTime)
+	return nextPublishTime, err
+}
+
+// RunScheduledPublisher - publishes scheduled posts at the time they are scheduled to
+// Publisher wakes up at the nearest publish time, but at least once per 'checkInterval' to catch posts that
+// were scheduled after it went to sleep
+// This function blocks until 'stop' channel is closed
+func RunScheduledPublisher(db *sql.DB, checkInterval time.Duration, stop <-chan struct{}, logInfo, logError *log.Logger) {
+	for {
+		publishedCount, err := PublishScheduled(db)
+		if err != nil {
+			logError.Printf("Error publishing scheduled posts: %s", err)
+		} else if publishedCount != 0 {
+			logInfo.Printf("Scheduled posts published. Published posts count: %d", publishedCount)
+		}
+
+		sleepDuration := checkInterval
+		if nextPublishTime, err := getNextPublishTime(db); err != nil {
+			logError.Printf("Error retrieving next publish time of scheduled posts: %s", err)
+		} else if nextPublishTime != nil {
+			if untilNextPublish := time.Until(*nextPublishTime); untilNextPublish < sleepDuration {
+				sleepDuration = untilNextPublish
+			}
+		}
+		// don't spin if some scheduled post can't be published
+		if sleepDuration < time.Second {
+			sleepDuration = time.Second
+		}
+
+		timer := time.NewTimer(sleepDuration)
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package postService
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/service/tagService"
+	"github.com/pmezard/go-difflib/difflib"
+	"strings"
+	"time"
+)
+
+const (
+	// revisionsAllFields - all post revision fields
+	revisionsAllFields = "id, post_id, date, title, content_md, metadata"
+	// revisionsDiffContextLines - number of unchanged lines shown around each change in the diff
+	revisionsDiffContextLines = 3
+)
+
+// saveRevision - saves the current version of the post as a new revision
+func saveRevision(tx *sql.Tx, postID, title, contentMD string, encodedMetadata []byte) error {
+	_, err := tx.Exec("insert into post_revisions (post_id, title, content_md, metadata) values ($1, $2, $3, $4)",
+		postID, title, contentMD, encodedMetadata)
+	return err
+}
+
+func scanRevision(row scanner, revision *models.PostRevision) error {
+	var metadataAsJSONString string
+	if err := row.Scan(&revision.ID, &revision.PostID, &revision.Date, &revision.Title, &revision.ContentMD,
+		&metadataAsJSONString); err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(metadataAsJSONString), &revision.Metadata)
+}
+
+// GetRevisionsByPostID - retrieves all revisions of the given post
+// the returned slice is sorted by revision creation time in descending order
+func GetRevisionsByPostID(db *sql.DB, postID string) ([]models.PostRevision, error) {
+	revisions := make([]models.PostRevision, 0)
+
+	rows, err := db.Query("select "+revisionsAllFields+" from post_revisions where post_id = $1 "+
+		"order by date DESC, id DESC", postID)
+	if err != nil {
+		return revisions, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var revision models.PostRevision
+		if err = scanRevision(rows, &revision); err != nil {
+			return revisions, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+// GetRevisionByID - retrieves revision of the given post with the given revision ID
+// if revision does not exist or belongs to another post, err.SqlNoRows error will be returned
+func GetRevisionByID(db *sql.DB, postID, revisionID string) (models.PostRevision, error) {
+	var revision models.PostRevision
+
+	err := scanRevision(db.QueryRow("select "+revisionsAllFields+" from post_revisions "+
+		"where id = $1 and post_id = $2", revisionID, postID), &revision)
+	return revision, err
+}
+
+// revisionText - returns text of the revision that is compared in diff. Title goes first, then the markdown
+func revisionText(revision models.PostRevision) string {
+	return "# " + revision.Title + "\n\n" + revision.ContentMD
+}
+
+// DiffRevisions - returns unified diff between two revisions of the post
+func DiffRevisions(from, to models.PostRevision) (string, error) {
+	// normalize line endings so that they don't show up in the diff
+	fromText := strings.Replace(revisionText(from), "\r\n", "\n", -1)
+	toText := strings.Replace(revisionText(to), "\r\n", "\n", -1)
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(fromText),
+		FromFile: fmt.Sprintf("revision %s", from.ID),
+		FromDate: from.Date.Format(time.RFC3339),
+		B:        difflib.SplitLines(toText),
+		ToFile:   fmt.Sprintf("revision %s", to.ID),
+		ToDate:   to.Date.Format(time.RFC3339),
+		Context:  revisionsDiffContextLines,
+	})
+}
+
+// RestoreRevision - sets title, content and metadata of the given revision as the current post version
+// Post slug, tags, co-authors and status are left unchanged. Restoring saves a new revision as any other update does
+// Current post is read and updated in one transaction, so concurrent updates are not overwritten
+// if revision does not exist or belongs to another post, err.SqlNoRows error will be returned
+func RestoreRevision(db *sql.DB, postID, revisionID string) (*models.Post, error) {
+	revision, err := GetRevisionByID(db, postID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	snippet, content, err := RenderContent(db, revision.ContentMD)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	request := &UpdateRequest{
+		ID:        postID,
+		Title:     revision.Title,
+		ContentMD: revision.ContentMD,
+		Metadata:  revision.Metadata,
+	}
+	var slug sql.NullString
+	if err = tx.QueryRow("select slug, status, publish_at from posts where id = $1 for update", postID).
+		Scan(&slug, &request.Status, &request.PublishAt); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	request.Slug = slug.String
+
+	if request.Tags, err = tagService.GetAllByPostID(tx, postID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if request.CoAuthors, err = GetCoAuthorsByPostID(tx, postID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	restoredPost, err := update(tx, request, snippet, content)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return restoredPost, fillPostAuthors(db, restoredPost)
+}
@@ -0,0 +1,42 @@
+package postService
+
+import (
+	"github.com/microcosm-cc/bluemonday"
+	"regexp"
+)
+
+// contentPolicy - allowlist of html elements and attributes allowed in post snippet and content
+// It allows common user generated content and the markup produced by the markdown renderer:
+// responsive images, highlighted code blocks and footnotes. Scripts, styles, frames and event handlers are removed
+var contentPolicy = newContentPolicy()
+
+// headlinePolicy - allows only highlighting of the found words in search headlines
+var headlinePolicy = newHeadlinePolicy()
+
+func newContentPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	// posts are written by the blog authors, so their links are followed
+	policy.RequireNoFollowOnLinks(false)
+	policy.AllowAttrs("srcset").Matching(regexp.MustCompile(`^[^\s,]+ \d+w(, [^\s,]+ \d+w)*$`)).OnElements("img")
+	policy.AllowAttrs("sizes").Matching(regexp.MustCompile(`^[\w\s(),:.-]+$`)).OnElements("img")
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^footnote(s|-ref)$`)).OnElements("div", "sup")
+	return policy
+}
+
+func newHeadlinePolicy() *bluemonday.Policy {
+	policy := bluemonday.NewPolicy()
+	policy.AllowElements("mark")
+	return policy
+}
+
+// sanitizeContent - removes html elements and attributes not allowed in posts from the rendered html
+func sanitizeContent(html string) string {
+	return contentPolicy.Sanitize(html)
+}
+
+// SanitizeHeadline - removes all html elements except <mark> from the search headline
+// Text of the headline should have html entities escaped
+func SanitizeHeadline(headline string) string {
+	return headlinePolicy.Sanitize(headline)
+}
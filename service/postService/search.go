@@ -0,0 +1,76 @@
+package postService
+
+import (
+	"database/sql"
+	"github.com/blinky-z/Blog/models"
+)
+
+const (
+	// searchQuery - tsquery built from user input. Query is parsed with both russian and english configurations,
+	// since posts are written in both languages
+	searchQuery = "(websearch_to_tsquery('russian', $1) || websearch_to_tsquery('english', $1))"
+	// searchHeadlineOptions - options of highlighted fragments of found posts
+	searchHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2, " +
+		"FragmentDelimiter=\" ... \""
+)
+
+// extraFieldsScanner - scans additional fields selected after the post fields
+type extraFieldsScanner struct {
+	scanner
+	extraDest []interface{}
+}
+
+func (s extraFieldsScanner) Scan(dest ...interface{}) error {
+	return s.scanner.Scan(append(dest, s.extraDest...)...)
+}
+
+// Search - retrieves published posts matching the given search query
+// Query supports web search syntax: quoted phrases, 'or' and '-' to exclude words
+// Posts are matched by title, snippet and content. Title matches are ranked higher than snippet and content ones
+// the returned slice is sorted by relevance in descending order
+func Search(db *sql.DB, query string, offset, limit int) ([]models.PostSearchResult, error) {
+	results := make([]models.PostSearchResult, 0)
+
+	// html tags are removed before building the headline, so that it consists of whole text fragments.
+	// Html entities are left escaped and the headline is sanitized, so it is safe to be rendered as html
+	rows, err := db.Query("select "+postsAllFieldsWithHtmlContent+", "+
+		"ts_headline('russian', regexp_replace(snippet || ' ' || content, '<[^>]*>', ' ', 'g'), query, "+
+		"'"+searchHeadlineOptions+"'), "+
+		"ts_rank(search_vector, query) as rank "+
+		"from posts, "+searchQuery+" query "+
+		"where search_vector @@ query and "+publishedPostsCondition+" "+
+		"order by rank DESC, date DESC offset $2 limit $3",
+		query, offset, limit)
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+
+	posts := make([]models.Post, 0)
+	for rows.Next() {
+		var result models.PostSearchResult
+		var post models.Post
+		if err = scanPost(extraFieldsScanner{rows, []interface{}{&result.Headline, &result.Rank}}, &post); err != nil {
+			return results, err
+		}
+		result.Headline = SanitizeHeadline(result.Headline)
+
+		posts = append(posts, post)
+		results = append(results, result)
+	}
+	if err = rows.Err(); err != nil {
+		return results, err
+	}
+
+	if posts, err = fillTags(db, posts); err != nil {
+		return results, err
+	}
+	if posts, err = fillAuthors(db, posts); err != nil {
+		return results, err
+	}
+	for resultIndex := range results {
+		results[resultIndex].Post = posts[resultIndex]
+	}
+
+	return results, nil
+}
@@ -0,0 +1,63 @@
+package postService
+
+import (
+	"database/sql"
+	"time"
+)
+
+// postLastModifiedExpression - time of the last change of the post. Scheduled posts are dated in future until published
+const postLastModifiedExpression = "greatest(posts.date, posts.updated)"
+
+// touchContentQuery - remembers the time of the post update or deletion in the site state
+const touchContentQuery = "update site_state set content_changed = NOW()"
+
+// PageLastModified - represents a site page identified by post slug or tag name together with time of its last change
+type PageLastModified struct {
+	Name         string
+	LastModified time.Time
+}
+
+func queryPagesLastModified(db *sql.DB, query string) ([]PageLastModified, error) {
+	pages := make([]PageLastModified, 0)
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return pages, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var page PageLastModified
+		if err = rows.Scan(&page.Name, &page.LastModified); err != nil {
+			return pages, err
+		}
+		pages = append(pages, page)
+	}
+
+	return pages, rows.Err()
+}
+
+// GetPostsLastModified - retrieves slugs of all published posts together with time of their last change
+// the returned slice is sorted by post creation time in descending order
+func GetPostsLastModified(db *sql.DB) ([]PageLastModified, error) {
+	return queryPagesLastModified(db, "select slug, "+postLastModifiedExpression+" from posts "+
+		"where "+publishedPostsCondition+" order by date DESC")
+}
+
+// GetTagsLastModified - retrieves all tags that have published posts together with time of the last change
+// of their posts
+// the returned slice is sorted by tag name
+func GetTagsLastModified(db *sql.DB) ([]PageLastModified, error) {
+	return queryPagesLastModified(db, "select tags.tag, max("+postLastModifiedExpression+") from tags "+
+		"join post_tags on post_tags.tag_id = tags.tag_id "+
+		"join posts on posts.id = post_tags.post_id "+
+		"where posts."+publishedPostsCondition+" group by tags.tag order by tags.tag")
+}
+
+// GetContentChanged - returns time of the last post update or deletion
+// Deleted and unpublished posts leave no change time, so pages listing posts are not older than this time
+func GetContentChanged(db *sql.DB) (time.Time, error) {
+	var contentChanged time.Time
+	err := db.QueryRow("select content_changed from site_state").Scan(&contentChanged)
+	return contentChanged, err
+}
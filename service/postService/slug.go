@@ -0,0 +1,201 @@
+package postService
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// MaxSlugLen - maximum post slug length
+	MaxSlugLen = 100
+	// defaultSlug - slug of the post which title has no characters suitable for slug
+	defaultSlug = "post"
+)
+
+// ErrSlugAlreadyExists - requested slug is already used by another post
+var ErrSlugAlreadyExists = errors.New("slug is already used by another post")
+
+// cyrillicToLatin - transliteration table for russian letters
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z", 'и': "i",
+	'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+	'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "",
+	'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// isAllDigits - checks if the string consists of digits only
+// Such slugs are not allowed as they can't be distinguished from post IDs
+func isAllDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// MakeSlug - generates url slug from the post title
+// Russian letters are transliterated, any other characters except latin letters and digits are replaced with hyphen
+func MakeSlug(title string) string {
+	var sb strings.Builder
+	pendingHyphen := false
+	for _, r := range strings.ToLower(title) {
+		var part string
+		if latin, ok := cyrillicToLatin[r]; ok {
+			part = latin
+		} else if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			part = string(r)
+		} else {
+			pendingHyphen = true
+			continue
+		}
+		if part == "" {
+			continue
+		}
+
+		if pendingHyphen && sb.Len() != 0 {
+			sb.WriteByte('-')
+		}
+		pendingHyphen = false
+		sb.WriteString(part)
+	}
+
+	slug := sb.String()
+	if len(slug) > MaxSlugLen {
+		slug = strings.TrimRight(slug[:MaxSlugLen], "-")
+	}
+	if slug == "" {
+		return defaultSlug
+	}
+	if isAllDigits(slug) {
+		return defaultSlug + "-" + slug
+	}
+	return slug
+}
+
+// SuffixedSlug - returns the slug with the numeric suffix that makes it unique, e.g. 'title-2'
+// Slug is cut before adding the suffix, so that the result fits MaxSlugLen
+func SuffixedSlug(slug string, suffix int) string {
+	suffixPart := "-" + strconv.Itoa(suffix)
+	if len(slug)+len(suffixPart) > MaxSlugLen {
+		slug = strings.TrimRight(slug[:MaxSlugLen-len(suffixPart)], "-")
+	}
+	return slug + suffixPart
+}
+
+// isSlugTaken - checks if the slug is used by a post other than the given one, now or in the past
+// pass empty post ID to check against all posts
+func isSlugTaken(tx *sql.Tx, slug, postID string) (bool, error) {
+	var taken bool
+	err := tx.QueryRow("select exists(select from posts where slug = $1 and id::text <> $2) "+
+		"or exists(select from post_slugs where slug = $1 and post_id::text <> $2)", slug, postID).Scan(&taken)
+	return taken, err
+}
+
+// resolveSlug - returns slug the post should be saved with
+// Requested slug is used as is, and ErrSlugAlreadyExists error is returned if it is taken by another post
+// If slug is not requested, current slug is kept. Posts without slug get a unique one generated from the title
+func resolveSlug(tx *sql.Tx, requestedSlug, currentSlug, title, postID string) (string, error) {
+	if requestedSlug != "" {
+		taken, err := isSlugTaken(tx, requestedSlug, postID)
+		if err != nil {
+			return "", err
+		}
+		if taken {
+			return "", ErrSlugAlreadyExists
+		}
+		return requestedSlug, nil
+	}
+	if currentSlug != "" {
+		return currentSlug, nil
+	}
+
+	baseSlug := MakeSlug(title)
+	slug := baseSlug
+	for suffix := 2; ; suffix++ {
+		taken, err := isSlugTaken(tx, slug, postID)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return slug, nil
+		}
+		slug = SuffixedSlug(baseSlug, suffix)
+	}
+}
+
+// saveSlugHistory - remembers the old slug of the post so old URLs can be redirected to the new one
+func saveSlugHistory(tx *sql.Tx, postID, oldSlug, newSlug string) error {
+	if oldSlug != "" && oldSlug != newSlug {
+		if _, err := tx.Exec("insert into post_slugs (slug, post_id) values ($1, $2) "+
+			"on conflict (slug) do update set post_id = excluded.post_id", oldSlug, postID); err != nil {
+			return err
+		}
+	}
+
+	// post might get its old slug back
+	_, err := tx.Exec("delete from post_slugs where slug = $1", newSlug)
+	return err
+}
+
+// GetSlugByID - retrieves current slug of the published post with the given ID
+// if post does not exist or is not published yet, err.SqlNoRows error will be returned
+func GetSlugByID(db *sql.DB, postID string) (string, error) {
+	var slug string
+	err := db.QueryRow("select slug from posts where id = $1 and "+publishedPostsCondition, postID).Scan(&slug)
+	return slug, err
+}
+
+// GetSlugByOldSlug - retrieves current slug of the published post which had the given slug before
+// if there is no such post, err.SqlNoRows error will be returned
+func GetSlugByOldSlug(db *sql.DB, oldSlug string) (string, error) {
+	var slug string
+	err := db.QueryRow("select posts.slug from post_slugs join posts on posts.id = post_slugs.post_id "+
+		"where post_slugs.slug = $1 and posts."+publishedPostsCondition, oldSlug).Scan(&slug)
+	return slug, err
+}
+
+// FillMissingSlugs - generates slugs for posts created before slugs were introduced
+// returns amount of updated posts and error
+func FillMissingSlugs(db *sql.DB) (int, error) {
+	rows, err := db.Query("select id, title from posts where slug is null")
+	if err != nil {
+		return 0, err
+	}
+
+	titles := make(map[string]string)
+	for rows.Next() {
+		var postID, title string
+		if err = rows.Scan(&postID, &title); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		titles[postID] = title
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	for postID, title := range titles {
+		slug, err := resolveSlug(tx, "", "", title, postID)
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+		if _, err = tx.Exec("update posts set slug = $1 where id = $2", slug, postID); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	return len(titles), tx.Commit()
+}
@@ -2,6 +2,7 @@ package tagService
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/blinky-z/Blog/models"
 	pg "github.com/lib/pq"
@@ -14,8 +15,26 @@ const (
 	postTagsInsertFields = "post_id, tag_id"
 	tagsInsertFields     = "tag"
 	tagsAllFields        = "tag_id, tag"
+	// uniqueViolationCode - postgres error code of unique constraint violation
+	uniqueViolationCode = "23505"
 )
 
+// Querier - common interface of sql.DB and sql.Tx, so that tags can be read inside of a transaction
+type Querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// ErrTagAlreadyExists - tag with the same name already exists
+var ErrTagAlreadyExists = errors.New("tag already exists")
+
+// translateError - replaces unique constraint violation with ErrTagAlreadyExists error
+func translateError(err error) error {
+	if pgErr, ok := err.(*pg.Error); ok && pgErr.Code == uniqueViolationCode {
+		return ErrTagAlreadyExists
+	}
+	return err
+}
+
 // GetAll - returns all tags sorted by ID in descending order
 func GetAll(db *sql.DB) ([]models.Tag, error) {
 	var tags []models.Tag
@@ -71,44 +90,30 @@ func updatePostTags(tx *sql.Tx, postID string, tags []string) error {
 	return nil
 }
 
-// DeletePostTags - removes all post tags. Usually you want to call this function when deleting a post
-// Notice that tags itself will not be deleted
-func DeletePostTags(tx *sql.Tx, postID string) error {
-	_, err := tx.Exec("delete from post_tags where post_id = $1", postID)
-	return err
-}
-
+// Save - saves a new tag
+// returns ErrTagAlreadyExists error if tag with the same name exists
 func Save(db *sql.DB, tag string) (models.Tag, error) {
 	savedTag := models.Tag{}
 	row := db.QueryRow("insert into tags ("+tagsInsertFields+") values ($1) returning "+tagsAllFields, tag)
 	err := row.Scan(&savedTag.ID, &savedTag.Name)
-	return savedTag, err
+	return savedTag, translateError(err)
 }
 
+// Update - renames the tag
+// returns ErrTagAlreadyExists error if another tag with the same name exists
+// if tag does not exist, err.SqlNoRows error will be returned
 func Update(db *sql.DB, tagID, tag string) (models.Tag, error) {
 	updatedTag := models.Tag{}
 	row := db.QueryRow("update tags set tag = $1 where tag_id = $2 returning "+tagsAllFields, tag, tagID)
 	err := row.Scan(&updatedTag.ID, &updatedTag.Name)
-	return updatedTag, err
+	return updatedTag, translateError(err)
 }
 
-// Delete - deletes a tag by its ID and removes this tag from all tagged posts
+// Delete - deletes a tag by its ID
+// The tag is removed from all tagged posts by the database cascade
 func DeleteByID(db *sql.DB, tagID string) error {
-	tx, err := db.Begin()
-	if err != nil {
-		return err
-	}
-
-	_, err = tx.Exec("delete from tags where tag_id = $1", tagID)
-	if err != nil {
-		return err
-	}
-	_, err = tx.Exec("delete from post_tags where tag_id = $1", tagID)
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
-	return tx.Commit()
+	_, err := db.Exec("delete from tags where tag_id = $1", tagID)
+	return err
 }
 
 // saveNewTags - saves bunch of tags. If tag already exists, it is omitted
@@ -135,7 +140,7 @@ func saveNewTags(tx *sql.Tx, tags []string) error {
 }
 
 // GetAllByPostID - returns all tags of the given post
-func GetAllByPostID(db *sql.DB, postID string) ([]string, error) {
+func GetAllByPostID(db Querier, postID string) ([]string, error) {
 	var tags []string
 
 	rows, err := db.Query("select tag from tags inner join post_tags ON tags.tag_id=post_tags.tag_id where post_id = $1",
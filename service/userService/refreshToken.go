@@ -0,0 +1,95 @@
+package userService
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrInvalidRefreshToken - refresh token does not exist or expired
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused - already rotated or revoked refresh token was used again
+	// It means the token might be stolen, so all refresh tokens of the user get revoked
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// hashRefreshToken - hashes refresh token. Only hashes are stored in database, so leaked database doesn't leak
+// valid tokens. Refresh token is random enough to not require slow hashing
+func hashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// SaveRefreshToken - saves a new refresh token of the user
+// Expired tokens of the user are cleaned up at the same time
+func SaveRefreshToken(db *sql.DB, username, token string, expiresAt time.Time) error {
+	if _, err := db.Exec("delete from refresh_tokens where username = $1 and expires_at < NOW()",
+		username); err != nil {
+		return err
+	}
+
+	_, err := db.Exec("insert into refresh_tokens (username, token_hash, expires_at) values ($1, $2, $3)",
+		username, hashRefreshToken(token), expiresAt)
+	return err
+}
+
+// RotateRefreshToken - revokes the given refresh token and saves a new one in its place
+// returns username of the token owner and error
+// ErrInvalidRefreshToken error is returned if the token does not exist or expired
+// ErrRefreshTokenReused error is returned if the token was already revoked. All tokens of the user are revoked then
+func RotateRefreshToken(db *sql.DB, token, newToken string, newExpiresAt time.Time) (string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+
+	var username string
+	var expiresAt time.Time
+	var revoked bool
+	err = tx.QueryRow("select username, expires_at, revoked from refresh_tokens where token_hash = $1 for update",
+		hashRefreshToken(token)).Scan(&username, &expiresAt, &revoked)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return "", ErrInvalidRefreshToken
+		}
+		return "", err
+	}
+
+	if revoked {
+		if _, err = tx.Exec("update refresh_tokens set revoked = true where username = $1", username); err != nil {
+			tx.Rollback()
+			return "", err
+		}
+		if err = tx.Commit(); err != nil {
+			return "", err
+		}
+		return username, ErrRefreshTokenReused
+	}
+	if time.Now().After(expiresAt) {
+		tx.Rollback()
+		return "", ErrInvalidRefreshToken
+	}
+
+	if _, err = tx.Exec("update refresh_tokens set revoked = true where token_hash = $1",
+		hashRefreshToken(token)); err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	if _, err = tx.Exec("insert into refresh_tokens (username, token_hash, expires_at) values ($1, $2, $3)",
+		username, hashRefreshToken(newToken), newExpiresAt); err != nil {
+		tx.Rollback()
+		return "", err
+	}
+
+	return username, tx.Commit()
+}
+
+// RevokeRefreshToken - revokes the given refresh token
+func RevokeRefreshToken(db *sql.DB, token string) error {
+	_, err := db.Exec("update refresh_tokens set revoked = true where token_hash = $1", hashRefreshToken(token))
+	return err
+}
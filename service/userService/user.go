@@ -2,16 +2,19 @@ package userService
 
 import (
 	"database/sql"
+	"github.com/blinky-z/Blog/models"
+	pg "github.com/lib/pq"
 )
 
 const (
 	// usersInsertFields - fields that should be filled while inserting a new entity
-	usersInsertFields = "username, email, password"
+	usersInsertFields = "username, email, password, role"
 )
 
 // Save - saves a new user in database
-func Save(db *sql.DB, username, email, password string) error {
-	_, err := db.Exec("insert into users ("+usersInsertFields+") values ($1, $2, $3)", username, email, password)
+func Save(db *sql.DB, username, email, password string, role models.UserRole) error {
+	_, err := db.Exec("insert into users ("+usersInsertFields+") values ($1, $2, $3, $4)",
+		username, email, password, role)
 	return err
 }
 
@@ -24,3 +27,81 @@ func ExistsByUsernameOrEmail(db *sql.DB, username, email string) (bool, error) {
 		Scan(&isUserExists)
 	return isUserExists, err
 }
+
+// GetRoleByUsername - returns role of the user
+// if user does not exist, err.SqlNoRows error will be returned
+func GetRoleByUsername(db *sql.DB, username string) (models.UserRole, error) {
+	var role models.UserRole
+	err := db.QueryRow("select role from users where username = $1", username).Scan(&role)
+	return role, err
+}
+
+// UpdateRole - sets a new role of the user
+// if user does not exist, err.SqlNoRows error will be returned
+func UpdateRole(db *sql.DB, username string, role models.UserRole) error {
+	result, err := db.Exec("update users set role = $1 where username = $2", role, username)
+	if err != nil {
+		return err
+	}
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affectedRows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GrantRole - sets the role to all users with the given usernames. Not registered usernames are skipped
+// returns amount of users which role was changed and error
+func GrantRole(db *sql.DB, usernames []string, role models.UserRole) (int64, error) {
+	result, err := db.Exec("update users set role = $1 where username = any($2) and role != $1",
+		role, pg.Array(usernames))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetAuthorByUsername - returns public profile of the user
+// if user does not exist, err.SqlNoRows error will be returned
+func GetAuthorByUsername(db *sql.DB, username string) (models.Author, error) {
+	var author models.Author
+	err := db.QueryRow("select username, display_name, bio, avatar_url from users where username = $1", username).
+		Scan(&author.Username, &author.DisplayName, &author.Bio, &author.AvatarURL)
+	return author, err
+}
+
+// UpdateProfile - updates public profile of the user
+// if user does not exist, err.SqlNoRows error will be returned
+func UpdateProfile(db *sql.DB, username string, request *models.UpdateProfileRequest) (models.Author, error) {
+	var author models.Author
+	err := db.QueryRow("update users set (display_name, bio, avatar_url) = ($1, $2, $3) where username = $4 "+
+		"returning username, display_name, bio, avatar_url",
+		request.DisplayName, request.Bio, request.AvatarURL, username).
+		Scan(&author.Username, &author.DisplayName, &author.Bio, &author.AvatarURL)
+	return author, err
+}
+
+// GetNotExistingUsernames - returns usernames from the given ones that are not registered
+func GetNotExistingUsernames(db *sql.DB, usernames []string) ([]string, error) {
+	notExisting := make([]string, 0)
+
+	rows, err := db.Query("select requested.username from unnest($1::varchar[]) as requested(username) "+
+		"where not exists(select from users where users.username = requested.username)", pg.Array(usernames))
+	if err != nil {
+		return notExisting, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var username string
+		if err = rows.Scan(&username); err != nil {
+			return notExisting, err
+		}
+		notExisting = append(notExisting, username)
+	}
+
+	return notExisting, rows.Err()
+}
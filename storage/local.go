@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// LocalStorage - stores files in a directory on the local filesystem
+type LocalStorage struct {
+	dir string
+}
+
+// NewLocalStorage - creates storage in the given directory. Directory is created if it does not exist
+func NewLocalStorage(dir string) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{dir: dir}, nil
+}
+
+func (storage *LocalStorage) path(key string) (string, error) {
+	if !IsKeyValid(key) {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(storage.dir, key), nil
+}
+
+// Save - writes the file into a temporary file first, so readers never see partially written files
+func (storage *LocalStorage) Save(key string, content []byte, contentType string) error {
+	path, err := storage.path(key)
+	if err != nil {
+		return err
+	}
+
+	tempFile, err := ioutil.TempFile(storage.dir, ".upload-")
+	if err != nil {
+		return err
+	}
+	if _, err = tempFile.Write(content); err != nil {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+		return err
+	}
+	if err = tempFile.Close(); err != nil {
+		os.Remove(tempFile.Name())
+		return err
+	}
+	if err = os.Chmod(tempFile.Name(), 0644); err != nil {
+		os.Remove(tempFile.Name())
+		return err
+	}
+
+	if err = os.Rename(tempFile.Name(), path); err != nil {
+		os.Remove(tempFile.Name())
+		return err
+	}
+	return nil
+}
+
+func (storage *LocalStorage) Open(key string) (io.ReadCloser, error) {
+	path, err := storage.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotExist
+	}
+	return file, err
+}
+
+func (storage *LocalStorage) Delete(key string) error {
+	path, err := storage.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
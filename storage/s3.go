@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// s3Service - service name used in request signature
+	s3Service = "s3"
+	// s3SigningAlgorithm - AWS Signature Version 4 algorithm name
+	s3SigningAlgorithm = "AWS4-HMAC-SHA256"
+	// s3SignedHeaders - headers included in request signature. Headers must be sorted
+	s3SignedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	// s3RequestTimeout - max duration of a single request to S3
+	s3RequestTimeout = 30 * time.Second
+	// s3MaxErrorBodyLen - max length of error response body included in returned error
+	s3MaxErrorBodyLen = 512
+)
+
+// S3Config - connection settings of S3-compatible storage
+// @Endpoint - base URL of the storage, e.g. https://s3.eu-central-1.amazonaws.com or http://localhost:9000 for MinIO
+// @Region - region used in request signature. MinIO accepts any region, 'us-east-1' by default
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// S3Storage - stores files in a bucket of S3-compatible storage, such as Amazon S3 or MinIO
+// Bucket is addressed in path style, so it works with any S3-compatible storage without DNS setup
+// Bucket should exist before the storage is used
+type S3Storage struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+// NewS3Storage - creates storage in the bucket described by the config
+func NewS3Storage(config S3Config) (*S3Storage, error) {
+	if config.Endpoint == "" || config.Bucket == "" || config.AccessKey == "" || config.SecretKey == "" {
+		return nil, errors.New("s3: endpoint, bucket, access key and secret key are required")
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(config.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("s3: unsupported endpoint scheme: %s", endpoint.Scheme)
+	}
+	region := config.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return &S3Storage{
+		endpoint:  endpoint,
+		region:    region,
+		bucket:    config.Bucket,
+		accessKey: config.AccessKey,
+		secretKey: config.SecretKey,
+		client:    &http.Client{Timeout: s3RequestTimeout},
+	}, nil
+}
+
+func (storage *S3Storage) Save(key string, content []byte, contentType string) error {
+	response, err := storage.send(http.MethodPut, key, content, contentType)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return responseError(response)
+	}
+	return nil
+}
+
+func (storage *S3Storage) Open(key string) (io.ReadCloser, error) {
+	response, err := storage.send(http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return response.Body, nil
+	case http.StatusNotFound:
+		response.Body.Close()
+		return nil, ErrNotExist
+	default:
+		defer response.Body.Close()
+		return nil, responseError(response)
+	}
+}
+
+func (storage *S3Storage) Delete(key string) error {
+	response, err := storage.send(http.MethodDelete, key, nil, "")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	// S3 responds with 204 No Content even if the object does not exist, but other storages may respond with 404
+	if response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusOK &&
+		response.StatusCode != http.StatusNotFound {
+		return responseError(response)
+	}
+	return nil
+}
+
+// send - sends signed request for the object with the given key
+func (storage *S3Storage) send(method, key string, content []byte, contentType string) (*http.Response, error) {
+	if !IsKeyValid(key) {
+		return nil, ErrInvalidKey
+	}
+
+	// keys and bucket names contain only characters that don't need escaping in URL path
+	objectURL := *storage.endpoint
+	objectURL.Path = storage.endpoint.Path + "/" + storage.bucket + "/" + key
+
+	request, err := http.NewRequest(method, objectURL.String(), bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	storage.sign(request, content, time.Now())
+
+	return storage.client.Do(request)
+}
+
+// sign - signs the request with AWS Signature Version 4
+// See https://docs.aws.amazon.com/general/latest/gr/sigv4_signing.html
+func (storage *S3Storage) sign(request *http.Request, content []byte, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	payloadHash := sha256Hex(content)
+
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		request.URL.RawQuery,
+		"host:" + request.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		s3SignedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + storage.region + "/" + s3Service + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		s3SigningAlgorithm,
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+storage.secretKey), date)
+	signingKey = hmacSHA256(signingKey, storage.region)
+	signingKey = hmacSHA256(signingKey, s3Service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SigningAlgorithm, storage.accessKey, scope, s3SignedHeaders, signature))
+}
+
+func sha256Hex(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// responseError - creates error describing unexpected response. S3 describes errors in response body
+func responseError(response *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(response.Body, s3MaxErrorBodyLen))
+	return fmt.Errorf("s3: unexpected response status %s: %s", response.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"regexp"
+)
+
+// ErrNotExist - file with the given key does not exist
+var ErrNotExist = errors.New("file does not exist")
+
+// ErrInvalidKey - key contains characters that are not allowed
+var ErrInvalidKey = errors.New("invalid file key")
+
+// keyPattern - lowercase latin letters, digits, dots, hyphens and underscores. Key can't start with a dot
+var keyPattern = regexp.MustCompile("^[a-z0-9_-][a-z0-9._-]*$")
+
+// MaxKeyLen - max length of file key
+const MaxKeyLen = 100
+
+// Storage - stores uploaded files, such as images. Files are addressed by flat keys
+// Keys may contain lowercase latin letters, digits, dots, hyphens and underscores only, so they are safe to use
+// both as file names and in URLs
+type Storage interface {
+	// Save - saves the file under the given key. Existing file with the same key is replaced
+	Save(key string, content []byte, contentType string) error
+	// Open - opens the file for reading. ErrNotExist error is returned if there is no file with the given key
+	Open(key string) (io.ReadCloser, error)
+	// Delete - deletes the file. Deleting a file that does not exist is not an error
+	Delete(key string) error
+}
+
+// IsKeyValid - checks if the key can be used to address a file
+func IsKeyValid(key string) bool {
+	return len(key) <= MaxKeyLen && keyPattern.MatchString(key)
+}
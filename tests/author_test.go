@@ -0,0 +1,223 @@
+package tests
+
+import (
+	"github.com/blinky-z/Blog/handler/restapi"
+	"github.com/blinky-z/Blog/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// registerAuthor - registers a new user with author role and returns the user profile
+// Current auth data should be of the admin and is kept
+func registerAuthor(t *testing.T) models.Author {
+	adminAuthData := getAuthData()
+	defer setAuthData(adminAuthData)
+
+	setAuthData(registerUserWithRole(t, models.RoleAuthor))
+	r := updateProfile(models.UpdateProfileRequest{DisplayName: "Author " + uuid.New().String()})
+	assertNiceResponse(t, r, http.StatusOK)
+	return decodeResponseWithAuthorBody(r.Body).Body
+}
+
+func TestCreatePostRecordsAuthor(t *testing.T) {
+	r := createPost(createPostRequestFactory())
+	assertNiceResponse(t, r, http.StatusCreated)
+	createdPost := decodeResponseWithPostBody(r.Body).Body
+	defer deletePost(createdPost.ID)
+
+	require.Len(t, createdPost.Authors, 1)
+	require.Equal(t, loginUsername, createdPost.Authors[0].Username)
+
+	r = getCertainPost(createdPost.ID)
+	assertNiceResponse(t, r, http.StatusOK)
+	require.Equal(t, createdPost.Authors, decodeResponseWithCertainPostBody(r.Body).Body.Post.Authors)
+}
+
+func TestCreatePostWithCoAuthors(t *testing.T) {
+	firstCoAuthor := registerAuthor(t)
+	secondCoAuthor := registerAuthor(t)
+
+	request := createPostRequestFactory()
+	// post author and duplicates are skipped
+	request.CoAuthors = []string{secondCoAuthor.Username, loginUsername, " " + firstCoAuthor.Username + " ",
+		secondCoAuthor.Username}
+	r := createPost(request)
+	assertNiceResponse(t, r, http.StatusCreated)
+	createdPost := decodeResponseWithPostBody(r.Body).Body
+	defer deletePost(createdPost.ID)
+
+	require.Len(t, createdPost.Authors, 3)
+	require.Equal(t, loginUsername, createdPost.Authors[0].Username)
+	require.Equal(t, secondCoAuthor, createdPost.Authors[1])
+	require.Equal(t, firstCoAuthor, createdPost.Authors[2])
+
+	updateRequest := updatePostRequestFactory()
+	updateRequest.CoAuthors = []string{firstCoAuthor.Username}
+	r = updatePost(createdPost.ID, updateRequest)
+	assertNiceResponse(t, r, http.StatusCreated)
+	updatedPost := decodeResponseWithPostBody(r.Body).Body
+
+	require.Len(t, updatedPost.Authors, 2)
+	require.Equal(t, firstCoAuthor, updatedPost.Authors[1])
+}
+
+func TestCreatePostWithNotExistingCoAuthor(t *testing.T) {
+	request := createPostRequestFactory()
+	request.CoAuthors = []string{uuid.New().String()}
+	r := createPost(request)
+
+	assertErrorResponse(t, r, http.StatusBadRequest, restapi.NoSuchUser)
+}
+
+func TestCreatePostWithTooManyCoAuthors(t *testing.T) {
+	request := createPostRequestFactory()
+	for i := 0; i <= restapi.MaxCoAuthorsAmount; i++ {
+		request.CoAuthors = append(request.CoAuthors, uuid.New().String())
+	}
+	r := createPost(request)
+
+	assertErrorResponse(t, r, http.StatusBadRequest, restapi.InvalidPostCoAuthors)
+}
+
+func TestCoAuthorCanEditDraft(t *testing.T) {
+	adminAuthData := getAuthData()
+	defer setAuthData(adminAuthData)
+
+	authorAuthData := registerUserWithRole(t, models.RoleAuthor)
+	setAuthData(authorAuthData)
+	r := updateProfile(models.UpdateProfileRequest{})
+	assertNiceResponse(t, r, http.StatusOK)
+	author := decodeResponseWithAuthorBody(r.Body).Body
+
+	setAuthData(adminAuthData)
+	draftRequest := createPostRequestFactory()
+	draftRequest.Status = models.PostStatusDraft
+	draftRequest.CoAuthors = []string{author.Username}
+	r = createPost(draftRequest)
+	assertNiceResponse(t, r, http.StatusCreated)
+	draft := decodeResponseWithPostBody(r.Body).Body
+
+	setAuthData(authorAuthData)
+	updateRequest := updatePostRequestFactory()
+	updateRequest.Status = models.PostStatusDraft
+	updateRequest.CoAuthors = []string{author.Username}
+	r = updatePost(draft.ID, updateRequest)
+	assertNiceResponse(t, r, http.StatusCreated)
+
+	setAuthData(adminAuthData)
+	r = deletePost(draft.ID)
+	assertNiceResponse(t, r, http.StatusOK)
+}
+
+func TestUpdateProfile(t *testing.T) {
+	request := models.UpdateProfileRequest{
+		DisplayName: "  Admin " + uuid.New().String() + "  ",
+		Bio:         "Writes about programming",
+		AvatarURL:   "https://example.com/avatar.png",
+	}
+	r := updateProfile(request)
+	assertNiceResponse(t, r, http.StatusOK)
+	updatedProfile := decodeResponseWithAuthorBody(r.Body).Body
+
+	require.Equal(t, loginUsername, updatedProfile.Username)
+	require.Equal(t, strings.TrimSpace(request.DisplayName), updatedProfile.DisplayName)
+	require.Equal(t, request.Bio, updatedProfile.Bio)
+	require.Equal(t, request.AvatarURL, updatedProfile.AvatarURL)
+
+	r = getAuthor(loginUsername)
+	assertNiceResponse(t, r, http.StatusOK)
+	require.Equal(t, updatedProfile, decodeResponseWithAuthorBody(r.Body).Body)
+}
+
+func TestUpdateProfileWithTooLongDisplayName(t *testing.T) {
+	r := updateProfile(models.UpdateProfileRequest{
+		DisplayName: generateRandomAlphanumericString(restapi.MaxDisplayNameLen + 1),
+	})
+
+	assertErrorResponse(t, r, http.StatusBadRequest, restapi.InvalidDisplayName)
+}
+
+func TestUpdateProfileWithTooLongBio(t *testing.T) {
+	r := updateProfile(models.UpdateProfileRequest{Bio: generateRandomAlphanumericString(restapi.MaxBioLen + 1)})
+
+	assertErrorResponse(t, r, http.StatusBadRequest, restapi.InvalidBio)
+}
+
+func TestUpdateProfileWithInvalidAvatarURL(t *testing.T) {
+	for _, avatarURL := range []string{"javascript:alert(1)", "/images/avatar.png", "ftp://example.com/avatar.png"} {
+		r := updateProfile(models.UpdateProfileRequest{AvatarURL: avatarURL})
+
+		assertErrorResponse(t, r, http.StatusBadRequest, restapi.InvalidAvatarURL)
+	}
+}
+
+func TestUpdateProfileWithBadRequestBody(t *testing.T) {
+	r := updateProfile("not a profile")
+
+	assertErrorResponse(t, r, http.StatusBadRequest, restapi.BadRequestBody)
+}
+
+func TestGetNotExistingAuthor(t *testing.T) {
+	r := getAuthor(uuid.New().String())
+
+	assertErrorResponse(t, r, http.StatusNotFound, restapi.NoSuchUser)
+}
+
+func TestGetPostsByAuthor(t *testing.T) {
+	coAuthor := registerAuthor(t)
+
+	request := createPostRequestFactory()
+	request.CoAuthors = []string{coAuthor.Username}
+	r := createPost(request)
+	assertNiceResponse(t, r, http.StatusCreated)
+	createdPost := decodeResponseWithPostBody(r.Body).Body
+	defer deletePost(createdPost.ID)
+
+	draftRequest := createPostRequestFactory()
+	draftRequest.Status = models.PostStatusDraft
+	draftRequest.CoAuthors = []string{coAuthor.Username}
+	r = createPost(draftRequest)
+	assertNiceResponse(t, r, http.StatusCreated)
+	defer deletePost(decodeResponseWithPostBody(r.Body).Body.ID)
+
+	r = getPostsInRangeByAuthor(coAuthor.Username, "0", "10")
+	assertNiceResponse(t, r, http.StatusOK)
+	response := decodeResponseWithRangeOfPostsBody(r.Body)
+
+	// drafts are not visible on the public site
+	require.Len(t, response.Body, 1)
+	require.Equal(t, createdPost.ID, response.Body[0].ID)
+	require.Equal(t, 1, response.Pagination.TotalCount)
+}
+
+func TestAuthorPage(t *testing.T) {
+	author := registerAuthor(t)
+
+	request := createPostRequestFactory()
+	request.CoAuthors = []string{author.Username}
+	r := createPost(request)
+	assertNiceResponse(t, r, http.StatusCreated)
+	createdPost := decodeResponseWithPostBody(r.Body).Body
+	defer deletePost(createdPost.ID)
+
+	r = getPage("/authors/"+author.Username, "")
+	require.Equal(t, http.StatusOK, r.StatusCode)
+	body, _ := ioutil.ReadAll(r.Body)
+	require.True(t, strings.Contains(string(body), author.DisplayName))
+	require.True(t, strings.Contains(string(body), "/posts/"+createdPost.Slug))
+
+	r = getPage("/posts/"+createdPost.Slug, "")
+	require.Equal(t, http.StatusOK, r.StatusCode)
+	body, _ = ioutil.ReadAll(r.Body)
+	require.True(t, strings.Contains(string(body), "/authors/"+author.Username))
+}
+
+func TestNotExistingAuthorPage(t *testing.T) {
+	r := getPage("/authors/"+uuid.New().String(), "")
+
+	require.Equal(t, http.StatusNotFound, r.StatusCode)
+}
@@ -206,3 +206,25 @@ func TestEnsureCommentWithChildsWasNotDeletedButContentReplaced(t *testing.T) {
 		t.Fatalf("Parent comment's content should be replaced with special deletion message, but was: %v", actualParentComment)
 	}
 }
+
+func TestDeletePostDeletesItsComments(t *testing.T) {
+	createPostRequest := createPostRequestFactory()
+	createPostResponse := createPost(createPostRequest)
+	post := decodeResponseWithPostBody(createPostResponse.Body).Body
+
+	parentCommentRequest := createCommentRequestFactory(post.ID)
+	r := createComment(parentCommentRequest)
+	assertNiceResponse(t, r, http.StatusCreated)
+	parentComment := decodeResponseWithCommentBody(r.Body).Body
+
+	replyCommentRequest := createCommentWithParentRequestFactory(post.ID, parentComment.ID)
+	r = createComment(replyCommentRequest)
+	assertNiceResponse(t, r, http.StatusCreated)
+
+	r = deletePost(post.ID)
+	assertNiceResponse(t, r, http.StatusOK)
+
+	comments, err := commentService.GetAllByPostID(db, post.ID)
+	assert.NilError(t, err)
+	assert.Assert(t, len(comments) == 0, "Comments of the deleted post should be deleted, but was: %v", comments)
+}
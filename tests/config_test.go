@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// configFile - config file the server is started with
+var configFile string
+
+const (
+	testSiteTitle   = "Test Blog"
+	testSiteAbout   = "<p>About the test blog</p>"
+	testSiteTagline = "A blog for tests"
+)
+
+// writeConfigFile - writes config file with the given site title
+func writeConfigFile(siteTitle string) {
+	content := fmt.Sprintf(`site:
+  name: Test
+  tagline: %s
+  title: %s
+  description: Test blog description
+  keywords: [test, blog]
+  about: "%s"
+  posts_per_page: 10
+`, testSiteTagline, siteTitle, testSiteAbout)
+	if err := ioutil.WriteFile(configFile, []byte(content), 0644); err != nil {
+		panic(fmt.Sprintf("Error writing config file: %s", err))
+	}
+}
+
+// getPageBody - retrieves page and returns its body
+func getPageBody(t *testing.T, path string) string {
+	r := getPage(path, "")
+	require.Equal(t, http.StatusOK, r.StatusCode)
+	body, _ := ioutil.ReadAll(r.Body)
+	return string(body)
+}
+
+func TestAboutPageContainsConfiguredSite(t *testing.T) {
+	body := getPageBody(t, "/about")
+
+	require.True(t, strings.Contains(body, testSiteAbout))
+	require.True(t, strings.Contains(body, "About | Test - "+testSiteTagline))
+}
+
+func TestSiteSettingsReloadOnConfigFileChange(t *testing.T) {
+	changedTitle := "Changed Test Blog"
+	writeConfigFile(changedTitle)
+	defer writeConfigFile(testSiteTitle)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(getPageBody(t, "/about"), changedTitle) {
+		if time.Now().After(deadline) {
+			t.Fatal("Site settings were not reloaded after the config file change")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
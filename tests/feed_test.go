@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRSSFeedContainsCreatedPost(t *testing.T) {
+	r := createPost(createPostRequestFactory())
+	post := decodeResponseWithPostBody(r.Body).Body
+
+	r = getPage("/feed.xml", "")
+	require.Equal(t, http.StatusOK, r.StatusCode)
+	require.True(t, strings.HasPrefix(r.Header.Get("Content-Type"), "application/rss+xml"))
+
+	body, _ := ioutil.ReadAll(r.Body)
+	require.True(t, strings.Contains(string(body), "/posts/"+post.Slug))
+}
+
+func TestFeedIsNotModifiedSinceLastRequest(t *testing.T) {
+	createPost(createPostRequestFactory())
+
+	for _, feedPath := range []string{"/feed.xml", "/atom.xml", "/feed.json"} {
+		r := getPage(feedPath, "")
+		require.Equal(t, http.StatusOK, r.StatusCode)
+		lastModified := r.Header.Get("Last-Modified")
+		require.NotEmpty(t, lastModified)
+
+		r = getPage(feedPath, lastModified)
+		require.Equal(t, http.StatusNotModified, r.StatusCode)
+	}
+}
+
+func TestFeedIsModifiedAfterPostDeletion(t *testing.T) {
+	createPost(createPostRequestFactory())
+	r := createPost(createPostRequestFactory())
+	newestPost := decodeResponseWithPostBody(r.Body).Body
+
+	r = getPage("/feed.xml", "")
+	require.Equal(t, http.StatusOK, r.StatusCode)
+	lastModified := r.Header.Get("Last-Modified")
+
+	// Last-Modified has one second precision
+	time.Sleep(time.Second)
+	assertNiceResponse(t, deletePost(newestPost.ID), http.StatusOK)
+
+	for _, path := range []string{"/feed.xml", "/sitemap.xml"} {
+		r = getPage(path, lastModified)
+		require.Equal(t, http.StatusOK, r.StatusCode)
+	}
+}
+
+func TestFeedIsModifiedAfterRenderingAllPosts(t *testing.T) {
+	r := createPost(createPostRequestFactory())
+	post := decodeResponseWithPostBody(r.Body).Body
+	defer deletePost(post.ID)
+	// html rendered by the previous renderer version
+	_, err := db.Exec("update posts set content = 'stale content' where id = $1", post.ID)
+	require.NoError(t, err)
+
+	r = getPage("/feed.xml", "")
+	require.Equal(t, http.StatusOK, r.StatusCode)
+	lastModified := r.Header.Get("Last-Modified")
+
+	// Last-Modified has one second precision
+	time.Sleep(time.Second)
+	assertNiceResponse(t, renderAllPosts(), http.StatusOK)
+
+	r = getPage("/feed.xml", lastModified)
+	require.Equal(t, http.StatusOK, r.StatusCode)
+	body, _ := ioutil.ReadAll(r.Body)
+	require.False(t, strings.Contains(string(body), "stale content"))
+}
+
+func TestFeedsContainPostAuthor(t *testing.T) {
+	r := createPost(createPostRequestFactory())
+	post := decodeResponseWithPostBody(r.Body).Body
+	defer deletePost(post.ID)
+
+	for _, feedPath := range []string{"/feed.xml", "/atom.xml", "/feed.json"} {
+		r = getPage(feedPath, "")
+		require.Equal(t, http.StatusOK, r.StatusCode)
+
+		body, _ := ioutil.ReadAll(r.Body)
+		require.True(t, strings.Contains(string(body), post.Authors[0].Name()))
+	}
+}
@@ -1,13 +1,20 @@
 package tests
 
 import (
+	"context"
 	"fmt"
+	"github.com/blinky-z/Blog/config"
+	"github.com/blinky-z/Blog/models"
 	"github.com/blinky-z/Blog/server"
+	"github.com/blinky-z/Blog/service/userService"
 	"github.com/google/uuid"
+	"io/ioutil"
+	"log"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
-	"time"
 )
 
 // performs required initialization and runs tests
@@ -15,23 +22,51 @@ func TestMain(m *testing.M) {
 	loginUsername = uuid.New().String()
 	loginEmail = loginUsername + "@gmail.com"
 	loginPassword = uuid.New().String() + "Z"
-	admins := loginUsername
-	serverPort := "8080"
-	address = "localhost:" + serverPort
+	unclaimedAdminUsername = uuid.New().String()
+	admins := loginUsername + "," + unclaimedAdminUsername
 
 	_ = os.Setenv("JWT_SECRET_KEY", "testSecretKey")
 	_ = os.Setenv("ADMINS", admins)
-	_ = os.Setenv("SERVER_PORT", serverPort)
+	_ = os.Setenv("REGISTRATION_ENABLED", "true")
 
-	go server.RunServer()
-	for {
-		resp, err := http.Get("http://" + address + "/api/hc")
-		if err == nil && resp.StatusCode == http.StatusOK {
-			break
-		}
-		time.Sleep(100 * time.Millisecond)
+	mediaDir, err := ioutil.TempDir("", "blog-media")
+	if err != nil {
+		panic(fmt.Sprintf("Error creating media directory: %s", err))
+	}
+	_ = os.Setenv("STORAGE_BACKEND", "local")
+	_ = os.Setenv("MEDIA_DIR", mediaDir)
+
+	configDir, err := ioutil.TempDir("", "blog-config")
+	if err != nil {
+		panic(fmt.Sprintf("Error creating config directory: %s", err))
+	}
+	configFile = filepath.Join(configDir, "config.yaml")
+	writeConfigFile(testSiteTitle)
+	_ = os.Setenv("CONFIG_FILE", configFile)
+
+	// server is listening on a random port, so the domain is known only after the listener is created
+	testServer := httptest.NewUnstartedServer(nil)
+	address = testServer.Listener.Addr().String()
+	_ = os.Setenv("DOMAIN", "http://"+address)
+
+	configLoader, err := config.NewLoader()
+	if err != nil {
+		panic(fmt.Sprintf("Error reading config: %s", err))
+	}
+	cfg, err := configLoader.Config()
+	if err != nil {
+		panic(fmt.Sprintf("Error reading config: %s", err))
+	}
+	blogServer, err := server.New(cfg)
+	if err != nil {
+		panic(fmt.Sprintf("Error creating server: %s", err))
 	}
-	db = server.Db
+	configLoader.WatchSite(blogServer.Site(),
+		log.New(os.Stdout, "[config] INFO: ", log.Ltime),
+		log.New(os.Stderr, "[config] ERROR: ", log.Ltime))
+	testServer.Config.Handler = blogServer.Handler()
+	testServer.Start()
+	db = blogServer.DB()
 
 	// register a new user for tests
 	{
@@ -39,6 +74,10 @@ func TestMain(m *testing.M) {
 		if r.StatusCode != http.StatusOK {
 			panic(fmt.Sprintf("Error registering user. Received status code was not 200 OK: %d", r.StatusCode))
 		}
+		// registered user gets admin role on the server start like users from admins list do
+		if _, err = userService.GrantRole(db, []string{loginUsername}, models.RoleAdmin); err != nil {
+			panic(fmt.Sprintf("Error granting admin role to user: %s", err))
+		}
 	}
 
 	// login with registered user and save auth data
@@ -51,5 +90,11 @@ func TestMain(m *testing.M) {
 	}
 
 	code := m.Run()
+	testServer.Close()
+	if err = blogServer.Shutdown(context.Background()); err != nil {
+		fmt.Printf("Error shutting down server: %s\n", err)
+	}
+	_ = os.RemoveAll(mediaDir)
+	_ = os.RemoveAll(configDir)
 	os.Exit(code)
 }
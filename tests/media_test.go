@@ -0,0 +1,217 @@
+package tests
+
+import (
+	"bytes"
+	"github.com/blinky-z/Blog/handler/restapi"
+	"github.com/blinky-z/Blog/models"
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/jpeg"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUploadMedia(t *testing.T) {
+	content := generatePNG(16, 16)
+
+	r := uploadMedia("C:\\Users\\admin\\picture.png", content)
+	assertNiceResponse(t, r, http.StatusCreated)
+	media := decodeResponseWithMediaBody(r.Body).Body
+	defer deleteMedia(media.ID)
+
+	require.Equal(t, "picture.png", media.Filename)
+	require.Equal(t, "image/png", media.ContentType)
+	require.Equal(t, int64(len(content)), media.Size)
+	require.Equal(t, 16, media.Width)
+	require.Equal(t, 16, media.Height)
+	require.Equal(t, loginUsername, media.UploadedBy)
+	require.True(t, strings.HasSuffix(media.Key, ".png"))
+	require.Equal(t, models.MediaURLPrefix+media.Key, media.URL)
+	require.Equal(t, strings.TrimSuffix(media.URL, ".png")+"_thumb.png", media.ThumbnailURL)
+
+	r = getPage(media.URL, "")
+	require.Equal(t, http.StatusOK, r.StatusCode)
+	require.Equal(t, "image/png", r.Header.Get("Content-Type"))
+	servedContent, _ := ioutil.ReadAll(r.Body)
+	require.True(t, bytes.Equal(content, servedContent))
+}
+
+func TestServeImageVariants(t *testing.T) {
+	r := uploadMedia("wide.png", generatePNG(1000, 500))
+	assertNiceResponse(t, r, http.StatusCreated)
+	media := decodeResponseWithMediaBody(r.Body).Body
+	defer deleteMedia(media.ID)
+
+	variantURL := func(name string) string {
+		return strings.TrimSuffix(media.URL, ".png") + "_" + name + ".png"
+	}
+	assertVariantSize := func(name string, width, height int) {
+		r := getPage(variantURL(name), "")
+		require.Equal(t, http.StatusOK, r.StatusCode)
+		require.Equal(t, "image/png", r.Header.Get("Content-Type"))
+		config, _, err := image.DecodeConfig(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, width, config.Width)
+		require.Equal(t, height, config.Height)
+	}
+
+	assertVariantSize("thumb", 320, 320)
+	assertVariantSize("w480", 480, 240)
+	assertVariantSize("w960", 960, 480)
+
+	// images are not upscaled
+	r = getPage(variantURL("w1920"), "")
+	require.Equal(t, http.StatusNotFound, r.StatusCode)
+}
+
+func TestServeMissingImageVariant(t *testing.T) {
+	r := uploadMedia("missing-variant.png", generatePNG(600, 600))
+	assertNiceResponse(t, r, http.StatusCreated)
+	media := decodeResponseWithMediaBody(r.Body).Body
+	defer deleteMedia(media.ID)
+
+	variantKey := strings.TrimSuffix(media.Key, ".png") + "_w480.png"
+	require.NoError(t, os.Remove(filepath.Join(os.Getenv("MEDIA_DIR"), variantKey)))
+
+	// missing variant is generated on request
+	r = getPage(models.MediaURLPrefix+variantKey, "")
+	require.Equal(t, http.StatusOK, r.StatusCode)
+	config, _, err := image.DecodeConfig(r.Body)
+	require.NoError(t, err)
+	require.Equal(t, 480, config.Width)
+
+	_, err = os.Stat(filepath.Join(os.Getenv("MEDIA_DIR"), variantKey))
+	require.NoError(t, err)
+}
+
+func TestUploadMediaStripsExif(t *testing.T) {
+	var encoded bytes.Buffer
+	require.NoError(t, jpeg.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 16, 16)), nil))
+	jpegContent := encoded.Bytes()
+
+	exif := []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08\x00\x00GPS 55.7558 37.6173")
+	segment := append([]byte{0xFF, 0xE1, 0, byte(len(exif) + 2)}, exif...)
+	content := append(append(append([]byte{}, jpegContent[:2]...), segment...), jpegContent[2:]...)
+
+	r := uploadMedia("photo.jpg", content)
+	assertNiceResponse(t, r, http.StatusCreated)
+	media := decodeResponseWithMediaBody(r.Body).Body
+	defer deleteMedia(media.ID)
+
+	require.Equal(t, int64(len(jpegContent)), media.Size)
+
+	r = getPage(media.URL, "")
+	require.Equal(t, http.StatusOK, r.StatusCode)
+	servedContent, _ := ioutil.ReadAll(r.Body)
+	require.True(t, bytes.Equal(jpegContent, servedContent))
+}
+
+func TestPostContentContainsResponsiveImages(t *testing.T) {
+	r := uploadMedia("post-image.png", generatePNG(1000, 500))
+	assertNiceResponse(t, r, http.StatusCreated)
+	media := decodeResponseWithMediaBody(r.Body).Body
+	defer deleteMedia(media.ID)
+
+	request := createPostRequestFactory()
+	request.ContentMD += "\n\n![screenshot](" + media.URL + ")"
+	r = createPost(request)
+	assertNiceResponse(t, r, http.StatusCreated)
+	post := decodeResponseWithPostBody(r.Body).Body
+	defer deletePost(post.ID)
+
+	base := strings.TrimSuffix(media.URL, ".png")
+	require.Contains(t, post.Content, `width="1000" height="500"`)
+	require.Contains(t, post.Content,
+		`srcset="`+base+`_w480.png 480w, `+base+`_w960.png 960w, `+media.URL+` 1000w"`)
+	require.Contains(t, post.Content, `alt="screenshot"`)
+}
+
+func TestUploadMediaDetectsTypeByContent(t *testing.T) {
+	r := uploadMedia("image.png", []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"><script>alert(1)</script></svg>"))
+
+	assertErrorResponse(t, r, http.StatusBadRequest, restapi.InvalidMediaType)
+}
+
+func TestUploadTooLargeMedia(t *testing.T) {
+	content := append(generatePNG(16, 16), make([]byte, restapi.MaxMediaSize)...)
+	r := uploadMedia("large.png", content)
+
+	assertErrorResponse(t, r, http.StatusRequestEntityTooLarge, restapi.MediaTooLarge)
+}
+
+func TestUploadMediaWithBadRequestBody(t *testing.T) {
+	r := sendMessage("POST", "http://"+address+"/api/media", "not a multipart form", true)
+
+	assertErrorResponse(t, r, http.StatusBadRequest, restapi.BadRequestBody)
+}
+
+func TestGetMedia(t *testing.T) {
+	r := uploadMedia("first.png", generatePNG(8, 8))
+	assertNiceResponse(t, r, http.StatusCreated)
+	firstMedia := decodeResponseWithMediaBody(r.Body).Body
+	defer deleteMedia(firstMedia.ID)
+
+	r = uploadMedia("second.png", generatePNG(8, 8))
+	assertNiceResponse(t, r, http.StatusCreated)
+	secondMedia := decodeResponseWithMediaBody(r.Body).Body
+	defer deleteMedia(secondMedia.ID)
+
+	r = getMedia("0", "2")
+	assertNiceResponse(t, r, http.StatusOK)
+	response := decodeResponseWithRangeOfMediaBody(r.Body)
+
+	// the most recent files go first
+	require.Len(t, response.Body, 2)
+	require.Equal(t, secondMedia.ID, response.Body[0].ID)
+	require.Equal(t, firstMedia.ID, response.Body[1].ID)
+	require.True(t, response.Pagination.TotalCount >= 2)
+}
+
+func TestGetMediaWithInvalidRange(t *testing.T) {
+	r := getMedia("-1", "10")
+
+	assertErrorResponse(t, r, http.StatusBadRequest, restapi.InvalidMediaRange)
+}
+
+func TestDeleteMedia(t *testing.T) {
+	r := uploadMedia("deleted.png", generatePNG(8, 8))
+	assertNiceResponse(t, r, http.StatusCreated)
+	media := decodeResponseWithMediaBody(r.Body).Body
+
+	r = deleteMedia(media.ID)
+	assertNiceResponse(t, r, http.StatusOK)
+
+	r = getPage(media.URL, "")
+	require.Equal(t, http.StatusNotFound, r.StatusCode)
+
+	r = deleteMedia(media.ID)
+	assertErrorResponse(t, r, http.StatusBadRequest, restapi.NoSuchMedia)
+}
+
+func TestServeNotExistingMedia(t *testing.T) {
+	for _, path := range []string{models.MediaURLPrefix + "notexisting.png", models.MediaURLPrefix + "..%2Frobots.txt"} {
+		r := getPage(path, "")
+		require.Equal(t, http.StatusNotFound, r.StatusCode)
+	}
+}
+
+func TestAuthorCanUploadButNotDeleteMedia(t *testing.T) {
+	adminAuthData := getAuthData()
+	defer setAuthData(adminAuthData)
+
+	setAuthData(registerUserWithRole(t, models.RoleAuthor))
+	r := uploadMedia("author.png", generatePNG(8, 8))
+	assertNiceResponse(t, r, http.StatusCreated)
+	media := decodeResponseWithMediaBody(r.Body).Body
+
+	r = deleteMedia(media.ID)
+	assertErrorResponse(t, r, http.StatusForbidden, restapi.NoPermissions)
+
+	setAuthData(adminAuthData)
+	r = deleteMedia(media.ID)
+	assertNiceResponse(t, r, http.StatusOK)
+}
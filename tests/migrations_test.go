@@ -0,0 +1,24 @@
+package tests
+
+import (
+	"github.com/blinky-z/Blog/migrations"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMigrationsAreAppliedAtStartup(t *testing.T) {
+	statuses, err := migrations.GetStatus(db)
+	require.NoError(t, err)
+	require.NotEmpty(t, statuses)
+
+	for _, status := range statuses {
+		require.NotNil(t, status.AppliedAt, "migration %d_%s is not applied", status.Version, status.Name)
+	}
+}
+
+func TestApplyMigrationsTwice(t *testing.T) {
+	appliedMigrations, err := migrations.Up(db)
+
+	require.NoError(t, err)
+	require.Empty(t, appliedMigrations)
+}
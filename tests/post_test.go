@@ -3,10 +3,14 @@ package tests
 import (
 	"github.com/blinky-z/Blog/handler/restapi"
 	"github.com/blinky-z/Blog/models"
+	"github.com/blinky-z/Blog/service/postService"
+	"github.com/blinky-z/Blog/service/tagService"
 	"github.com/stretchr/testify/require"
 	"net/http"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestPostIntegrationTest(t *testing.T) {
@@ -26,10 +30,10 @@ func TestPostIntegrationTest(t *testing.T) {
 	// Step 2: Get created post and compare it with working
 	{
 		r := getCertainPost(workingPost.ID)
-		resp := decodeResponseWithPostBody(r.Body)
+		resp := decodeResponseWithCertainPostBody(r.Body)
 		assertNiceResponse(t, r, http.StatusOK)
 
-		receivedPost := resp.Body
+		receivedPost := resp.Body.Post
 
 		if !comparePosts(receivedPost, workingPost) {
 			t.Fatalf("Received post does not match created post\nReceived post: %v\nCreated post: %v",
@@ -205,3 +209,263 @@ func TestGetRangeOfPostsGetEmptyPage(t *testing.T) {
 
 	require.Equal(t, 0, len(receivedPosts))
 }
+
+func TestDraftPostIsNotAvailableOnSite(t *testing.T) {
+	request := createPostRequestFactory()
+	request.Status = models.PostStatusDraft
+	r := createPost(request)
+	assertNiceResponse(t, r, http.StatusCreated)
+	post := decodeResponseWithPostBody(r.Body).Body
+
+	r = getCertainPost(post.ID)
+
+	assertErrorResponse(t, r, http.StatusNotFound, restapi.NoSuchPost)
+}
+
+func TestPublishDraftPost(t *testing.T) {
+	createPostRequest := createPostRequestFactory()
+	createPostRequest.Status = models.PostStatusDraft
+	r := createPost(createPostRequest)
+	post := decodeResponseWithPostBody(r.Body).Body
+
+	updatePostRequest := updatePostRequestFactory()
+	updatePostRequest.Status = models.PostStatusPublished
+	r = updatePost(post.ID, updatePostRequest)
+	assertNiceResponse(t, r, http.StatusCreated)
+
+	r = getCertainPost(post.ID)
+
+	assertNiceResponse(t, r, http.StatusOK)
+}
+
+func TestCreatePostWithInvalidStatus(t *testing.T) {
+	request := createPostRequestFactory()
+	request.Status = "archived"
+	r := createPost(request)
+
+	assertErrorResponse(t, r, http.StatusBadRequest, restapi.InvalidPostStatus)
+}
+
+func TestCreateScheduledPostWithoutPublishTime(t *testing.T) {
+	request := createPostRequestFactory()
+	request.Status = models.PostStatusScheduled
+	r := createPost(request)
+
+	assertErrorResponse(t, r, http.StatusBadRequest, restapi.InvalidPostStatus)
+}
+
+func TestScheduledPostIsNotAvailableBeforePublishTime(t *testing.T) {
+	publishAt := time.Now().Add(time.Hour)
+	request := createPostRequestFactory()
+	request.Status = models.PostStatusScheduled
+	request.PublishAt = &publishAt
+	r := createPost(request)
+	assertNiceResponse(t, r, http.StatusCreated)
+	post := decodeResponseWithPostBody(r.Body).Body
+
+	r = getCertainPost(post.ID)
+
+	assertErrorResponse(t, r, http.StatusNotFound, restapi.NoSuchPost)
+}
+
+func TestCreatePostGeneratesSlugFromTitle(t *testing.T) {
+	request := createPostRequestFactory()
+	request.Title = "Привет, мир! Hello " + generateRandomAlphanumericString(restapi.MinPostTitleLen)
+	r := createPost(request)
+	assertNiceResponse(t, r, http.StatusCreated)
+	post := decodeResponseWithPostBody(r.Body).Body
+
+	require.True(t, strings.HasPrefix(post.Slug, "privet-mir-hello-"))
+}
+
+func TestCreatePostsWithDuplicateLongTitle(t *testing.T) {
+	request := createPostRequestFactory()
+	titlePrefix := generateRandomAlphanumericString(postService.MaxSlugLen - 3)
+	request.Title = titlePrefix + " " + generateRandomAlphanumericString(restapi.MinPostTitleLen)
+
+	for _, expectedSlug := range []string{"", strings.ToLower(titlePrefix) + "-2", strings.ToLower(titlePrefix) + "-3"} {
+		r := createPost(request)
+		assertNiceResponse(t, r, http.StatusCreated)
+		post := decodeResponseWithPostBody(r.Body).Body
+		defer deletePost(post.ID)
+
+		require.True(t, len(post.Slug) <= postService.MaxSlugLen)
+		if expectedSlug != "" {
+			require.Equal(t, expectedSlug, post.Slug)
+		}
+	}
+}
+
+func TestUpdatePostKeepsSlug(t *testing.T) {
+	r := createPost(createPostRequestFactory())
+	post := decodeResponseWithPostBody(r.Body).Body
+
+	r = updatePost(post.ID, updatePostRequestFactory())
+	assertNiceResponse(t, r, http.StatusCreated)
+	updatedPost := decodeResponseWithPostBody(r.Body).Body
+
+	require.Equal(t, post.Slug, updatedPost.Slug)
+}
+
+func TestCreatePostWithInvalidSlug(t *testing.T) {
+	request := createPostRequestFactory()
+	request.Slug = "Invalid Slug"
+	r := createPost(request)
+
+	assertErrorResponse(t, r, http.StatusBadRequest, restapi.InvalidPostSlug)
+}
+
+func TestCreatePostWithNumericSlug(t *testing.T) {
+	request := createPostRequestFactory()
+	request.Slug = "12345"
+	r := createPost(request)
+
+	assertErrorResponse(t, r, http.StatusBadRequest, restapi.InvalidPostSlug)
+}
+
+func TestCreatePostWithTakenSlug(t *testing.T) {
+	r := createPost(createPostRequestFactory())
+	post := decodeResponseWithPostBody(r.Body).Body
+
+	request := createPostRequestFactory()
+	request.Slug = post.Slug
+	r = createPost(request)
+
+	assertErrorResponse(t, r, http.StatusBadRequest, restapi.PostSlugAlreadyExists)
+}
+
+func TestCreatePostWithOldSlugOfAnotherPost(t *testing.T) {
+	r := createPost(createPostRequestFactory())
+	post := decodeResponseWithPostBody(r.Body).Body
+
+	// rename the post, so its first slug becomes an old one
+	updatePostRequest := updatePostRequestFactory()
+	updatePostRequest.Slug = strings.ToLower(generateRandomAlphanumericString(restapi.MinPostTitleLen)) + "-renamed"
+	r = updatePost(post.ID, updatePostRequest)
+	assertNiceResponse(t, r, http.StatusCreated)
+
+	request := createPostRequestFactory()
+	request.Slug = post.Slug
+	r = createPost(request)
+
+	assertErrorResponse(t, r, http.StatusBadRequest, restapi.PostSlugAlreadyExists)
+}
+
+func TestSearchPosts(t *testing.T) {
+	uniqueWord := generateRandomAlphanumericString(restapi.MinPostTitleLen)
+	request := createPostRequestFactory()
+	request.Title = "Search test " + uniqueWord
+	r := createPost(request)
+	post := decodeResponseWithPostBody(r.Body).Body
+
+	r = searchPosts(uniqueWord)
+	assertNiceResponse(t, r, http.StatusOK)
+	results := decodeResponseWithSearchResultsBody(r.Body).Body
+
+	require.Len(t, results, 1)
+	require.Equal(t, post.ID, results[0].Post.ID)
+}
+
+func TestSearchDoesNotFindDrafts(t *testing.T) {
+	uniqueWord := generateRandomAlphanumericString(restapi.MinPostTitleLen)
+	request := createPostRequestFactory()
+	request.Title = "Search test " + uniqueWord
+	request.Status = models.PostStatusDraft
+	createPost(request)
+
+	r := searchPosts(uniqueWord)
+	assertNiceResponse(t, r, http.StatusOK)
+	results := decodeResponseWithSearchResultsBody(r.Body).Body
+
+	require.Len(t, results, 0)
+}
+
+func TestSearchWithEmptyQuery(t *testing.T) {
+	r := searchPosts(" ")
+
+	assertErrorResponse(t, r, http.StatusBadRequest, restapi.InvalidSearchQuery)
+}
+
+func TestGetRangeOfPostsReturnsPagination(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		createPost(createPostRequestFactory())
+	}
+
+	r := getPostsInRange("1", "1")
+	assertNiceResponse(t, r, http.StatusOK)
+	pagination := decodeResponseWithRangeOfPostsBody(r.Body).Pagination
+
+	require.Equal(t, 1, pagination.Page)
+	require.Equal(t, 1, pagination.PageSize)
+	require.True(t, pagination.TotalCount >= 3)
+	require.Equal(t, pagination.TotalCount, pagination.TotalPages)
+	require.True(t, strings.Contains(pagination.Next, "page=2"))
+	require.True(t, strings.Contains(pagination.Prev, "page=0"))
+}
+
+func TestGetRangeOfPostsByTag(t *testing.T) {
+	tag := generateRandomAlphanumericString(restapi.MaxTagLen)
+	request := createPostRequestFactory()
+	request.Tags = []string{tag}
+	r := createPost(request)
+	post := decodeResponseWithPostBody(r.Body).Body
+	createPost(createPostRequestFactory())
+
+	r = getPostsInRangeByTag(tag, "0", restapi.DefaultPostsPerPage)
+	assertNiceResponse(t, r, http.StatusOK)
+	resp := decodeResponseWithRangeOfPostsBody(r.Body)
+
+	require.Len(t, resp.Body, 1)
+	require.Equal(t, post.ID, resp.Body[0].ID)
+	require.Equal(t, 1, resp.Pagination.TotalCount)
+	require.Empty(t, resp.Pagination.Next)
+}
+
+func TestGetTagsWithPostsCount(t *testing.T) {
+	tag := generateRandomAlphanumericString(restapi.MaxTagLen)
+	for i := 0; i < 2; i++ {
+		request := createPostRequestFactory()
+		request.Tags = []string{tag}
+		createPost(request)
+	}
+
+	r := getTags()
+	assertNiceResponse(t, r, http.StatusOK)
+	tags := decodeResponseWithTagsBody(r.Body).Body
+
+	found := false
+	for _, receivedTag := range tags {
+		if receivedTag.Name == tag {
+			found = true
+			require.Equal(t, 2, receivedTag.PostsCount)
+		}
+	}
+	require.True(t, found)
+}
+
+func TestDeleteTagRemovesItFromPosts(t *testing.T) {
+	tag := generateRandomAlphanumericString(restapi.MaxTagLen)
+	request := createPostRequestFactory()
+	request.Tags = []string{tag}
+	r := createPost(request)
+	assertNiceResponse(t, r, http.StatusCreated)
+	post := decodeResponseWithPostBody(r.Body).Body
+	defer deletePost(post.ID)
+
+	r = getTags()
+	assertNiceResponse(t, r, http.StatusOK)
+	var tagID string
+	for _, receivedTag := range decodeResponseWithTagsBody(r.Body).Body {
+		if receivedTag.Name == tag {
+			tagID = receivedTag.ID
+		}
+	}
+	require.NotEmpty(t, tagID)
+
+	r = deleteTag(tagID)
+	assertNiceResponse(t, r, http.StatusOK)
+
+	postTags, err := tagService.GetAllByPostID(db, post.ID)
+	require.NoError(t, err)
+	require.Empty(t, postTags)
+}
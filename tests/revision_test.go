@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"github.com/blinky-z/Blog/handler/restapi"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestEveryPostUpdateSavesRevision(t *testing.T) {
+	createPostRequest := createPostRequestFactory()
+	r := createPost(createPostRequest)
+	post := decodeResponseWithPostBody(r.Body).Body
+
+	updatePostRequest := updatePostRequestFactory()
+	updatePost(post.ID, updatePostRequest)
+
+	r = getPostRevisions(post.ID)
+	assertNiceResponse(t, r, http.StatusOK)
+	revisions := decodeResponseWithRevisionsBody(r.Body).Body
+
+	require.Len(t, revisions, 2)
+	// revisions are sorted from the newest to the oldest
+	require.Equal(t, updatePostRequest.ContentMD, revisions[0].ContentMD)
+	require.Equal(t, createPostRequest.ContentMD, revisions[1].ContentMD)
+}
+
+func TestDiffPostRevisions(t *testing.T) {
+	createPostRequest := createPostRequestFactory()
+	r := createPost(createPostRequest)
+	post := decodeResponseWithPostBody(r.Body).Body
+
+	updatePostRequest := updatePostRequestFactory()
+	updatePost(post.ID, updatePostRequest)
+
+	revisions := decodeResponseWithRevisionsBody(getPostRevisions(post.ID).Body).Body
+
+	r = getPostRevisionsDiff(post.ID, revisions[1].ID, revisions[0].ID)
+	assertNiceResponse(t, r, http.StatusOK)
+	diff := decodeResponseWithRevisionsDiffBody(r.Body).Body
+
+	require.True(t, strings.Contains(diff.Diff, "-"+createPostRequest.ContentMD))
+	require.True(t, strings.Contains(diff.Diff, "+"+updatePostRequest.ContentMD))
+}
+
+func TestRestorePostRevision(t *testing.T) {
+	createPostRequest := createPostRequestFactory()
+	r := createPost(createPostRequest)
+	post := decodeResponseWithPostBody(r.Body).Body
+
+	updatePost(post.ID, updatePostRequestFactory())
+
+	revisions := decodeResponseWithRevisionsBody(getPostRevisions(post.ID).Body).Body
+	firstRevision := revisions[len(revisions)-1]
+
+	r = restorePostRevision(post.ID, firstRevision.ID)
+	assertNiceResponse(t, r, http.StatusCreated)
+	restoredPost := decodeResponseWithPostBody(r.Body).Body
+
+	require.Equal(t, createPostRequest.Title, restoredPost.Title)
+
+	// restoring is an update too, so it saves a new revision
+	revisions = decodeResponseWithRevisionsBody(getPostRevisions(post.ID).Body).Body
+	require.Len(t, revisions, 3)
+	require.Equal(t, createPostRequest.ContentMD, revisions[0].ContentMD)
+}
+
+func TestRestorePostRevisionKeepsCoAuthors(t *testing.T) {
+	request := createPostRequestFactory()
+	request.CoAuthors = []string{registerAuthor(t).Username}
+	r := createPost(request)
+	assertNiceResponse(t, r, http.StatusCreated)
+	post := decodeResponseWithPostBody(r.Body).Body
+	defer deletePost(post.ID)
+
+	updateRequest := updatePostRequestFactory()
+	updateRequest.CoAuthors = request.CoAuthors
+	r = updatePost(post.ID, updateRequest)
+	assertNiceResponse(t, r, http.StatusCreated)
+	updatedPost := decodeResponseWithPostBody(r.Body).Body
+	require.Len(t, updatedPost.Authors, 2)
+
+	revisions := decodeResponseWithRevisionsBody(getPostRevisions(post.ID).Body).Body
+
+	r = restorePostRevision(post.ID, revisions[len(revisions)-1].ID)
+	assertNiceResponse(t, r, http.StatusCreated)
+	restoredPost := decodeResponseWithPostBody(r.Body).Body
+
+	require.Equal(t, request.Title, restoredPost.Title)
+	require.Equal(t, updatedPost.Slug, restoredPost.Slug)
+	require.Equal(t, updatedPost.Authors, restoredPost.Authors)
+}
+
+func TestRestoreRevisionOfAnotherPost(t *testing.T) {
+	r := createPost(createPostRequestFactory())
+	firstPost := decodeResponseWithPostBody(r.Body).Body
+	r = createPost(createPostRequestFactory())
+	secondPost := decodeResponseWithPostBody(r.Body).Body
+
+	revisions := decodeResponseWithRevisionsBody(getPostRevisions(secondPost.ID).Body).Body
+
+	r = restorePostRevision(firstPost.ID, revisions[0].ID)
+
+	assertErrorResponse(t, r, http.StatusNotFound, restapi.NoSuchRevision)
+}
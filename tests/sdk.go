@@ -7,10 +7,14 @@ import (
 	"fmt"
 	"github.com/blinky-z/Blog/handler/restapi"
 	"github.com/blinky-z/Blog/models"
+	"image"
+	"image/png"
 	"io"
 	"io/ioutil"
 	"math/rand"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"testing"
 )
 
@@ -20,11 +24,14 @@ var (
 	loginUsername string
 	loginEmail    string
 	loginPassword string
-
-	authToken string
-	fgpCookie *http.Cookie
-	address   string
-	db        *sql.DB
+	// unclaimedAdminUsername - username from admins list that is not registered on the server start
+	unclaimedAdminUsername string
+
+	authToken     string
+	fgpCookie     *http.Cookie
+	refreshCookie *http.Cookie
+	address       string
+	db            *sql.DB
 )
 
 // Response - generic struct for storing deserialized response body
@@ -47,8 +54,15 @@ type ResponseWithCertainPost struct {
 
 // ResponseWithRangeOfPosts - struct for storing returned range of posts
 type ResponseWithRangeOfPosts struct {
+	Error      interface{}
+	Body       []models.Post
+	Pagination models.Pagination
+}
+
+// ResponseWithTags - struct for storing returned tags with posts count
+type ResponseWithTags struct {
 	Error interface{}
-	Body  []models.Post
+	Body  []models.TagWithPostsCount
 }
 
 // ResponseWithComment - struct for storing returned comment
@@ -57,6 +71,43 @@ type ResponseWithComment struct {
 	Body  models.Comment
 }
 
+// ResponseWithRevisions - struct for storing returned post revisions
+type ResponseWithRevisions struct {
+	Error interface{}
+	Body  []models.PostRevision
+}
+
+// ResponseWithRevisionsDiff - struct for storing returned diff of post revisions
+type ResponseWithRevisionsDiff struct {
+	Error interface{}
+	Body  models.PostRevisionsDiff
+}
+
+// ResponseWithSearchResults - struct for storing returned search results
+type ResponseWithSearchResults struct {
+	Error interface{}
+	Body  []models.PostSearchResult
+}
+
+// ResponseWithMedia - struct for storing returned uploaded file
+type ResponseWithMedia struct {
+	Error interface{}
+	Body  models.Media
+}
+
+// ResponseWithRangeOfMedia - struct for storing returned range of uploaded files
+type ResponseWithRangeOfMedia struct {
+	Error      interface{}
+	Body       []models.Media
+	Pagination models.Pagination
+}
+
+// ResponseWithAuthor - struct for storing returned author profile
+type ResponseWithAuthor struct {
+	Error interface{}
+	Body  models.Author
+}
+
 // -----------
 // Internal helper methods
 
@@ -123,6 +174,19 @@ func decodeResponseWithRangeOfPostsBody(responseBody io.ReadCloser) *ResponseWit
 	return resp
 }
 
+// decodeResponseWithTagsBody - use this function to deserialize response that contains tags
+func decodeResponseWithTagsBody(responseBody io.ReadCloser) *ResponseWithTags {
+	bodyBytes, _ := ioutil.ReadAll(responseBody)
+	responseBodyCopy := ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
+
+	resp := &ResponseWithTags{}
+	err := json.NewDecoder(responseBodyCopy).Decode(resp)
+	if err != nil {
+		panic(fmt.Sprintf("Error decoding received body. Error: %s", err))
+	}
+	return resp
+}
+
 // decodeResponseWithPostBody - use this function to deserialize response that contains comment
 func decodeResponseWithCommentBody(responseBody io.ReadCloser) *ResponseWithComment {
 	bodyBytes, _ := ioutil.ReadAll(responseBody)
@@ -136,6 +200,84 @@ func decodeResponseWithCommentBody(responseBody io.ReadCloser) *ResponseWithComm
 	return resp
 }
 
+// decodeResponseWithRevisionsBody - use this function to deserialize response that contains post revisions
+func decodeResponseWithRevisionsBody(responseBody io.ReadCloser) *ResponseWithRevisions {
+	bodyBytes, _ := ioutil.ReadAll(responseBody)
+	responseBodyCopy := ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
+
+	resp := &ResponseWithRevisions{}
+	err := json.NewDecoder(responseBodyCopy).Decode(resp)
+	if err != nil {
+		panic(fmt.Sprintf("Error decoding received body. Error: %s", err))
+	}
+	return resp
+}
+
+// decodeResponseWithRevisionsDiffBody - use this function to deserialize response that contains diff of post revisions
+func decodeResponseWithRevisionsDiffBody(responseBody io.ReadCloser) *ResponseWithRevisionsDiff {
+	bodyBytes, _ := ioutil.ReadAll(responseBody)
+	responseBodyCopy := ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
+
+	resp := &ResponseWithRevisionsDiff{}
+	err := json.NewDecoder(responseBodyCopy).Decode(resp)
+	if err != nil {
+		panic(fmt.Sprintf("Error decoding received body. Error: %s", err))
+	}
+	return resp
+}
+
+// decodeResponseWithSearchResultsBody - use this function to deserialize response that contains search results
+func decodeResponseWithSearchResultsBody(responseBody io.ReadCloser) *ResponseWithSearchResults {
+	bodyBytes, _ := ioutil.ReadAll(responseBody)
+	responseBodyCopy := ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
+
+	resp := &ResponseWithSearchResults{}
+	err := json.NewDecoder(responseBodyCopy).Decode(resp)
+	if err != nil {
+		panic(fmt.Sprintf("Error decoding received body. Error: %s", err))
+	}
+	return resp
+}
+
+// decodeResponseWithMediaBody - use this function to deserialize response that contains uploaded file
+func decodeResponseWithMediaBody(responseBody io.ReadCloser) *ResponseWithMedia {
+	bodyBytes, _ := ioutil.ReadAll(responseBody)
+	responseBodyCopy := ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
+
+	resp := &ResponseWithMedia{}
+	err := json.NewDecoder(responseBodyCopy).Decode(resp)
+	if err != nil {
+		panic(fmt.Sprintf("Error decoding received body. Error: %s", err))
+	}
+	return resp
+}
+
+// decodeResponseWithRangeOfMediaBody - use this function to deserialize response that contains range of uploaded files
+func decodeResponseWithRangeOfMediaBody(responseBody io.ReadCloser) *ResponseWithRangeOfMedia {
+	bodyBytes, _ := ioutil.ReadAll(responseBody)
+	responseBodyCopy := ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
+
+	resp := &ResponseWithRangeOfMedia{}
+	err := json.NewDecoder(responseBodyCopy).Decode(resp)
+	if err != nil {
+		panic(fmt.Sprintf("Error decoding received body. Error: %s", err))
+	}
+	return resp
+}
+
+// decodeResponseWithAuthorBody - use this function to deserialize response that contains author profile
+func decodeResponseWithAuthorBody(responseBody io.ReadCloser) *ResponseWithAuthor {
+	bodyBytes, _ := ioutil.ReadAll(responseBody)
+	responseBodyCopy := ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
+
+	resp := &ResponseWithAuthor{}
+	err := json.NewDecoder(responseBodyCopy).Decode(resp)
+	if err != nil {
+		panic(fmt.Sprintf("Error decoding received body. Error: %s", err))
+	}
+	return resp
+}
+
 // sendMessage - generic function for sending a request
 // @method - supports "GET", "POST", "PUT", "DELETE"
 // @address - http address to send request to. Example of address: "localhost:8080"
@@ -154,6 +296,9 @@ func sendMessage(method, address string, message interface{}, addAuthData bool)
 		if request, err = http.NewRequest("GET", address, body); err != nil {
 			break
 		}
+		if addAuthData {
+			addAuthDataToRequest(request)
+		}
 		response, err = client.Do(request)
 	case "POST":
 		if request, err = http.NewRequest("POST", address, body); err != nil {
@@ -193,7 +338,7 @@ func sendMessage(method, address string, message interface{}, addAuthData bool)
 
 // -----------
 // Auth managing: perform registration or log in request, extract/set auth data
-// Auth data consists of Bearer JWT token and fingerprint cookie for now
+// Auth data consists of Bearer JWT token and fingerprint cookie. Refresh token cookie is used to get a new JWT token
 
 func registerUser(login, email, password string) *http.Response {
 	registrationCredentials := models.RegistrationRequest{Username: login, Email: email, Password: password}
@@ -209,12 +354,63 @@ func setNewAuthData(r *http.Response) {
 	resp := decodeResponse(r.Body)
 
 	authToken = resp.Body.(string)
-	cookies := r.Cookies()
-	for _, currentCookie := range cookies {
-		if currentCookie.Name == "Secure-Fgp" {
-			fgpCookie = currentCookie
+	fgpCookie = getResponseCookie(r, "Secure-Fgp")
+	if newRefreshCookie := getResponseCookie(r, "Secure-Refresh"); newRefreshCookie != nil {
+		refreshCookie = newRefreshCookie
+	}
+}
+
+// authData - auth data of the logged in user. Use it to switch between users
+type authData struct {
+	token         string
+	fgpCookie     *http.Cookie
+	refreshCookie *http.Cookie
+}
+
+func getAuthData() authData {
+	return authData{token: authToken, fgpCookie: fgpCookie, refreshCookie: refreshCookie}
+}
+
+func setAuthData(data authData) {
+	authToken = data.token
+	fgpCookie = data.fgpCookie
+	refreshCookie = data.refreshCookie
+}
+
+// getResponseCookie - returns cookie with the given name set by the response or nil if there is no such cookie
+func getResponseCookie(r *http.Response, name string) *http.Cookie {
+	for _, currentCookie := range r.Cookies() {
+		if currentCookie.Name == name {
+			return currentCookie
 		}
 	}
+	return nil
+}
+
+// sendWithRefreshCookie - sends request to the user API with the given refresh token cookie
+// Pass nil cookie to send request without refresh token
+func sendWithRefreshCookie(path string, cookie *http.Cookie) *http.Response {
+	request, err := http.NewRequest("POST", "http://"+address+path, nil)
+	if err != nil {
+		panic(fmt.Sprintf("Can not create request. Error: %s", err))
+	}
+	if cookie != nil {
+		request.AddCookie(cookie)
+	}
+
+	r, err := client.Do(request)
+	if err != nil {
+		panic(fmt.Sprintf("Can not send request. Error: %s", err))
+	}
+	return r
+}
+
+func refreshAuthToken(cookie *http.Cookie) *http.Response {
+	return sendWithRefreshCookie("/api/user/refresh", cookie)
+}
+
+func logoutUser(cookie *http.Cookie) *http.Response {
+	return sendWithRefreshCookie("/api/user/logout", cookie)
 }
 
 func addAuthDataToRequest(r *http.Request) {
@@ -293,10 +489,9 @@ func comparePosts(l, r models.Post) bool {
 
 func createPostRequestFactory() models.CreatePostRequest {
 	return models.CreatePostRequest{
-		Title:   generateRandomAlphanumericString(restapi.MinPostTitleLen),
-		Author:  generateRandomAlphanumericString(restapi.MinUsernameLen),
-		Snippet: generateRandomAlphanumericString(restapi.MinSnippetLen),
-		Content: generateRandomAlphanumericString(restapi.MinSnippetLen),
+		Title: generateRandomAlphanumericString(restapi.MinPostTitleLen),
+		ContentMD: generateRandomAlphanumericString(restapi.MinSnippetLen) + "<cut>" +
+			generateRandomAlphanumericString(restapi.MinSnippetLen),
 		Metadata: models.MetaData{
 			Description: generateRandomAlphanumericString(restapi.MinMetaDescriptionLen),
 			Keywords:    []string{generateRandomAlphanumericString(restapi.MinMetaKeywordLen)},
@@ -306,10 +501,9 @@ func createPostRequestFactory() models.CreatePostRequest {
 
 func updatePostRequestFactory() models.UpdatePostRequest {
 	return models.UpdatePostRequest{
-		Title:   generateRandomAlphanumericString(restapi.MinPostTitleLen),
-		Author:  generateRandomAlphanumericString(restapi.MinUsernameLen),
-		Snippet: generateRandomAlphanumericString(restapi.MinSnippetLen),
-		Content: generateRandomAlphanumericString(restapi.MinSnippetLen),
+		Title: generateRandomAlphanumericString(restapi.MinPostTitleLen),
+		ContentMD: generateRandomAlphanumericString(restapi.MinSnippetLen) + "<cut>" +
+			generateRandomAlphanumericString(restapi.MinSnippetLen),
 		Metadata: models.MetaData{
 			Description: generateRandomAlphanumericString(restapi.MinMetaDescriptionLen),
 			Keywords:    []string{generateRandomAlphanumericString(restapi.MinMetaKeywordLen)},
@@ -320,14 +514,37 @@ func updatePostRequestFactory() models.UpdatePostRequest {
 // blog posts related rest api access
 
 func getCertainPost(postID string) *http.Response {
-	return sendMessage("GET", "http://"+address+"/api/posts/"+postID, "", false)
+	return sendMessage("GET", "http://"+address+"/api/v1/posts/"+postID, "", false)
 }
 
 func getPostsInRange(page, postsPerPage string) *http.Response {
 	if postsPerPage == "" {
-		return sendMessage("GET", "http://"+address+"/api/posts?page="+page, "", false)
+		return sendMessage("GET", "http://"+address+"/api/v1/posts?page="+page, "", false)
 	}
-	return sendMessage("GET", "http://"+address+"/api/posts?page="+page+"&posts-per-page="+postsPerPage, "", false)
+	return sendMessage("GET", "http://"+address+"/api/v1/posts?page="+page+"&posts-per-page="+postsPerPage, "", false)
+}
+
+func getPostsInRangeByTag(tag, page, postsPerPage string) *http.Response {
+	return sendMessage("GET", "http://"+address+"/api/v1/tags/"+url.PathEscape(tag)+"/posts?page="+page+
+		"&posts-per-page="+postsPerPage, "", false)
+}
+
+func getPostsInRangeByAuthor(username, page, postsPerPage string) *http.Response {
+	return sendMessage("GET", "http://"+address+"/api/v1/authors/"+url.PathEscape(username)+"/posts?page="+page+
+		"&posts-per-page="+postsPerPage, "", false)
+}
+
+func getAuthor(username string) *http.Response {
+	return sendMessage("GET", "http://"+address+"/api/v1/authors/"+url.PathEscape(username), "", false)
+}
+
+// pass models.UpdateProfileRequest if you don't want to test bad body
+func updateProfile(message interface{}) *http.Response {
+	return sendMessage("PUT", "http://"+address+"/api/user/profile", message, true)
+}
+
+func getTags() *http.Response {
+	return sendMessage("GET", "http://"+address+"/api/v1/tags", "", false)
 }
 
 // pass models.CreatePostRequest if you don't want to test bad body
@@ -340,10 +557,63 @@ func updatePost(postID string, message interface{}) *http.Response {
 	return sendMessage("PUT", "http://"+address+"/api/posts/"+postID, message, true)
 }
 
+func updateUserRole(username string, role models.UserRole) *http.Response {
+	return sendMessage("PUT", "http://"+address+"/api/users/"+url.PathEscape(username)+"/role",
+		models.UpdateUserRoleRequest{Role: role}, true)
+}
+
+func createTag(name string) *http.Response {
+	return sendMessage("POST", "http://"+address+"/api/tags", models.CreateTagRequest{Name: name}, true)
+}
+
+func deleteTag(tagID string) *http.Response {
+	return sendMessage("DELETE", "http://"+address+"/api/tags/"+tagID, "", true)
+}
+
 func deletePost(postID string) *http.Response {
 	return sendMessage("DELETE", "http://"+address+"/api/posts/"+postID, "", true)
 }
 
+func renderAllPosts() *http.Response {
+	return sendMessage("POST", "http://"+address+"/api/posts/render", "", true)
+}
+
+func searchPosts(query string) *http.Response {
+	return sendMessage("GET", "http://"+address+"/api/search?q="+url.QueryEscape(query), "", false)
+}
+
+// getPage - retrieves the page with the given path. Pass empty 'ifModifiedSince' to get the page unconditionally
+func getPage(path, ifModifiedSince string) *http.Response {
+	request, err := http.NewRequest("GET", "http://"+address+path, nil)
+	if err != nil {
+		panic(fmt.Sprintf("Error creating request. Error: %s", err))
+	}
+	if ifModifiedSince != "" {
+		request.Header.Set("If-Modified-Since", ifModifiedSince)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		panic(fmt.Sprintf("Error sending request. Error: %s", err))
+	}
+	return response
+}
+
+// post revisions related rest api access
+
+func getPostRevisions(postID string) *http.Response {
+	return sendMessage("GET", "http://"+address+"/api/posts/"+postID+"/revisions", "", true)
+}
+
+func getPostRevisionsDiff(postID, fromRevisionID, toRevisionID string) *http.Response {
+	return sendMessage("GET", "http://"+address+"/api/posts/"+postID+"/revisions/diff?from="+fromRevisionID+
+		"&to="+toRevisionID, "", true)
+}
+
+func restorePostRevision(postID, revisionID string) *http.Response {
+	return sendMessage("POST", "http://"+address+"/api/posts/"+postID+"/revisions/"+revisionID+"/restore", "", true)
+}
+
 // -----------
 // comments related SDK
 
@@ -383,3 +653,56 @@ func updateComment(id string, message interface{}) *http.Response {
 func deleteComment(id string) *http.Response {
 	return sendMessage("DELETE", "http://"+address+"/api/comments/"+id, "", true)
 }
+
+// media library related rest api access
+
+// uploadMedia - uploads file with the given name and content in multipart form
+func uploadMedia(filename string, content []byte) *http.Response {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		panic(fmt.Sprintf("Error creating multipart form. Error: %s", err))
+	}
+	if _, err = part.Write(content); err != nil {
+		panic(fmt.Sprintf("Error writing multipart form. Error: %s", err))
+	}
+	if err = form.Close(); err != nil {
+		panic(fmt.Sprintf("Error closing multipart form. Error: %s", err))
+	}
+
+	request, err := http.NewRequest("POST", "http://"+address+"/api/media", &body)
+	if err != nil {
+		panic(fmt.Sprintf("Error creating request. Error: %s", err))
+	}
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	addAuthDataToRequest(request)
+
+	response, err := client.Do(request)
+	if err != nil {
+		panic(fmt.Sprintf("Error sending request. Error: %s", err))
+	}
+	return response
+}
+
+func getMedia(page, perPage string) *http.Response {
+	return sendMessage("GET", "http://"+address+"/api/media?page="+page+"&per-page="+perPage, "", true)
+}
+
+func deleteMedia(mediaID string) *http.Response {
+	return sendMessage("DELETE", "http://"+address+"/api/media/"+mediaID, "", true)
+}
+
+// generatePNG - encodes a random image of the given size as PNG
+func generatePNG(width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	if _, err := rand.Read(img.Pix); err != nil {
+		panic(fmt.Sprintf("Error generating image. Error: %s", err))
+	}
+
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, img); err != nil {
+		panic(fmt.Sprintf("Error encoding image. Error: %s", err))
+	}
+	return encoded.Bytes()
+}
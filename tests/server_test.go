@@ -0,0 +1,13 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestServerIsReady(t *testing.T) {
+	r := getPage("/api/ready", "")
+
+	require.Equal(t, http.StatusOK, r.StatusCode)
+}
@@ -0,0 +1,29 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSitemapContainsCreatedPost(t *testing.T) {
+	r := createPost(createPostRequestFactory())
+	post := decodeResponseWithPostBody(r.Body).Body
+
+	r = getPage("/sitemap.xml", "")
+	require.Equal(t, http.StatusOK, r.StatusCode)
+
+	body, _ := ioutil.ReadAll(r.Body)
+	require.True(t, strings.Contains(string(body), "/posts/"+post.Slug+"</loc>"))
+}
+
+func TestRobotsPointsToSitemap(t *testing.T) {
+	r := getPage("/robots.txt", "")
+	require.Equal(t, http.StatusOK, r.StatusCode)
+
+	body, _ := ioutil.ReadAll(r.Body)
+	require.True(t, strings.Contains(string(body), "Sitemap: "))
+	require.True(t, strings.Contains(string(body), "/sitemap.xml"))
+}
@@ -31,6 +31,21 @@ func TestRegisterUserWithExistingLogin(t *testing.T) {
 	assertErrorResponse(t, r, http.StatusBadRequest, restapi.UserAlreadyRegistered)
 }
 
+func TestRegisterUserFromAdminsListGetsNoPermissions(t *testing.T) {
+	adminAuthData := getAuthData()
+	defer setAuthData(adminAuthData)
+
+	password := uuid.New().String() + "Z"
+	r := registerUser(unclaimedAdminUsername, unclaimedAdminUsername+"@gmail.com", password)
+	assertNiceResponse(t, r, http.StatusOK)
+	r = loginUser(unclaimedAdminUsername, "", password)
+	assertNiceResponse(t, r, http.StatusOK)
+	setNewAuthData(r)
+
+	r = createPost(createPostRequestFactory())
+	assertErrorResponse(t, r, http.StatusForbidden, restapi.NoPermissions)
+}
+
 func TestRegisterUserWithTooLongUsername(t *testing.T) {
 	username := strings.Repeat("a", restapi.MaxUsernameLen*2)
 
@@ -136,6 +151,187 @@ func TestLoginUserWithEmptyPassword(t *testing.T) {
 	assertErrorResponse(t, r, http.StatusBadRequest, restapi.IncompleteCredentials)
 }
 
+// Refresh token tests
+
+// loginForRefreshToken - logs in with the current user and returns refresh token cookie
+func loginForRefreshToken(t *testing.T) *http.Cookie {
+	r := loginUser(loginUsername, "", loginPassword)
+	defer r.Body.Close()
+	assertNiceResponse(t, r, http.StatusOK)
+
+	cookie := getResponseCookie(r, "Secure-Refresh")
+	if cookie == nil || cookie.Value == "" {
+		t.Fatalf("Login response does not contain refresh token cookie")
+	}
+	return cookie
+}
+
+func TestRefreshToken(t *testing.T) {
+	cookie := loginForRefreshToken(t)
+
+	r := refreshAuthToken(cookie)
+	defer r.Body.Close()
+	assertNiceResponse(t, r, http.StatusOK)
+
+	newCookie := getResponseCookie(r, "Secure-Refresh")
+	if newCookie == nil || newCookie.Value == cookie.Value {
+		t.Fatalf("Refresh token was not rotated")
+	}
+	setNewAuthData(r)
+
+	// refreshed access token should be accepted
+	createPostResponse := createPost(createPostRequestFactory())
+	resp := decodeResponseWithPostBody(createPostResponse.Body)
+	assertNiceResponse(t, createPostResponse, http.StatusCreated)
+
+	deletePostResponse := deletePost(resp.Body.ID)
+	assertNiceResponse(t, deletePostResponse, http.StatusOK)
+}
+
+func TestRefreshTokenReuseRevokesAllTokens(t *testing.T) {
+	cookie := loginForRefreshToken(t)
+
+	r := refreshAuthToken(cookie)
+	assertNiceResponse(t, r, http.StatusOK)
+	newCookie := getResponseCookie(r, "Secure-Refresh")
+
+	// the old token was rotated, so using it again looks like the token was stolen
+	r = refreshAuthToken(cookie)
+	assertErrorResponse(t, r, http.StatusUnauthorized, restapi.InvalidRefreshToken)
+
+	r = refreshAuthToken(newCookie)
+	assertErrorResponse(t, r, http.StatusUnauthorized, restapi.InvalidRefreshToken)
+}
+
+func TestRefreshTokenAfterLogout(t *testing.T) {
+	cookie := loginForRefreshToken(t)
+
+	r := logoutUser(cookie)
+	assertNiceResponse(t, r, http.StatusOK)
+
+	r = refreshAuthToken(cookie)
+	assertErrorResponse(t, r, http.StatusUnauthorized, restapi.InvalidRefreshToken)
+}
+
+func TestRefreshTokenWithMissingToken(t *testing.T) {
+	r := refreshAuthToken(nil)
+
+	assertErrorResponse(t, r, http.StatusUnauthorized, restapi.InvalidRefreshToken)
+}
+
+func TestRefreshTokenWithInvalidToken(t *testing.T) {
+	r := refreshAuthToken(&http.Cookie{Name: "Secure-Refresh", Value: uuid.New().String()})
+
+	assertErrorResponse(t, r, http.StatusUnauthorized, restapi.InvalidRefreshToken)
+}
+
+// Roles tests
+
+// registerUserWithRole - registers a new user, sets the role and logs the user in
+// returns auth data of the new user. Current auth data should be of the admin and is kept
+func registerUserWithRole(t *testing.T, role models.UserRole) authData {
+	adminAuthData := getAuthData()
+	defer setAuthData(adminAuthData)
+
+	username := uuid.New().String()
+	password := uuid.New().String() + "Z"
+
+	r := registerUser(username, username+"@gmail.com", password)
+	assertNiceResponse(t, r, http.StatusOK)
+
+	r = updateUserRole(username, role)
+	assertNiceResponse(t, r, http.StatusOK)
+
+	r = loginUser(username, "", password)
+	assertNiceResponse(t, r, http.StatusOK)
+	setNewAuthData(r)
+	return getAuthData()
+}
+
+func TestAuthorCanWriteOnlyOwnDrafts(t *testing.T) {
+	adminAuthData := getAuthData()
+	defer setAuthData(adminAuthData)
+
+	adminDraftRequest := createPostRequestFactory()
+	adminDraftRequest.Status = models.PostStatusDraft
+	r := createPost(adminDraftRequest)
+	assertNiceResponse(t, r, http.StatusCreated)
+	adminDraft := decodeResponseWithPostBody(r.Body).Body
+	defer deletePost(adminDraft.ID)
+
+	authorAuthData := registerUserWithRole(t, models.RoleAuthor)
+	setAuthData(authorAuthData)
+
+	// authors can't publish posts
+	r = createPost(createPostRequestFactory())
+	assertErrorResponse(t, r, http.StatusForbidden, restapi.NoPermissions)
+
+	draftRequest := createPostRequestFactory()
+	draftRequest.Status = models.PostStatusDraft
+	r = createPost(draftRequest)
+	assertNiceResponse(t, r, http.StatusCreated)
+	draft := decodeResponseWithPostBody(r.Body).Body
+
+	updateRequest := updatePostRequestFactory()
+	updateRequest.Status = models.PostStatusDraft
+	r = updatePost(draft.ID, updateRequest)
+	assertNiceResponse(t, r, http.StatusCreated)
+
+	publishRequest := updatePostRequestFactory()
+	r = updatePost(draft.ID, publishRequest)
+	assertErrorResponse(t, r, http.StatusForbidden, restapi.NoPermissions)
+
+	r = updatePost(adminDraft.ID, updateRequest)
+	assertErrorResponse(t, r, http.StatusForbidden, restapi.NoPermissions)
+
+	r = deletePost(draft.ID)
+	assertErrorResponse(t, r, http.StatusForbidden, restapi.NoPermissions)
+
+	r = createTag(uuid.New().String())
+	assertErrorResponse(t, r, http.StatusForbidden, restapi.NoPermissions)
+
+	setAuthData(adminAuthData)
+	r = deletePost(draft.ID)
+	assertNiceResponse(t, r, http.StatusOK)
+}
+
+func TestEditorCanManageAnyPostsAndTags(t *testing.T) {
+	adminAuthData := getAuthData()
+	defer setAuthData(adminAuthData)
+
+	r := createPost(createPostRequestFactory())
+	assertNiceResponse(t, r, http.StatusCreated)
+	adminPost := decodeResponseWithPostBody(r.Body).Body
+
+	editorAuthData := registerUserWithRole(t, models.RoleEditor)
+	setAuthData(editorAuthData)
+
+	r = updatePost(adminPost.ID, updatePostRequestFactory())
+	assertNiceResponse(t, r, http.StatusCreated)
+
+	r = createTag(uuid.New().String())
+	assertNiceResponse(t, r, http.StatusOK)
+
+	// only admins manage users
+	r = updateUserRole(loginUsername, models.RoleUser)
+	assertErrorResponse(t, r, http.StatusForbidden, restapi.NoPermissions)
+
+	r = deletePost(adminPost.ID)
+	assertNiceResponse(t, r, http.StatusOK)
+}
+
+func TestUpdateUserRoleWithInvalidRole(t *testing.T) {
+	r := updateUserRole(loginUsername, "superuser")
+
+	assertErrorResponse(t, r, http.StatusBadRequest, restapi.InvalidRole)
+}
+
+func TestUpdateRoleOfNotExistingUser(t *testing.T) {
+	r := updateUserRole(uuid.New().String(), models.RoleEditor)
+
+	assertErrorResponse(t, r, http.StatusBadRequest, restapi.NoSuchUser)
+}
+
 // Test JWT tokens
 
 func TestRegisterNotAdmin(t *testing.T) {
@@ -200,6 +396,18 @@ func TestDeletePostWithNotAdminUser(t *testing.T) {
 	assertErrorResponse(t, r, http.StatusForbidden, restapi.NoPermissions)
 }
 
+func TestCreateTagWithNotAdminUser(t *testing.T) {
+	r := createTag(uuid.New().String())
+	defer func() {
+		err := r.Body.Close()
+		if err != nil {
+			panic(err)
+		}
+	}()
+
+	assertErrorResponse(t, r, http.StatusForbidden, restapi.NoPermissions)
+}
+
 func TestInitInvalidJwtToken(t *testing.T) {
 	var claims models.TokenClaims
 	claims.ExpiresAt = time.Now().Add(1 * time.Hour).Unix()
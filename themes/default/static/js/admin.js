@@ -0,0 +1,468 @@
+var cutDelimiter = '<cut>';
+var editor;
+var tagsInputTagify;
+const editorTextBackupKey = "editor-text";
+const accessTokenKey = "token";
+
+function redirectToLogin() {
+    window.location.href = "/login?next=" + encodeURIComponent(window.location.pathname + window.location.search);
+}
+
+// refreshAccessToken - exchanges refresh token stored in cookie for a new access token
+function refreshAccessToken(onSuccess, onFailure) {
+    $.ajax(
+        {
+            url: '/api/user/refresh',
+            type: 'POST',
+            success: function (data, textStatus, jqXHR) {
+                var response = JSON.parse(jqXHR.responseText);
+                sessionStorage.setItem(accessTokenKey, response.body);
+                onSuccess();
+            },
+            error: function () {
+                sessionStorage.removeItem(accessTokenKey);
+                onFailure();
+            }
+        }
+    );
+}
+
+// sendAuthorized - sends request to the admin api with access token
+// Expired access token is refreshed once, and user is redirected to the login page if it is impossible
+function sendAuthorized(settings, isRetry) {
+    var request = $.extend({}, settings, {
+        beforeSend: function (xhr) {
+            var token = sessionStorage.getItem(accessTokenKey);
+            if (token !== null) {
+                xhr.setRequestHeader('Authorization', `Bearer ${token}`);
+            }
+        },
+        error: function (jqXHR, textStatus, errorThrown) {
+            if (jqXHR.status !== 401) {
+                settings.error(jqXHR, textStatus, errorThrown);
+            } else if (isRetry) {
+                redirectToLogin();
+            } else {
+                refreshAccessToken(function () {
+                    sendAuthorized(settings, true);
+                }, redirectToLogin);
+            }
+        }
+    });
+    $.ajax(request);
+}
+
+function login() {
+    var loginInput = $("#login").val().trim();
+    var credentials = {
+        username: "",
+        email: "",
+        password: $("#password").val()
+    };
+    if (loginInput.indexOf("@") !== -1) {
+        credentials.email = loginInput;
+    } else {
+        credentials.username = loginInput;
+    }
+
+    $.ajax(
+        {
+            url: '/api/user/login',
+            type: 'POST',
+            contentType: 'application/json',
+            data: JSON.stringify(credentials),
+            success: function (data, textStatus, jqXHR) {
+                var response = JSON.parse(jqXHR.responseText);
+                sessionStorage.setItem(accessTokenKey, response.body);
+                redirectAfterLogin();
+            },
+            error: function (jqXHR, textStatus, errorThrown) {
+                var response = JSON.parse(jqXHR.responseText);
+                alert(response.error)
+            }
+        }
+    );
+}
+
+// redirectAfterLogin - returns user to the page the login page was opened from
+function redirectAfterLogin() {
+    var next = new URLSearchParams(window.location.search).get("next");
+    // allow local paths only
+    if (next === null || !next.startsWith("/") || next.startsWith("//")) {
+        next = "/";
+    }
+    window.location.replace(next);
+}
+
+// initLogin - skips the login page if user still has valid refresh token
+function initLogin() {
+    refreshAccessToken(redirectAfterLogin, function () {
+    });
+}
+
+function logout() {
+    $.ajax(
+        {
+            url: '/api/user/logout',
+            type: 'POST',
+            complete: function () {
+                sessionStorage.removeItem(accessTokenKey);
+                window.location.replace("/login");
+            }
+        }
+    );
+}
+
+// initialize editor section: create tui-editor and add available tags to tagify suggestions
+function initEditor() {
+    editor = new tui.Editor({
+        el: document.querySelector('#editSection'),
+        initialEditType: 'markdown',
+        previewStyle: 'tab',
+        height: '600px',
+        usageStatistics: false,
+        hooks: {
+            // upload pasted and dropped images to the media library instead of embedding them into markdown
+            addImageBlobHook: function (blob, callback) {
+                uploadMedia(blob, function (media) {
+                    callback(media.url, media.filename);
+                });
+                return false;
+            }
+        }
+    });
+
+    var contentTemp = $("#contentTemp");
+
+    var postID = contentTemp.attr("data-id");
+    var content = contentTemp.val();
+
+    var backupText = localStorage.getItem(editorTextBackupKey + postID);
+    if (backupText != null) {
+        editor.setMarkdown(backupText);
+    } else {
+        editor.setMarkdown(content);
+    }
+
+    var tagsInput = document.querySelector("#tags");
+    var allTags = tagsInput.getAttribute("data-allTags");
+    if (allTags !== "") {
+        allTags = allTags.split(",")
+    } else {
+        allTags = []
+    }
+
+    tagsInputTagify = new Tagify(tagsInput, {
+        whitelist: allTags,
+    });
+
+    // datetime-local input accepts local time without timezone only
+    var publishAtInput = $("#publishAt");
+    var publishAt = publishAtInput.attr("data-value");
+    if (publishAt !== "") {
+        var date = new Date(publishAt);
+        date.setMinutes(date.getMinutes() - date.getTimezoneOffset());
+        publishAtInput.val(date.toISOString().slice(0, 16));
+    }
+
+    window.setInterval(function () {
+        localStorage.setItem(editorTextBackupKey + postID, editor.getMarkdown())
+    }, 5000);
+}
+
+function replaceContentWithActual() {
+    editor.setMarkdown($("#contentTemp").val());
+}
+
+// getEditorInput - collects post from the editor. Post html is rendered on the server side from the markdown
+function getEditorInput() {
+    var title = $("#title").val();
+    var slug = $("#slug").val().trim().toLowerCase();
+    var contentMD = editor.getMarkdown();
+
+    if (contentMD.indexOf(cutDelimiter) === -1) {
+        alert("Please insert cut delimiter");
+        return -1;
+    }
+
+    var keywords = $("#metaKeywords").val();
+    if (keywords !== "") {
+        keywords = keywords.split(",");
+    } else {
+        keywords = [];
+    }
+
+    var metadata = {
+        description: $("#metaDescription").val(),
+        keywords: keywords
+    };
+
+    var status = $("#status").val();
+    var publishAt = null;
+    if (status === "scheduled") {
+        var publishAtValue = $("#publishAt").val();
+        if (publishAtValue === "") {
+            alert("Please set publish time of the scheduled post");
+            return -1;
+        }
+        publishAt = new Date(publishAtValue).toISOString();
+    }
+
+    var tagsTagify = tagsInputTagify.value;
+    var tags = [];
+    tagsTagify.forEach(function (elem) {
+        tags.push(elem.value);
+    });
+
+    var coAuthors = $("#coAuthors").val();
+    if (coAuthors !== "") {
+        coAuthors = coAuthors.split(",");
+    } else {
+        coAuthors = [];
+    }
+
+    return {
+        title: title,
+        slug: slug,
+        contentMD: contentMD,
+        metadata: metadata,
+        tags: tags,
+        status: status,
+        publishAt: publishAt,
+        coAuthors: coAuthors
+    };
+}
+
+function publishPost(action, domain) {
+    var postID = $(action).attr("data-id");
+
+    var post = getEditorInput();
+    if (post === -1) {
+        return
+    }
+    var encodedPost = JSON.stringify(post);
+
+    var url, type;
+    if (postID === "") {
+        url = `/api/posts`;
+        type = 'POST';
+    } else {
+        url = `/api/posts/${postID}`;
+        type = 'PUT';
+    }
+
+    localStorage.setItem(editorTextBackupKey + postID, editor.getMarkdown());
+
+    sendAuthorized(
+        {
+            url: url,
+            type: type,
+            contentType: 'application/json',
+            data: encodedPost,
+            success: function (data, textStatus, jqXHR) {
+                var response = JSON.parse(jqXHR.responseText);
+                var createdPost = response.body;
+
+                // drafts and scheduled posts are not available on the public site yet
+                var redirectURL = `${domain}/posts/${createdPost.Slug}`;
+                if (createdPost.Status !== "published") {
+                    alert("Post saved");
+                    redirectURL = "/manage-posts";
+                } else {
+                    alert("Post published");
+                }
+
+                // avoid situation when post might be published again
+                if (postID === "") {
+                    window.location.replace(redirectURL)
+                } else {
+                    window.location.href = redirectURL
+                }
+            },
+            error: function (jqXHR, textStatus, errorThrown) {
+                alert(errorThrown);
+                var response = JSON.parse(jqXHR.responseText);
+                alert(response.error)
+            }
+        }
+    );
+}
+
+function renderAllPosts() {
+    var result = confirm("You sure you want to re-render all posts?");
+    if (result) {
+        sendAuthorized(
+            {
+                url: '/api/posts/render',
+                type: 'POST',
+                success: function (data, textStatus, jqXHR) {
+                    var response = JSON.parse(jqXHR.responseText);
+                    alert(`Posts rendered: ${response.body}`);
+                },
+                error: function (jqXHR, textStatus, errorThrown) {
+                    var response = JSON.parse(jqXHR.responseText);
+                    alert(response.error)
+                }
+            }
+        );
+    }
+}
+
+function deletePost(action) {
+    var result = confirm("You sure you want to delete this post?");
+    if (result) {
+        var actions = $(action).parent();
+        var postID = actions.attr("data-id");
+
+        sendAuthorized(
+            {
+                url: `/api/posts/${postID}`,
+                type: 'DELETE',
+                success: function (data, textStatus, jqXHR) {
+                    alert("Post deleted");
+                    document.location.reload()
+                },
+                error: function (jqXHR, textStatus, errorThrown) {
+                    var response = JSON.parse(jqXHR.responseText);
+                    alert(response.error)
+                }
+            }
+        );
+    }
+}
+
+function createTag() {
+    var tagName = prompt("Enter a tag name");
+    if (tagName == null) {
+        return
+    }
+
+    var data = {Name: tagName};
+
+    sendAuthorized(
+        {
+            url: '/api/tags',
+            type: 'POST',
+            data: JSON.stringify(data),
+            success: function (data, textStatus, jqXHR) {
+                document.location.reload()
+            },
+            error: function (jqXHR, textStatus, errorThrown) {
+                var response = JSON.parse(jqXHR.responseText);
+                alert(response.error)
+            }
+        }
+    );
+}
+
+function editTag(action) {
+    var actions = $(action).parent();
+    var tagID = actions.attr("data-id");
+    var tagName = actions.attr("data-name");
+
+    var newTagName = prompt("Enter a new tag name", tagName);
+    if (newTagName == null) {
+        return false
+    }
+
+    var data = {Name: newTagName};
+
+    sendAuthorized(
+        {
+            url: `/api/tags/${tagID}`,
+            type: 'PUT',
+            data: JSON.stringify(data),
+            success: function (data, textStatus, jqXHR) {
+                document.location.reload()
+            },
+            error: function (jqXHR, textStatus, errorThrown) {
+                var response = JSON.parse(jqXHR.responseText);
+                alert(response.error)
+            }
+        }
+    );
+}
+
+function deleteTag(action) {
+    var result = confirm("You sure you want to delete this tag?");
+    if (result) {
+        var actions = $(action).parent();
+        var tagID = actions.attr("data-id");
+
+        sendAuthorized(
+            {
+                url: `/api/tags/${tagID}`,
+                type: 'DELETE',
+                success: function (data, textStatus, jqXHR) {
+                    alert("Tag deleted");
+                    document.location.reload()
+                },
+                error: function (jqXHR, textStatus, errorThrown) {
+                    var response = JSON.parse(jqXHR.responseText);
+                    alert(response.error)
+                }
+            }
+        );
+    }
+}
+
+// uploadMedia - uploads file to the media library and passes the uploaded file info to the callback
+function uploadMedia(file, onSuccess) {
+    var formData = new FormData();
+    formData.append("file", file);
+
+    sendAuthorized(
+        {
+            url: '/api/media',
+            type: 'POST',
+            data: formData,
+            processData: false,
+            contentType: false,
+            success: function (data, textStatus, jqXHR) {
+                var response = JSON.parse(jqXHR.responseText);
+                onSuccess(response.body);
+            },
+            error: function (jqXHR, textStatus, errorThrown) {
+                var response = JSON.parse(jqXHR.responseText);
+                alert(response.error)
+            }
+        }
+    );
+}
+
+function uploadMediaFromInput(input) {
+    if (input.files.length === 0) {
+        return
+    }
+    uploadMedia(input.files[0], function (media) {
+        document.location.reload()
+    });
+}
+
+function copyMediaMarkdown(action) {
+    var url = $(action).parent().attr("data-url");
+    navigator.clipboard.writeText(`![](${url})`);
+}
+
+function deleteMedia(action) {
+    var result = confirm("You sure you want to delete this file? Posts using it will show a broken image");
+    if (result) {
+        var actions = $(action).parent();
+        var mediaID = actions.attr("data-id");
+
+        sendAuthorized(
+            {
+                url: `/api/media/${mediaID}`,
+                type: 'DELETE',
+                success: function (data, textStatus, jqXHR) {
+                    alert("File deleted");
+                    document.location.reload()
+                },
+                error: function (jqXHR, textStatus, errorThrown) {
+                    var response = JSON.parse(jqXHR.responseText);
+                    alert(response.error)
+                }
+            }
+        );
+    }
+}
\ No newline at end of file
@@ -0,0 +1,52 @@
+$.fancybox.defaults.animationEffect = "fade";
+$.fancybox.defaults.buttons = [
+    "zoom",
+    "download",
+    "close"
+];
+$.fancybox.defaults.toolbar = false;
+$.fancybox.defaults.mobile = {
+    clickSlide: "close"
+};
+
+// showReplyForm - moves a copy of the comment form under the comment that is being replied to
+function showReplyForm(action) {
+    var comment = $(action).closest("li.comment");
+    $(".comments .reply-form").remove();
+
+    var form = $(".comments .root-form form.comment-form").clone();
+    form.find("input, textarea").val("");
+    var wrapper = $('<div class="comment-form-wrapper reply-form"></div>').append(form);
+    wrapper.attr("data-parent-id", comment.attr("data-id"));
+    $(action).after(wrapper);
+}
+
+function submitComment(form) {
+    var wrapper = $(form).closest(".comment-form-wrapper");
+    var parentID = wrapper.attr("data-parent-id");
+
+    var comment = {
+        postID: $(".comments").attr("data-post-id"),
+        parentCommentID: parentID === undefined ? null : parentID,
+        author: $(form).find("input[name=author]").val(),
+        content: $(form).find("textarea[name=content]").val()
+    };
+
+    $.ajax(
+        {
+            url: '/api/comments',
+            type: 'POST',
+            contentType: 'application/json',
+            data: JSON.stringify(comment),
+            success: function (data, textStatus, jqXHR) {
+                var createdComment = JSON.parse(jqXHR.responseText).body;
+                window.location.hash = `comment-${createdComment.ID}`;
+                window.location.reload();
+            },
+            error: function (jqXHR, textStatus, errorThrown) {
+                var response = JSON.parse(jqXHR.responseText);
+                alert(response.error)
+            }
+        }
+    );
+}
@@ -0,0 +1,71 @@
+package themes
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"sort"
+)
+
+// embedded - themes compiled into the server binary. Every theme directory holds the manifest,
+// the templates in the layouts directory and the static files
+//
+//go:embed */theme.yaml */layouts */static
+var embedded embed.FS
+
+// Embedded - returns file system of the themes compiled into the server binary
+func Embedded() fs.FS {
+	return embedded
+}
+
+// overlayFS - file system opening files from the upper file system and falling back to the lower one
+// for the missing files
+type overlayFS struct {
+	upper fs.FS
+	lower fs.FS
+}
+
+// Overlay - returns file system with the files of the upper file system replacing the files of the lower one
+// Directories are merged by fs.ReadDir, but directory opened with Open lists only the files of one file system
+func Overlay(upper, lower fs.FS) fs.FS {
+	return overlayFS{upper: upper, lower: lower}
+}
+
+func (overlay overlayFS) Open(name string) (fs.File, error) {
+	file, err := overlay.upper.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return overlay.lower.Open(name)
+	}
+	return file, err
+}
+
+// ReadDir - returns entries of the directory in both file systems sorted by name
+// Entries of the upper file system replace the entries of the lower one with the same names
+func (overlay overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	upperEntries, upperErr := fs.ReadDir(overlay.upper, name)
+	if upperErr != nil && !errors.Is(upperErr, fs.ErrNotExist) {
+		return nil, upperErr
+	}
+	lowerEntries, lowerErr := fs.ReadDir(overlay.lower, name)
+	if lowerErr != nil && !errors.Is(lowerErr, fs.ErrNotExist) {
+		return nil, lowerErr
+	}
+	if upperErr != nil && lowerErr != nil {
+		return nil, upperErr
+	}
+
+	entries := upperEntries
+	isAdded := make(map[string]bool, len(upperEntries))
+	for _, entry := range upperEntries {
+		isAdded[entry.Name()] = true
+	}
+	for _, entry := range lowerEntries {
+		if !isAdded[entry.Name()] {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
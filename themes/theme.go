@@ -0,0 +1,167 @@
+package themes
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+const (
+	// DefaultName - name of the default theme. Other themes fall back to it
+	// for the templates and static files they do not provide
+	DefaultName = "default"
+	// ManifestFile - name of the manifest file in the theme directory
+	ManifestFile = "theme.yaml"
+	// LayoutsDir - directory of the theme templates
+	LayoutsDir = "layouts"
+	// StaticDir - directory of the theme static files: css, js and images
+	StaticDir = "static"
+)
+
+// Manifest - theme description read from the manifest file
+// @Templates - templates provided by the theme. Paths are relative to the layouts directory,
+// e.g. 'partials/header.html'
+type Manifest struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Templates   []string `yaml:"templates"`
+}
+
+// Theme - set of page templates and static files
+// A theme provides only the templates declared in its manifest, other templates are taken from the default theme.
+// Template files replace the default ones as a whole, so a theme overriding 'partials/header.html'
+// should define the same templates as the default file
+type Theme struct {
+	Manifest Manifest
+	// dir - theme directory in the themes file system
+	dir   string
+	files fs.FS
+	// fallback - theme providing missing templates and static files. Nil for the default theme
+	fallback *Theme
+}
+
+// Load - loads the theme with the given name from the themes file system
+// Themes other than the default one fall back to the default theme, so the default theme is loaded too
+func Load(files fs.FS, name string) (*Theme, error) {
+	defaultTheme, err := loadTheme(files, DefaultName)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" || name == DefaultName {
+		return defaultTheme, nil
+	}
+
+	theme, err := loadTheme(files, name)
+	if err != nil {
+		return nil, err
+	}
+	theme.fallback = defaultTheme
+	return theme, nil
+}
+
+// loadTheme - reads manifest of the theme and checks that the declared templates exist
+func loadTheme(files fs.FS, name string) (*Theme, error) {
+	if !fs.ValidPath(name) || strings.Contains(name, "/") || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid theme name: %s", name)
+	}
+	manifestData, err := fs.ReadFile(files, path.Join(name, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifest of theme %s: %s", name, err)
+	}
+	var manifest Manifest
+	if err := yaml.UnmarshalStrict(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("error parsing manifest of theme %s: %s", name, err)
+	}
+	if manifest.Name == "" {
+		manifest.Name = name
+	}
+
+	theme := &Theme{Manifest: manifest, dir: name, files: files}
+	for _, template := range manifest.Templates {
+		if err := validateTemplatePath(template); err != nil {
+			return nil, fmt.Errorf("invalid template %s in manifest of theme %s: %s", template, name, err)
+		}
+		info, err := fs.Stat(files, theme.templateFile(template))
+		if err != nil {
+			return nil, fmt.Errorf("template %s of theme %s is not found: %s", template, name, err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("template %s of theme %s is a directory", template, name)
+		}
+	}
+	return theme, nil
+}
+
+// validateTemplatePath - checks that the template path is an html file inside the layouts directory
+func validateTemplatePath(template string) error {
+	if path.Ext(template) != ".html" {
+		return errors.New("template should be an html file")
+	}
+	if !fs.ValidPath(template) {
+		return errors.New("path should be relative to the layouts directory")
+	}
+	return nil
+}
+
+// templateFile - returns path of the theme template file in the themes file system
+func (theme *Theme) templateFile(template string) string {
+	return path.Join(theme.dir, LayoutsDir, template)
+}
+
+// Name - returns name of the theme
+func (theme *Theme) Name() string {
+	return theme.Manifest.Name
+}
+
+// FS - returns themes file system the theme is loaded from
+func (theme *Theme) FS() fs.FS {
+	return theme.files
+}
+
+// TemplateFiles - returns paths of the theme template files in the themes file system
+// Templates the theme does not declare are taken from the default theme
+func (theme *Theme) TemplateFiles() []string {
+	var files []string
+	if theme.fallback != nil {
+		provided := make(map[string]bool, len(theme.Manifest.Templates))
+		for _, template := range theme.Manifest.Templates {
+			provided[template] = true
+		}
+		for _, template := range theme.fallback.Manifest.Templates {
+			if !provided[template] {
+				files = append(files, theme.fallback.templateFile(template))
+			}
+		}
+	}
+	for _, template := range theme.Manifest.Templates {
+		files = append(files, theme.templateFile(template))
+	}
+	return files
+}
+
+// TemplateDirs - returns directories of the theme template files in the themes file system
+func (theme *Theme) TemplateDirs() []string {
+	var dirs []string
+	isAdded := make(map[string]bool)
+	for _, file := range theme.TemplateFiles() {
+		if dir := path.Dir(file); !isAdded[dir] {
+			isAdded[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// StaticFiles - returns file system of the theme static files
+// Files missing in the theme are taken from the default theme
+func (theme *Theme) StaticFiles() fs.FS {
+	// sub file system is valid for any valid directory name, so the error is not possible
+	static, _ := fs.Sub(theme.files, path.Join(theme.dir, StaticDir))
+	if theme.fallback == nil {
+		return static
+	}
+	return Overlay(static, theme.fallback.StaticFiles())
+}
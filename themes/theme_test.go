@@ -0,0 +1,100 @@
+package themes
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+// newTestThemes - returns themes file system with the default theme and the 'dark' theme overriding the header
+func newTestThemes() fstest.MapFS {
+	files := map[string]string{
+		"default/theme.yaml":                    "templates: [partials/header.html, index.html]",
+		"default/layouts/partials/header.html":  "default header",
+		"default/layouts/index.html":            "default index",
+		"default/static/css/main.css":           "default main.css",
+		"default/static/css/admin.css":          "default admin.css",
+		"dark/theme.yaml":                       "name: Dark\ntemplates: [partials/header.html]",
+		"dark/layouts/partials/header.html":     "dark header",
+		"dark/layouts/partials/not-listed.html": "not listed in the manifest",
+		"dark/static/css/main.css":              "dark main.css",
+	}
+	themes := make(fstest.MapFS, len(files))
+	for name, content := range files {
+		themes[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return themes
+}
+
+func readStaticFile(t *testing.T, theme *Theme, name string) string {
+	content, err := fs.ReadFile(theme.StaticFiles(), name)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestLoadDefaultTheme(t *testing.T) {
+	theme, err := Load(newTestThemes(), "")
+	require.NoError(t, err)
+	require.Equal(t, DefaultName, theme.Name())
+	require.Equal(t, []string{"default/layouts/partials/header.html", "default/layouts/index.html"},
+		theme.TemplateFiles())
+}
+
+func TestThemeFallsBackToDefaultTheme(t *testing.T) {
+	theme, err := Load(newTestThemes(), "dark")
+	require.NoError(t, err)
+	require.Equal(t, "Dark", theme.Name())
+	require.Equal(t, []string{"default/layouts/index.html", "dark/layouts/partials/header.html"},
+		theme.TemplateFiles())
+	require.Equal(t, []string{"default/layouts", "dark/layouts/partials"}, theme.TemplateDirs())
+
+	require.Equal(t, "dark main.css", readStaticFile(t, theme, "css/main.css"))
+	require.Equal(t, "default admin.css", readStaticFile(t, theme, "css/admin.css"))
+	_, err = fs.Stat(theme.StaticFiles(), "css/missing.css")
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestLoadInvalidTheme(t *testing.T) {
+	themes := newTestThemes()
+	themes["missing-template/theme.yaml"] = &fstest.MapFile{Data: []byte("templates: [post.html]")}
+	themes["outside/theme.yaml"] = &fstest.MapFile{Data: []byte("templates: [../../default/layouts/index.html]")}
+	themes["not-html/theme.yaml"] = &fstest.MapFile{Data: []byte("templates: [partials/header.txt]")}
+	themes["unknown-field/theme.yaml"] = &fstest.MapFile{Data: []byte("template: [index.html]")}
+
+	for _, name := range []string{"missing", "missing-template", "outside", "not-html", "unknown-field", "../default"} {
+		_, err := Load(themes, name)
+		require.Error(t, err, "theme %s should be invalid", name)
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	upper := fstest.MapFS{"default/layouts/index.html": &fstest.MapFile{Data: []byte("changed index")}}
+	themes := Overlay(upper, newTestThemes())
+
+	theme, err := Load(themes, DefaultName)
+	require.NoError(t, err)
+	index, err := fs.ReadFile(theme.FS(), "default/layouts/index.html")
+	require.NoError(t, err)
+	require.Equal(t, "changed index", string(index))
+	header, err := fs.ReadFile(theme.FS(), "default/layouts/partials/header.html")
+	require.NoError(t, err)
+	require.Equal(t, "default header", string(header))
+
+	entries, err := fs.ReadDir(themes, "default/layouts")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "index.html", entries[0].Name())
+	require.Equal(t, "partials", entries[1].Name())
+}
+
+func TestLoadEmbeddedThemes(t *testing.T) {
+	themeDirs, err := fs.ReadDir(Embedded(), ".")
+	require.NoError(t, err)
+	require.NotEmpty(t, themeDirs)
+	for _, themeDir := range themeDirs {
+		_, err := Load(Embedded(), themeDir.Name())
+		require.NoError(t, err, "theme %s is invalid", themeDir.Name())
+	}
+}